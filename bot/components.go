@@ -0,0 +1,200 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// Components are buttons, select menus and modals. Their custom ID is "prefix:key", and the ComponentInfo registered
+// with the prefix handles them. State that a component needs, such as which page a paginator is on, can be saved by
+// its custom ID with SetComponentState, which is kept in config/components.json so that it survives restarts.
+
+var (
+	componentStates      = make(map[string]componentState) // [custom id]componentState
+	componentStatesMutex sync.Mutex
+	componentStatesDirty = false
+	componentStatesPath  = "config/components.json"
+
+	DefaultComponentTTL = 24 * time.Hour // DefaultComponentTTL is how long component state is kept by default
+)
+
+// ComponentHandler handles a Component interaction. If it returns an error, it is shown to the user like a command error.
+type ComponentHandler func(Component) error
+
+// ComponentInfo is used by features in order to register a ComponentHandler for custom IDs that start with Prefix
+type ComponentInfo struct {
+	Fn     ComponentHandler
+	FnName string
+	Prefix string
+}
+
+func (i ComponentInfo) String() string {
+	return fmt.Sprintf("[%s, %s, %p]", i.FnName, i.Prefix, i.Fn)
+}
+
+// Component is passed to ComponentInfo.Fn's arguments when a component is used
+type Component struct {
+	E      *gateway.InteractionCreateEvent
+	FnName string
+	ID     string            // ID is the custom ID of the component
+	Key    string            // Key is the part of ID after the prefix
+	Values []string          // Values are the selected values of a select menu
+	Fields map[string]string // Fields are the values of a submitted modal's text inputs, by their custom ID
+
+	responded *bool
+}
+
+// NewComponent will create a Component for e, with a custom ID of id
+func NewComponent(e *gateway.InteractionCreateEvent, id string) Component {
+	_, key, _ := strings.Cut(id, ":")
+	return Component{E: e, ID: id, Key: key, Fields: make(map[string]string), responded: new(bool)}
+}
+
+// ComponentID will return the custom ID for a component with prefix and key, which can be at most 100 characters
+func ComponentID(prefix, key string) discord.ComponentID {
+	return discord.ComponentID(prefix + ":" + key)
+}
+
+// Respond will respond to the interaction. Every interaction has to be responded to once, and the dispatcher
+// acknowledges it if the handler doesn't.
+func (c Component) Respond(resp api.InteractionResponse) error {
+	if c.responded != nil {
+		*c.responded = true
+	}
+	return Client.RespondInteraction(c.E.ID, c.E.Token, resp)
+}
+
+// Responded will return if Respond was called
+func (c Component) Responded() bool {
+	return c.responded != nil && *c.responded
+}
+
+// User will return the user that used the component
+func (c Component) User() discord.User {
+	if u := c.E.Sender(); u != nil {
+		return *u
+	}
+	return discord.User{}
+}
+
+// State will unmarshal the state saved for the component into v, and return false if there isn't any
+func (c Component) State(v any) (bool, error) {
+	return ComponentState(c.ID, v)
+}
+
+type componentState struct {
+	Data    json.RawMessage `json:"data"`
+	Expires time.Time       `json:"expires"`
+}
+
+// SetComponentState will save v as the state of the component with id, until ttl has passed
+func SetComponentState(id discord.ComponentID, v any, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	componentStatesMutex.Lock()
+	defer componentStatesMutex.Unlock()
+
+	componentStates[string(id)] = componentState{Data: data, Expires: time.Now().Add(ttl)}
+	componentStatesDirty = true
+	return nil
+}
+
+// ComponentState will unmarshal the state of the component with id into v, and return false if there isn't any
+func ComponentState(id string, v any) (bool, error) {
+	componentStatesMutex.Lock()
+	state, ok := componentStates[id]
+	componentStatesMutex.Unlock()
+
+	if !ok || time.Now().After(state.Expires) {
+		return false, nil
+	}
+	return true, json.Unmarshal(state.Data, v)
+}
+
+// DeleteComponentState will remove the state of the component with id
+func DeleteComponentState(id string) {
+	componentStatesMutex.Lock()
+	defer componentStatesMutex.Unlock()
+
+	if _, ok := componentStates[id]; ok {
+		delete(componentStates, id)
+		componentStatesDirty = true
+	}
+}
+
+// LoadComponentStates will load the saved component states, dropping the ones that have expired
+func LoadComponentStates() {
+	bytes, err := os.ReadFile(componentStatesPath)
+	if err != nil {
+		slog.Warn("error loading component states", "err", err)
+		return
+	}
+
+	states := make(map[string]componentState)
+	if err := json.Unmarshal(bytes, &states); err != nil {
+		slog.Error("error unmarshalling component states", "err", err)
+		return
+	}
+
+	now := time.Now()
+
+	componentStatesMutex.Lock()
+	defer componentStatesMutex.Unlock()
+
+	for id, state := range states {
+		if now.After(state.Expires) {
+			componentStatesDirty = true
+			continue
+		}
+		componentStates[id] = state
+	}
+
+	slog.Info("loaded component states", "count", len(componentStates))
+}
+
+// SaveComponentStates will save the component states that haven't expired, if they have changed since they were last saved
+func SaveComponentStates() {
+	now := time.Now()
+	defer ObserveSince(ConfigSaveDuration.WithLabelValues("components"), now)
+
+	componentStatesMutex.Lock()
+	if !componentStatesDirty {
+		componentStatesMutex.Unlock()
+		return
+	}
+
+	for id, state := range componentStates {
+		if now.After(state.Expires) {
+			delete(componentStates, id)
+		}
+	}
+
+	bytes, err := json.MarshalIndent(componentStates, "", "    ")
+	componentStatesDirty = false
+	componentStatesMutex.Unlock()
+
+	if err != nil {
+		slog.Error("failed to marshal component states", "err", err)
+		return
+	}
+
+	if err = os.WriteFile(componentStatesPath, bytes, FileMode); err != nil {
+		slog.Error("failed to write component states", "err", err)
+
+		componentStatesMutex.Lock()
+		componentStatesDirty = true
+		componentStatesMutex.Unlock()
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"github.com/5HT2/taro-bot/util"
 	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/diamondburned/arikawa/v3/gateway"
-	"log"
+	"log/slog"
 	"os"
 	"strings"
 	"sync"
@@ -19,7 +19,7 @@ var (
 
 	DefaultPrefix  = "."
 	DefaultPlugins = []string{"base", "base-extra", "base-fun", "bookmarker", "leave-join-msg", "message-roles",
-		"role-menu", "spotifytoyoutube", "starboard", "remindme", "sys-stats", "suggest-topic", "tenor-delete"}
+		"role-menu", "spotifytoyoutube", "starboard", "remindme", "sys-stats", "suggest-topic", "tags", "tenor-delete"}
 
 	FileMode = os.FileMode(0700)
 )
@@ -32,10 +32,14 @@ type guildOperation func(*GuildConfig) (*GuildConfig, string)
 // TODO: Having one "context" per command would be nice to have.
 func GuildContext(c discord.GuildID, g guildOperation) {
 	id := int64(c)
-	start := time.Now().UnixMilli()
+	start := time.Now()
 	found := false
 
 	C.Run(func(c *Config) {
+		locked := time.Now()
+		GuildContextWait.Observe(locked.Sub(start).Seconds())
+		defer ObserveSince(GuildContextHold, locked)
+
 		// Try to find an existing config, and if so, replace it with the result of executed guildOperation
 		// TODO: This isn't scalable with lots of Guilds, so a map would be preferable. See #6
 		for n, guild := range c.GuildConfigs {
@@ -45,8 +49,7 @@ func GuildContext(c discord.GuildID, g guildOperation) {
 				c.GuildConfigs[n] = *res
 				found = true
 
-				exec := time.Now().UnixMilli()
-				log.Printf("Execute: %vms (%s)\n", exec-start, fnName)
+				slog.Debug("executed guild operation", "guild", id, "fn", fnName, "ms", time.Since(start).Milliseconds())
 				break
 			}
 		}
@@ -85,6 +88,15 @@ type Config struct {
 	OperatorChannel int64               `json:"operator_channel,omitempty"`
 	OperatorIDs     []int64             `json:"operator_ids,omitempty"`
 	OperatorAliases map[string][]string `json:"operator_aliases,omitempty"`
+	RateLimit       float64             `json:"rate_limit,omitempty"`       // Commands and responses run per second, see WaitRateLimit
+	RateLimitBurst  int                 `json:"rate_limit_burst,omitempty"` // Commands and responses that can run at once, see WaitRateLimit
+	EditWindow      int64               `json:"edit_window,omitempty"`      // Seconds after sending a command that editing it runs it again, -1 to disable
+	LogLevels       map[string]string   `json:"log_levels,omitempty"`       // Log levels of plugins by their ConfigDir, such as "debug", see SetLogLevels
+	ErrorDigest     int64               `json:"error_digest,omitempty"`     // Seconds between digests of errors in the OperatorChannel, -1 to disable, see RunErrorDigests
+	DashboardToken  string              `json:"dashboard_token,omitempty"`  // Token that bot operators log into the dashboard with, disabled if empty
+	DashboardUrl    string              `json:"dashboard_url,omitempty"`    // Public URL of the dashboard, such as https://taro.example.com/dashboard/, used for login links
+	UserLocales     map[int64]string    `json:"user_locales,omitempty"`     // [user id]locale, which overrides the GuildConfig.Locale, see Locale
+	ShutdownTimeout int64               `json:"shutdown_timeout,omitempty"` // Seconds to wait for running commands and jobs when shutting down, see DefaultShutdownTimeout
 	GuildConfigs    []GuildConfig       `json:"guild_configs,omitempty"`
 }
 
@@ -99,11 +111,16 @@ type GuildConfig struct {
 	TopicVoteThreshold   int64             `json:"topic_vote_threshold,omitempty"`   // TODO: Migrate
 	TopicVoteEmoji       string            `json:"topic_vote_emoji,omitempty"`       // TODO: Migrate
 	Starboard            StarboardConfig   `json:"starboard_config"`                 // TODO: Migrate
+	KeepReplies          bool              `json:"keep_replies,omitempty"`           // KeepReplies stops the bot from deleting its replies to deleted commands
+	NoSuggestions        bool              `json:"no_suggestions,omitempty"`         // NoSuggestions stops the bot from suggesting commands for unknown ones
+	Locale               string            `json:"locale,omitempty"`                 // Locale that messages are translated to, such as "de", see Locale
 }
 
 type PluginConfig struct {
-	Mutex         sync.Mutex `json:"-"`              // not saved in DB
-	LoadedPlugins []string   `json:"loaded_plugins"` // A list of plugins to load, overrides DefaultPlugins
+	Mutex         sync.Mutex `json:"-"`                      // not saved in DB
+	LoadedPlugins []string   `json:"loaded_plugins"`         // A list of plugins to load, overrides DefaultPlugins
+	PanicLimit    int        `json:"panic_limit,omitempty"`  // Panics within PanicWindow before a plugin is disabled, -1 to never disable
+	PanicWindow   int64      `json:"panic_window,omitempty"` // Seconds that PanicLimit is counted over
 }
 
 // SetupConfigSaving will run SaveConfig and SavePluginConfig every 5 minutes with a ticker
@@ -115,6 +132,7 @@ func SetupConfigSaving() {
 			case <-ticker.C:
 				SaveConfig()
 				SavePluginConfig()
+				SaveComponentStates()
 			}
 		}
 	}()
@@ -123,11 +141,11 @@ func SetupConfigSaving() {
 func LoadConfig() {
 	bytes, err := os.ReadFile("config/config.json")
 	if err != nil {
-		log.Fatalf("error loading config: %v\n", err)
+		Fatal("error loading config", "err", err)
 	}
 
 	if err := json.Unmarshal(bytes, &C); err != nil {
-		log.Fatalf("error unmarshalling config: %v\n", err)
+		Fatal("error unmarshalling config", "err", err)
 	}
 
 	C.Run(func(c *Config) {
@@ -144,9 +162,12 @@ func LoadConfig() {
 		c.FohPublicUrl = valueOrDefault(c.FohPublicUrl, "https://cdn.l1v.in")
 		c.FohPublicDir = valueOrDefault(c.FohPublicDir, "/")
 	})
+
+	configSaved(nil)
 }
 
 func SaveConfig() {
+	defer ObserveSince(ConfigSaveDuration.WithLabelValues("config"), time.Now())
 	var bytes []byte
 	var err error = nil
 
@@ -155,45 +176,47 @@ func SaveConfig() {
 	})
 
 	if err != nil {
-		log.Printf("failed to marshal config: %v\n", err)
+		slog.Error("failed to marshal config", "err", err)
+		configSaved(err)
 		return
 	}
 
 	err = os.WriteFile("config/config.json", bytes, FileMode)
+	configSaved(err)
 	if err != nil {
-		log.Printf("failed to write config: %v\n", err)
+		slog.Error("failed to write config", "err", err)
 	} else {
-		log.Printf("saved taro config\n")
+		slog.Debug("saved taro config")
 	}
 }
 
 func LoadPluginConfig() {
 	bytes, err := os.ReadFile("config/plugins.json")
 	if err != nil {
-		log.Printf("error loading plugin config: %v\n", err)
-		log.Printf("loading default config/plugins.json\n")
+		slog.Warn("error loading plugin config, loading default config/plugins.json", "err", err)
 
 		P = PluginConfig{LoadedPlugins: make([]string, 0)}
 	} else {
 		if err := json.Unmarshal(bytes, &P); err != nil {
-			log.Fatalf("error unmarshalling plugin config: %v\n", err)
+			Fatal("error unmarshalling plugin config", "err", err)
 		}
 	}
 }
 
 func SavePluginConfig() {
+	defer ObserveSince(ConfigSaveDuration.WithLabelValues("plugins"), time.Now())
 	bytes, err := json.MarshalIndent(&P, "", "    ")
 
 	if err != nil {
-		log.Printf("failed to marshal plugin config: %v\n", err)
+		slog.Error("failed to marshal plugin config", "err", err)
 		return
 	}
 
 	err = os.WriteFile("config/plugins.json", bytes, FileMode)
 	if err != nil {
-		log.Printf("failed to write plugin config: %v\n", err)
+		slog.Error("failed to write plugin config", "err", err)
 	} else {
-		log.Printf("saved taro plugin config\n")
+		slog.Debug("saved taro plugin config")
 	}
 }
 
@@ -215,10 +238,10 @@ func LoadActivityStatus() {
 	name = strings.ReplaceAll(name, "USER_TAG", fmt.Sprintf("%v", util.FormattedUserTag(*User)))
 	name = strings.ReplaceAll(name, "USER_USERNAME", fmt.Sprintf("%v", User.Username))
 
-	if err := Client.Gateway().Send(Ctx, &gateway.UpdatePresenceCommand{
+	if err := Client.UpdatePresence(Ctx, gateway.UpdatePresenceCommand{
 		Activities: []discord.Activity{{Name: name, URL: url, Type: discord.ActivityType(activityType)}},
 	}); err != nil {
-		log.Printf("error loading activity status: %v\n", err)
+		slog.Error("error loading activity status", "err", err)
 	}
 }
 
@@ -226,7 +249,7 @@ func SetPrefix(fnName string, id discord.GuildID, prefix string) (string, error)
 	// Filter spaces
 	prefix = strings.ReplaceAll(prefix, " ", "")
 	if len(prefix) == 0 {
-		return "", GenericError(fnName, "getting prefix", "prefix is empty")
+		return "", GenericSyntaxError(fnName, prefix, "prefix is empty")
 	}
 
 	// Prefix is okay, set it in the cache
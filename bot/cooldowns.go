@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"fmt"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"golang.org/x/time/rate"
+	"sync"
+	"time"
+)
+
+//
+// Cooldowns are declared by commands and responses, and are checked by the dispatcher before running them.
+// The rate limiter is shared by all commands and responses, so that spam across the bot can't exceed Discord's rate limits.
+
+var (
+	cooldowns      = make(map[string]*cooldownEntry) // [name/scope/id]cooldownEntry
+	cooldownsMutex sync.Mutex
+	cooldownsPrune = time.Now()
+
+	limiter      *rate.Limiter
+	limiterMutex sync.Mutex
+
+	DefaultRateLimit      = 20.0            // DefaultRateLimit is the number of commands and responses run per second, see Config.RateLimit
+	DefaultRateLimitBurst = 40              // DefaultRateLimitBurst is how many commands and responses can run at once, see Config.RateLimitBurst
+	MaxRateLimitWait      = 5 * time.Second // MaxRateLimitWait is the longest that WaitRateLimit will wait, before dropping the event
+)
+
+// CooldownScope is what a Cooldown is counted for
+type CooldownScope string
+
+const (
+	CooldownUser    CooldownScope = "user"    // CooldownUser is counted separately for each user
+	CooldownChannel CooldownScope = "channel" // CooldownChannel is counted separately for each channel
+	CooldownGuild   CooldownScope = "guild"   // CooldownGuild is counted separately for each guild
+	CooldownGlobal  CooldownScope = "global"  // CooldownGlobal is counted once for everyone
+)
+
+// Cooldown is how often a command or response can be used, by Scope
+type Cooldown struct {
+	Scope    CooldownScope
+	Duration time.Duration
+}
+
+func (c Cooldown) String() string {
+	return fmt.Sprintf("[%s, %s]", c.Scope, c.Duration)
+}
+
+type cooldownEntry struct {
+	expires  time.Time
+	notified bool // notified is if the user has been told about this cooldown, so that they are only told once
+}
+
+// UseCooldowns will check the cooldowns of name for e. If none of them are active, they are all started and 0 is
+// returned. Otherwise, the longest remaining time is returned, and notify is true the first time that happens.
+func UseCooldowns(name string, c []Cooldown, e *gateway.MessageCreateEvent) (remaining time.Duration, notify bool) {
+	if len(c) == 0 {
+		return 0, false
+	}
+
+	now := time.Now()
+
+	cooldownsMutex.Lock()
+	defer cooldownsMutex.Unlock()
+
+	pruneCooldowns(now)
+
+	keys := make([]string, len(c))
+	for n, cooldown := range c {
+		keys[n] = cooldownKey(name, cooldown.Scope, e)
+
+		if entry, ok := cooldowns[keys[n]]; ok && now.Before(entry.expires) {
+			if left := entry.expires.Sub(now); left > remaining {
+				remaining = left
+				notify = !entry.notified
+			}
+		}
+	}
+
+	if remaining > 0 {
+		for _, key := range keys {
+			if entry, ok := cooldowns[key]; ok && now.Before(entry.expires) {
+				entry.notified = true
+			}
+		}
+		return remaining, notify
+	}
+
+	for n, cooldown := range c {
+		cooldowns[keys[n]] = &cooldownEntry{expires: now.Add(cooldown.Duration)}
+	}
+	return 0, false
+}
+
+// cooldownKey will return the key of a cooldown for e, such as "frog/user/1234"
+func cooldownKey(name string, scope CooldownScope, e *gateway.MessageCreateEvent) string {
+	id := ""
+	switch scope {
+	case CooldownUser:
+		id = e.Author.ID.String()
+	case CooldownChannel:
+		id = e.ChannelID.String()
+	case CooldownGuild:
+		id = e.GuildID.String()
+	}
+
+	return name + "/" + string(scope) + "/" + id
+}
+
+// pruneCooldowns will remove expired cooldowns once a minute, so that they don't keep growing
+func pruneCooldowns(now time.Time) {
+	if now.Sub(cooldownsPrune) < time.Minute {
+		return
+	}
+
+	for key, entry := range cooldowns {
+		if !now.Before(entry.expires) {
+			delete(cooldowns, key)
+		}
+	}
+	cooldownsPrune = now
+}
+
+// WaitRateLimit will wait until the global rate limit allows running a command or response, and return false if that
+// would take longer than MaxRateLimitWait, in which case it should be dropped.
+func WaitRateLimit() bool {
+	limit, burst := DefaultRateLimit, DefaultRateLimitBurst
+	C.Run(func(c *Config) {
+		if c.RateLimit > 0 {
+			limit = c.RateLimit
+		}
+		if c.RateLimitBurst > 0 {
+			burst = c.RateLimitBurst
+		}
+	})
+
+	limiterMutex.Lock()
+	if limiter == nil {
+		limiter = rate.NewLimiter(rate.Limit(limit), burst)
+	} else if limiter.Limit() != rate.Limit(limit) || limiter.Burst() != burst {
+		// The rate limit can be changed while the bot is running, such as with the operatorconfig command
+		limiter.SetLimit(rate.Limit(limit))
+		limiter.SetBurst(burst)
+	}
+	l := limiter
+	limiterMutex.Unlock()
+
+	r := l.Reserve()
+	if delay := r.Delay(); delay > MaxRateLimitWait {
+		r.Cancel()
+		return false
+	} else if delay > 0 {
+		time.Sleep(delay)
+	}
+	return true
+}
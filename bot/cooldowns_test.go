@@ -0,0 +1,37 @@
+package bot
+
+import "testing"
+
+func TestWaitRateLimitConfig(t *testing.T) {
+	t.Cleanup(func() {
+		C.Run(func(c *Config) {
+			c.RateLimit = 0
+			c.RateLimitBurst = 0
+		})
+		limiterMutex.Lock()
+		limiter = nil
+		limiterMutex.Unlock()
+	})
+
+	C.Run(func(c *Config) {
+		c.RateLimit = 0.001
+		c.RateLimitBurst = 1
+	})
+	if !WaitRateLimit() {
+		t.Fatalf("expected the burst to allow the first event")
+	}
+	if WaitRateLimit() {
+		t.Fatalf("expected the second event to be dropped instead of waiting")
+	}
+
+	// Changing rate_limit takes effect without restarting
+	C.Run(func(c *Config) {
+		c.RateLimit = 1000
+		c.RateLimitBurst = 10
+	})
+	for i := 0; i < 10; i++ {
+		if !WaitRateLimit() {
+			t.Fatalf("expected the new rate limit to be used, event %v was dropped", i)
+		}
+	}
+}
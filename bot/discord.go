@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"context"
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// Discord is every Discord operation that the bot uses, so that Client can be replaced with a fake in tests.
+// Its methods are the same as a *state.State's, which is what NewDiscord uses.
+type Discord interface {
+	// AddHandler will call handler with every gateway event of its argument's type, until rm is called
+	AddHandler(handler interface{}) (rm func())
+	// UpdatePresence will set the bot's activity
+	UpdatePresence(ctx context.Context, presence gateway.UpdatePresenceCommand) error
+
+	Me() (*discord.User, error)
+	User(userID discord.UserID) (*discord.User, error)
+	Guild(id discord.GuildID) (*discord.Guild, error)
+	Guilds() ([]discord.Guild, error)
+	Member(guildID discord.GuildID, userID discord.UserID) (*discord.Member, error)
+	Members(guildID discord.GuildID) ([]discord.Member, error)
+
+	Roles(guildID discord.GuildID) ([]discord.Role, error)
+	AddRole(guildID discord.GuildID, userID discord.UserID, roleID discord.RoleID, data api.AddRoleData) error
+	RemoveRole(guildID discord.GuildID, userID discord.UserID, roleID discord.RoleID, reason api.AuditLogReason) error
+
+	Channel(id discord.ChannelID) (*discord.Channel, error)
+	ModifyChannel(channelID discord.ChannelID, data api.ModifyChannelData) error
+	CreatePrivateChannel(recipient discord.UserID) (*discord.Channel, error)
+
+	Message(channelID discord.ChannelID, messageID discord.MessageID) (*discord.Message, error)
+	SendMessage(channelID discord.ChannelID, content string, embeds ...discord.Embed) (*discord.Message, error)
+	SendEmbeds(channelID discord.ChannelID, e ...discord.Embed) (*discord.Message, error)
+	SendMessageComplex(channelID discord.ChannelID, data api.SendMessageData) (*discord.Message, error)
+	EditMessage(channelID discord.ChannelID, messageID discord.MessageID, content string, embeds ...discord.Embed) (*discord.Message, error)
+	EditMessageComplex(channelID discord.ChannelID, messageID discord.MessageID, data api.EditMessageData) (*discord.Message, error)
+	DeleteMessage(channelID discord.ChannelID, messageID discord.MessageID, reason api.AuditLogReason) error
+
+	React(channelID discord.ChannelID, messageID discord.MessageID, emoji discord.APIEmoji) error
+	Unreact(channelID discord.ChannelID, messageID discord.MessageID, emoji discord.APIEmoji) error
+	Reactions(channelID discord.ChannelID, messageID discord.MessageID, emoji discord.APIEmoji, limit uint) ([]discord.User, error)
+
+	Emojis(guildID discord.GuildID) ([]discord.Emoji, error)
+	CreateEmoji(guildID discord.GuildID, data api.CreateEmojiData) (*discord.Emoji, error)
+
+	RespondInteraction(id discord.InteractionID, token string, resp api.InteractionResponse) error
+}
+
+// stateDiscord is the Discord used when the bot is running, which is a *state.State
+type stateDiscord struct {
+	*state.State
+}
+
+// NewDiscord will return a Discord that uses s
+func NewDiscord(s *state.State) Discord {
+	return stateDiscord{s}
+}
+
+func (s stateDiscord) UpdatePresence(ctx context.Context, presence gateway.UpdatePresenceCommand) error {
+	return s.Gateway().Send(ctx, &presence)
+}
@@ -1,17 +1,91 @@
 package bot
 
+import (
+	"errors"
+	"github.com/diamondburned/arikawa/v3/utils/httputil"
+)
+
+// ErrorKind is what went wrong, which decides how an Error is shown to users and if it is reported to bot operators.
+// An Error without a Kind is a request that the command can't do, such as adding a tag that already exists.
+type ErrorKind string
+
+const (
+	KindSyntax     ErrorKind = "syntax"     // KindSyntax is when the args of a command are invalid
+	KindPermission ErrorKind = "permission" // KindPermission is when the user isn't allowed to do something
+	KindNotFound   ErrorKind = "not_found"  // KindNotFound is when something the user asked for doesn't exist
+	KindUpstream   ErrorKind = "upstream"   // KindUpstream is when Discord or an external API failed
+	KindInternal   ErrorKind = "internal"   // KindInternal is a bug or misconfiguration in the bot
+)
+
+var (
+	// ErrPanic is wrapped by errors from functions that panicked, which were already reported by the panic handler
+	ErrPanic = errors.New("panicked")
+)
+
+// Error is an error from a command or other function of the bot.
+// Err is shown to users, so it shouldn't contain anything private, and the Cause is only shown to bot operators.
 type Error struct {
 	Func   string
 	Action string
 	Err    string
+	Kind   ErrorKind
+	Cause  error
 }
 
+// Error will return the detailed error, for logs and bot operators
 func (e *Error) Error() string {
-	return "taro." + e.Func + ":\n    error with: " + e.Action + "\n    because: " + e.Err
+	s := "taro." + e.Func + ":\n    error with: " + e.Action + "\n    because: " + e.Err
+	if e.Cause != nil {
+		s += "\n    caused by: " + e.Cause.Error()
+	}
+	return s
+}
+
+// Unwrap will return the Cause, so that it can be checked with errors.Is and errors.As
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// UserError will return the error that is shown to users, without the Cause
+func (e *Error) UserError() string {
+	if e.Kind == KindSyntax {
+		return "Error " + e.Action + ": " + e.Err
+	}
+	return e.Err
+}
+
+// Reported will return if errors of k should be reported to bot operators, because they aren't caused by the user
+func (k ErrorKind) Reported() bool {
+	return k == KindUpstream || k == KindInternal
+}
+
+// ErrorKindOf will return the Kind of the first Error in err's chain. Other errors are KindUpstream if they are from a
+// request to Discord, or KindInternal otherwise.
+func ErrorKindOf(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+
+	var httpErr *httputil.HTTPError
+	if errors.As(err, &httpErr) {
+		return KindUpstream
+	}
+	return KindInternal
+}
+
+// UserError will return the text of err that is shown to users. Errors that aren't an Error might have private
+// details, so they are replaced with a generic message.
+func UserError(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.UserError()
+	}
+	return "Something went wrong"
 }
 
 func GenericSyntaxError(fn, input, reason string) *Error {
-	return &Error{fn, "parsing \"" + input + "\"", reason}
+	return &Error{Func: fn, Action: "parsing \"" + input + "\"", Err: reason, Kind: KindSyntax}
 }
 
 func SyntaxError(fn, input string) *Error {
@@ -19,5 +93,25 @@ func SyntaxError(fn, input string) *Error {
 }
 
 func GenericError(fn, action, err string) *Error {
-	return &Error{fn, action, err}
+	return &Error{Func: fn, Action: action, Err: err}
+}
+
+// PermissionError will return an Error for when the user isn't allowed to do something
+func PermissionError(fn, action, err string) *Error {
+	return &Error{Func: fn, Action: action, Err: err, Kind: KindPermission}
+}
+
+// NotFoundError will return an Error for when something the user asked for doesn't exist
+func NotFoundError(fn, action, err string) *Error {
+	return &Error{Func: fn, Action: action, Err: err, Kind: KindNotFound}
+}
+
+// UpstreamError will return an Error for when Discord or an external API failed with cause
+func UpstreamError(fn, action, err string, cause error) *Error {
+	return &Error{Func: fn, Action: action, Err: err, Kind: KindUpstream, Cause: cause}
+}
+
+// InternalError will return an Error for a bug or misconfiguration, caused by cause if it isn't nil
+func InternalError(fn, action, err string, cause error) *Error {
+	return &Error{Func: fn, Action: action, Err: err, Kind: KindInternal, Cause: cause}
 }
@@ -0,0 +1,35 @@
+package bot
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestErrorKinds(t *testing.T) {
+	err := error(UpstreamError("Fetch", "fetching frog", "the frog api is down", io.ErrUnexpectedEOF))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected errors.Is to find the cause")
+	}
+	if kind := ErrorKindOf(err); kind != KindUpstream || !kind.Reported() {
+		t.Errorf("expected a reported upstream error, got %q", kind)
+	}
+	if got := UserError(err); got != "the frog api is down" {
+		t.Errorf("expected the user error to not have the cause, got %q", got)
+	}
+
+	if got := UserError(SyntaxError("ParseInt64Arg", "abc")); got != `Error parsing "abc": invalid syntax` {
+		t.Errorf("expected the user error to have the input, got %q", got)
+	}
+	if kind := ErrorKindOf(PermissionError("Secret", "running command", "missing permission")); kind.Reported() {
+		t.Errorf("expected permission errors to not be reported")
+	}
+
+	// Errors that aren't an Error could have anything in them, so they aren't shown to users
+	if kind := ErrorKindOf(io.EOF); kind != KindInternal {
+		t.Errorf("expected other errors to be internal, got %q", kind)
+	}
+	if got := UserError(io.EOF); got == io.EOF.Error() {
+		t.Errorf("expected other errors to be hidden from users")
+	}
+}
@@ -0,0 +1,606 @@
+// Package fakediscord is an in-memory bot.Discord, used to test commands, responses and plugins without connecting to
+// Discord. Guilds, channels, users and messages are added with the Put methods, and everything the bot does to them can
+// be checked afterwards.
+package fakediscord
+
+import (
+	"context"
+	"fmt"
+	"github.com/5HT2/taro-bot/bot"
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var _ bot.Discord = (*Discord)(nil)
+
+// Discord is the fake. Its zero value isn't usable, use New instead.
+type Discord struct {
+	mutex sync.Mutex
+	ids   uint64
+
+	me        discord.User
+	users     map[discord.UserID]discord.User
+	guilds    map[discord.GuildID]discord.Guild
+	members   map[discord.GuildID]map[discord.UserID]discord.Member
+	channels  map[discord.ChannelID]discord.Channel
+	messages  map[discord.ChannelID][]discord.Message                   // messages in each channel, oldest first
+	reactions map[discord.MessageID]map[discord.APIEmoji][]discord.User // users that reacted to each message, by emoji
+	deleted   []discord.MessageID
+	responses []Response
+	presence  *gateway.UpdatePresenceCommand
+	handlers  map[int]reflect.Value
+	handlerID int
+}
+
+// Response is a response to an interaction, see Discord.Responses
+type Response struct {
+	ID       discord.InteractionID
+	Token    string
+	Response api.InteractionResponse
+}
+
+// New will create an empty Discord, where the bot is a user named username
+func New(username string) *Discord {
+	d := &Discord{
+		users:     make(map[discord.UserID]discord.User),
+		guilds:    make(map[discord.GuildID]discord.Guild),
+		members:   make(map[discord.GuildID]map[discord.UserID]discord.Member),
+		channels:  make(map[discord.ChannelID]discord.Channel),
+		messages:  make(map[discord.ChannelID][]discord.Message),
+		reactions: make(map[discord.MessageID]map[discord.APIEmoji][]discord.User),
+		handlers:  make(map[int]reflect.Value),
+	}
+
+	d.me = discord.User{ID: discord.UserID(d.NewID()), Username: username, Discriminator: "0000", Bot: true}
+	d.users[d.me.ID] = d.me
+	return d
+}
+
+// NewID will return a new unique snowflake, with the current time
+func (d *Discord) NewID() discord.Snowflake {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	// The lower 22 bits of a snowflake aren't part of its time, so they are used to keep IDs from the same millisecond unique
+	d.ids++
+	return discord.NewSnowflake(time.Now()) | discord.Snowflake(d.ids&0x3FFFFF)
+}
+
+// notFound is the error returned when something doesn't exist, like Discord's "Unknown Channel"
+func notFound(kind string, id fmt.Stringer) error {
+	return fmt.Errorf("unknown %s: %s", kind, id)
+}
+
+//
+// Setting up the fake
+
+// PutUser will add or replace a user
+func (d *Discord) PutUser(u discord.User) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.users[u.ID] = u
+}
+
+// PutGuild will add or replace a guild
+func (d *Discord) PutGuild(g discord.Guild) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.guilds[g.ID] = g
+}
+
+// PutRole will add or replace a role in a guild
+func (d *Discord) PutRole(guildID discord.GuildID, r discord.Role) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	g, ok := d.guilds[guildID]
+	if !ok {
+		return notFound("guild", guildID)
+	}
+
+	roles := make([]discord.Role, 0, len(g.Roles)+1)
+	for _, role := range g.Roles {
+		if role.ID != r.ID {
+			roles = append(roles, role)
+		}
+	}
+
+	g.Roles = append(roles, r)
+	d.guilds[guildID] = g
+	return nil
+}
+
+// PutMember will add or replace a member of a guild, and its user
+func (d *Discord) PutMember(guildID discord.GuildID, m discord.Member) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.members[guildID] == nil {
+		d.members[guildID] = make(map[discord.UserID]discord.Member)
+	}
+	d.members[guildID][m.User.ID] = m
+	d.users[m.User.ID] = m.User
+}
+
+// PutChannel will add or replace a channel
+func (d *Discord) PutChannel(c discord.Channel) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.channels[c.ID] = c
+}
+
+// PutMessage will add a message to its channel, as if it was sent by its author, and return it with an ID if it didn't have one
+func (d *Discord) PutMessage(m discord.Message) discord.Message {
+	if !m.ID.IsValid() {
+		m.ID = discord.MessageID(d.NewID())
+	}
+	if !m.Timestamp.IsValid() {
+		m.Timestamp = discord.NewTimestamp(time.Now())
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.messages[m.ChannelID] = append(d.messages[m.ChannelID], m)
+	return m
+}
+
+// PutReaction will add a reaction to a message, without dispatching an event for it
+func (d *Discord) PutReaction(channelID discord.ChannelID, messageID discord.MessageID, emoji discord.APIEmoji, u discord.User) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, _, ok := d.findMessage(channelID, messageID); !ok {
+		return notFound("message", messageID)
+	}
+
+	if d.reactions[messageID] == nil {
+		d.reactions[messageID] = make(map[discord.APIEmoji][]discord.User)
+	}
+	for _, user := range d.reactions[messageID][emoji] {
+		if user.ID == u.ID {
+			return nil
+		}
+	}
+	d.reactions[messageID][emoji] = append(d.reactions[messageID][emoji], u)
+	return nil
+}
+
+// Dispatch will call every handler added with AddHandler that takes the type of event, like the gateway does
+func (d *Discord) Dispatch(event interface{}) {
+	v := reflect.ValueOf(event)
+
+	d.mutex.Lock()
+	handlers := make([]reflect.Value, 0)
+	for id := 0; id < d.handlerID; id++ {
+		if h, ok := d.handlers[id]; ok && h.Type().In(0) == v.Type() {
+			handlers = append(handlers, h)
+		}
+	}
+	d.mutex.Unlock()
+
+	for _, h := range handlers {
+		h.Call([]reflect.Value{v})
+	}
+}
+
+//
+// Checking what happened
+
+// Messages will return the messages in a channel, oldest first
+func (d *Discord) Messages(channelID discord.ChannelID) []discord.Message {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]discord.Message{}, d.messages[channelID]...)
+}
+
+// Deleted will return the IDs of the messages that were deleted, in order
+func (d *Discord) Deleted() []discord.MessageID {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]discord.MessageID{}, d.deleted...)
+}
+
+// ReactionUsers will return the users that reacted to a message with emoji
+func (d *Discord) ReactionUsers(messageID discord.MessageID, emoji discord.APIEmoji) []discord.User {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]discord.User{}, d.reactions[messageID][emoji]...)
+}
+
+// Responses will return the responses to interactions, in order
+func (d *Discord) Responses() []Response {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]Response{}, d.responses...)
+}
+
+// Presence will return the last presence set with UpdatePresence, or nil
+func (d *Discord) Presence() *gateway.UpdatePresenceCommand {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.presence
+}
+
+//
+// bot.Discord
+
+func (d *Discord) AddHandler(handler interface{}) (rm func()) {
+	v := reflect.ValueOf(handler)
+	if v.Kind() != reflect.Func || v.Type().NumIn() != 1 {
+		panic(fmt.Sprintf("fakediscord: handler has to be a func with one argument, got %T", handler))
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	id := d.handlerID
+	d.handlerID++
+	d.handlers[id] = v
+
+	return func() {
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		delete(d.handlers, id)
+	}
+}
+
+func (d *Discord) UpdatePresence(_ context.Context, presence gateway.UpdatePresenceCommand) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.presence = &presence
+	return nil
+}
+
+func (d *Discord) Me() (*discord.User, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	me := d.me
+	return &me, nil
+}
+
+func (d *Discord) User(userID discord.UserID) (*discord.User, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if u, ok := d.users[userID]; ok {
+		return &u, nil
+	}
+	return nil, notFound("user", userID)
+}
+
+func (d *Discord) Guild(id discord.GuildID) (*discord.Guild, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if g, ok := d.guilds[id]; ok {
+		return &g, nil
+	}
+	return nil, notFound("guild", id)
+}
+
+func (d *Discord) Guilds() ([]discord.Guild, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	guilds := make([]discord.Guild, 0, len(d.guilds))
+	for _, g := range d.guilds {
+		guilds = append(guilds, g)
+	}
+	return guilds, nil
+}
+
+func (d *Discord) Member(guildID discord.GuildID, userID discord.UserID) (*discord.Member, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if m, ok := d.members[guildID][userID]; ok {
+		return &m, nil
+	}
+	return nil, notFound("member", userID)
+}
+
+func (d *Discord) Members(guildID discord.GuildID) ([]discord.Member, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	members := make([]discord.Member, 0, len(d.members[guildID]))
+	for _, m := range d.members[guildID] {
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+func (d *Discord) Roles(guildID discord.GuildID) ([]discord.Role, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if g, ok := d.guilds[guildID]; ok {
+		return append([]discord.Role{}, g.Roles...), nil
+	}
+	return nil, notFound("guild", guildID)
+}
+
+func (d *Discord) AddRole(guildID discord.GuildID, userID discord.UserID, roleID discord.RoleID, _ api.AddRoleData) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	m, ok := d.members[guildID][userID]
+	if !ok {
+		return notFound("member", userID)
+	}
+
+	for _, id := range m.RoleIDs {
+		if id == roleID {
+			return nil
+		}
+	}
+
+	m.RoleIDs = append(m.RoleIDs, roleID)
+	d.members[guildID][userID] = m
+	return nil
+}
+
+func (d *Discord) RemoveRole(guildID discord.GuildID, userID discord.UserID, roleID discord.RoleID, _ api.AuditLogReason) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	m, ok := d.members[guildID][userID]
+	if !ok {
+		return notFound("member", userID)
+	}
+
+	roles := make([]discord.RoleID, 0, len(m.RoleIDs))
+	for _, id := range m.RoleIDs {
+		if id != roleID {
+			roles = append(roles, id)
+		}
+	}
+
+	m.RoleIDs = roles
+	d.members[guildID][userID] = m
+	return nil
+}
+
+func (d *Discord) Channel(id discord.ChannelID) (*discord.Channel, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if c, ok := d.channels[id]; ok {
+		return &c, nil
+	}
+	return nil, notFound("channel", id)
+}
+
+func (d *Discord) ModifyChannel(channelID discord.ChannelID, data api.ModifyChannelData) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	c, ok := d.channels[channelID]
+	if !ok {
+		return notFound("channel", channelID)
+	}
+
+	if data.Name != "" {
+		c.Name = data.Name
+	}
+	if data.Topic != nil {
+		c.Topic = data.Topic.Val
+	}
+	if data.NSFW != nil {
+		c.NSFW = data.NSFW.Val
+	}
+	if data.CategoryID.IsValid() {
+		c.ParentID = data.CategoryID
+	}
+	if data.Overwrites != nil {
+		c.Overwrites = *data.Overwrites
+	}
+
+	d.channels[channelID] = c
+	return nil
+}
+
+func (d *Discord) CreatePrivateChannel(recipient discord.UserID) (*discord.Channel, error) {
+	d.mutex.Lock()
+	u, ok := d.users[recipient]
+	if !ok {
+		d.mutex.Unlock()
+		return nil, notFound("user", recipient)
+	}
+
+	for _, c := range d.channels {
+		if c.Type == discord.DirectMessage && len(c.DMRecipients) == 1 && c.DMRecipients[0].ID == recipient {
+			d.mutex.Unlock()
+			return &c, nil
+		}
+	}
+	d.mutex.Unlock()
+
+	c := discord.Channel{ID: discord.ChannelID(d.NewID()), Type: discord.DirectMessage, DMRecipients: []discord.User{u}}
+	d.PutChannel(c)
+	return &c, nil
+}
+
+// findMessage will return the channel messages that a message is in, and its index. d.mutex has to be held when calling it.
+func (d *Discord) findMessage(channelID discord.ChannelID, messageID discord.MessageID) ([]discord.Message, int, bool) {
+	messages := d.messages[channelID]
+	for n, m := range messages {
+		if m.ID == messageID {
+			return messages, n, true
+		}
+	}
+	return nil, 0, false
+}
+
+func (d *Discord) Message(channelID discord.ChannelID, messageID discord.MessageID) (*discord.Message, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if messages, n, ok := d.findMessage(channelID, messageID); ok {
+		m := messages[n]
+		return &m, nil
+	}
+	return nil, notFound("message", messageID)
+}
+
+func (d *Discord) SendMessage(channelID discord.ChannelID, content string, embeds ...discord.Embed) (*discord.Message, error) {
+	return d.SendMessageComplex(channelID, api.SendMessageData{Content: content, Embeds: embeds})
+}
+
+func (d *Discord) SendEmbeds(channelID discord.ChannelID, e ...discord.Embed) (*discord.Message, error) {
+	return d.SendMessageComplex(channelID, api.SendMessageData{Embeds: e})
+}
+
+func (d *Discord) SendMessageComplex(channelID discord.ChannelID, data api.SendMessageData) (*discord.Message, error) {
+	c, err := d.Channel(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.Content == "" && len(data.Embeds) == 0 && len(data.Files) == 0 {
+		return nil, fmt.Errorf("cannot send an empty message")
+	}
+
+	m := discord.Message{
+		ChannelID:  channelID,
+		GuildID:    c.GuildID,
+		Author:     d.me,
+		Content:    data.Content,
+		Embeds:     append([]discord.Embed{}, data.Embeds...),
+		Components: data.Components,
+	}
+	if data.Reference != nil {
+		m.Reference = data.Reference
+	}
+
+	m = d.PutMessage(m)
+	return &m, nil
+}
+
+func (d *Discord) EditMessage(channelID discord.ChannelID, messageID discord.MessageID, content string, embeds ...discord.Embed) (*discord.Message, error) {
+	return d.EditMessageComplex(channelID, messageID, api.EditMessageData{Content: option.NewNullableString(content), Embeds: &embeds})
+}
+
+func (d *Discord) EditMessageComplex(channelID discord.ChannelID, messageID discord.MessageID, data api.EditMessageData) (*discord.Message, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	messages, n, ok := d.findMessage(channelID, messageID)
+	if !ok {
+		return nil, notFound("message", messageID)
+	}
+
+	m := messages[n]
+	if m.Author.ID != d.me.ID {
+		return nil, fmt.Errorf("cannot edit a message authored by another user")
+	}
+
+	if data.Content != nil {
+		m.Content = data.Content.Val
+	}
+	if data.Embeds != nil {
+		m.Embeds = append([]discord.Embed{}, *data.Embeds...)
+	}
+	if data.Components != nil {
+		m.Components = *data.Components
+	}
+	m.EditedTimestamp = discord.NewTimestamp(time.Now())
+
+	messages[n] = m
+	return &m, nil
+}
+
+func (d *Discord) DeleteMessage(channelID discord.ChannelID, messageID discord.MessageID, _ api.AuditLogReason) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	messages, n, ok := d.findMessage(channelID, messageID)
+	if !ok {
+		return notFound("message", messageID)
+	}
+
+	d.messages[channelID] = append(messages[:n:n], messages[n+1:]...)
+	delete(d.reactions, messageID)
+	d.deleted = append(d.deleted, messageID)
+	return nil
+}
+
+func (d *Discord) React(channelID discord.ChannelID, messageID discord.MessageID, emoji discord.APIEmoji) error {
+	return d.PutReaction(channelID, messageID, emoji, d.me)
+}
+
+func (d *Discord) Unreact(channelID discord.ChannelID, messageID discord.MessageID, emoji discord.APIEmoji) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, _, ok := d.findMessage(channelID, messageID); !ok {
+		return notFound("message", messageID)
+	}
+
+	users := make([]discord.User, 0)
+	for _, u := range d.reactions[messageID][emoji] {
+		if u.ID != d.me.ID {
+			users = append(users, u)
+		}
+	}
+
+	if d.reactions[messageID] != nil {
+		d.reactions[messageID][emoji] = users
+	}
+	return nil
+}
+
+func (d *Discord) Reactions(channelID discord.ChannelID, messageID discord.MessageID, emoji discord.APIEmoji, limit uint) ([]discord.User, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, _, ok := d.findMessage(channelID, messageID); !ok {
+		return nil, notFound("message", messageID)
+	}
+
+	users := append([]discord.User{}, d.reactions[messageID][emoji]...)
+	if limit > 0 && uint(len(users)) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (d *Discord) Emojis(guildID discord.GuildID) ([]discord.Emoji, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if g, ok := d.guilds[guildID]; ok {
+		return append([]discord.Emoji{}, g.Emojis...), nil
+	}
+	return nil, notFound("guild", guildID)
+}
+
+func (d *Discord) CreateEmoji(guildID discord.GuildID, data api.CreateEmojiData) (*discord.Emoji, error) {
+	e := discord.Emoji{ID: discord.EmojiID(d.NewID()), Name: data.Name}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	g, ok := d.guilds[guildID]
+	if !ok {
+		return nil, notFound("guild", guildID)
+	}
+
+	g.Emojis = append(g.Emojis, e)
+	d.guilds[guildID] = g
+	return &e, nil
+}
+
+func (d *Discord) RespondInteraction(id discord.InteractionID, token string, resp api.InteractionResponse) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.responses = append(d.responses, Response{ID: id, Token: token, Response: resp})
+	return nil
+}
@@ -0,0 +1,165 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	// GatewayGracePeriod is how long the gateway can be disconnected for before /healthz fails, so that reconnects
+	// don't make the bot unhealthy
+	GatewayGracePeriod = 2 * time.Minute
+	// ConfigSaveMaxAge is how long it can be since the config was last saved before /readyz fails, config saving runs
+	// every 5 minutes, see SetupConfigSaving
+	ConfigSaveMaxAge = 15 * time.Minute
+
+	health = struct {
+		sync.Mutex
+		connected      bool
+		changed        time.Time // changed is when connected last changed
+		lastConfigSave time.Time
+		configSaveErr  error
+		checks         []HealthCheck
+	}{changed: time.Now()}
+)
+
+// HealthCheck is a check that is reported by /readyz, and /healthz unless it is Ready
+type HealthCheck struct {
+	Name  string
+	Ready bool                   // Ready checks are only used by /readyz, for things that don't need a restart to fix
+	Fn    func() (string, error) // Fn returns a detail about the check, or an error if it failed
+}
+
+// HealthResult is the result of a HealthCheck
+type HealthResult struct {
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+}
+
+// AddHealthCheck will add c to the checks served by ServeHealth
+func AddHealthCheck(c HealthCheck) {
+	health.Lock()
+	defer health.Unlock()
+	health.checks = append(health.checks, c)
+}
+
+// SetGatewayConnected will set whether the gateway is connected, from its events
+func SetGatewayConnected(connected bool) {
+	health.Lock()
+	defer health.Unlock()
+
+	if health.connected != connected {
+		health.connected = connected
+		health.changed = time.Now()
+	}
+}
+
+// configSaved will record the result of SaveConfig or LoadConfig, for the config health check
+func configSaved(err error) {
+	health.Lock()
+	defer health.Unlock()
+
+	health.configSaveErr = err
+	if err == nil {
+		health.lastConfigSave = time.Now()
+	}
+}
+
+// ServeHealth will serve /healthz and /readyz on Mux, which respond with 503 when a check fails
+func ServeHealth() {
+	Mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		serveHealth(w, false)
+	})
+	Mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		serveHealth(w, true)
+	})
+}
+
+func serveHealth(w http.ResponseWriter, ready bool) {
+	results, ok := RunHealthChecks(ready)
+
+	w.Header().Set("Content-Type", "application/json")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	_ = json.NewEncoder(w).Encode(struct {
+		OK     bool                    `json:"ok"`
+		Checks map[string]HealthResult `json:"checks"`
+	}{ok, results})
+}
+
+// RunHealthChecks will run the checks for /readyz if ready is set, otherwise the checks for /healthz,
+// and return their results and if all of them passed
+func RunHealthChecks(ready bool) (map[string]HealthResult, bool) {
+	health.Lock()
+	checks := append([]HealthCheck{
+		{Name: "gateway", Fn: gatewayCheck(!ready)},
+		{Name: "scheduler", Fn: schedulerCheck},
+		{Name: "config", Ready: true, Fn: configCheck},
+	}, health.checks...)
+	health.Unlock()
+
+	results := make(map[string]HealthResult)
+	ok := true
+	for _, c := range checks {
+		if c.Ready && !ready {
+			continue
+		}
+
+		detail, err := c.Fn()
+		if err != nil {
+			ok = false
+			detail = err.Error()
+		}
+		results[c.Name] = HealthResult{OK: err == nil, Detail: detail}
+	}
+
+	return results, ok
+}
+
+// gatewayCheck will check if the gateway is connected, allowing it to reconnect for GatewayGracePeriod if grace is set
+func gatewayCheck(grace bool) func() (string, error) {
+	return func() (string, error) {
+		health.Lock()
+		defer health.Unlock()
+
+		since := time.Since(health.changed).Round(time.Second)
+		if health.connected {
+			return fmt.Sprintf("connected for %s", since), nil
+		}
+		if grace && since < GatewayGracePeriod {
+			return fmt.Sprintf("disconnected for %s, waiting to reconnect", since), nil
+		}
+		return "", fmt.Errorf("disconnected for %s", since)
+	}
+}
+
+func schedulerCheck() (string, error) {
+	if !Scheduler.IsRunning() {
+		return "", errors.New("scheduler is not running")
+	}
+	return fmt.Sprintf("running %v jobs", Scheduler.Len()), nil
+}
+
+func configCheck() (string, error) {
+	health.Lock()
+	defer health.Unlock()
+
+	if health.configSaveErr != nil {
+		return "", fmt.Errorf("last save failed: %v", health.configSaveErr)
+	}
+	if health.lastConfigSave.IsZero() {
+		return "", errors.New("config hasn't been loaded")
+	}
+
+	since := time.Since(health.lastConfigSave).Round(time.Second)
+	if since > ConfigSaveMaxAge {
+		return "", fmt.Errorf("last loaded or saved %s ago", since)
+	}
+	return fmt.Sprintf("last loaded or saved %s ago", since), nil
+}
@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGatewayCheck(t *testing.T) {
+	SetGatewayConnected(true)
+	if _, err := gatewayCheck(false)(); err != nil {
+		t.Errorf("expected a connected gateway to be healthy, got %v", err)
+	}
+
+	// A reconnect is only allowed by /healthz, and only for GatewayGracePeriod
+	SetGatewayConnected(false)
+	if _, err := gatewayCheck(true)(); err != nil {
+		t.Errorf("expected a reconnecting gateway to be healthy, got %v", err)
+	}
+	if _, err := gatewayCheck(false)(); err == nil {
+		t.Errorf("expected a reconnecting gateway to not be ready")
+	}
+
+	health.Lock()
+	health.changed = time.Now().Add(-GatewayGracePeriod)
+	health.Unlock()
+	if _, err := gatewayCheck(true)(); err == nil {
+		t.Errorf("expected a disconnected gateway to be unhealthy")
+	}
+}
+
+func TestConfigCheck(t *testing.T) {
+	configSaved(nil)
+	if _, err := configCheck(); err != nil {
+		t.Errorf("expected a saved config to be ready, got %v", err)
+	}
+
+	configSaved(errors.New("disk full"))
+	if _, err := configCheck(); err == nil {
+		t.Errorf("expected a failed save to not be ready")
+	}
+
+	configSaved(nil)
+	health.Lock()
+	health.lastConfigSave = time.Now().Add(-ConfigSaveMaxAge - time.Minute)
+	health.Unlock()
+	if _, err := configCheck(); err == nil {
+		t.Errorf("expected an old save to not be ready")
+	}
+}
+
+func TestRunHealthChecks(t *testing.T) {
+	AddHealthCheck(HealthCheck{Name: "test", Ready: true, Fn: func() (string, error) {
+		return "", errors.New("not ready")
+	}})
+	t.Cleanup(func() {
+		health.Lock()
+		health.checks = nil
+		health.Unlock()
+	})
+
+	results, _ := RunHealthChecks(false)
+	if _, ok := results["test"]; ok {
+		t.Errorf("expected ready checks to not be run by /healthz")
+	}
+
+	results, ok := RunHealthChecks(true)
+	if ok || results["test"].OK || results["test"].Detail != "not ready" {
+		t.Errorf("expected the failing check to fail /readyz, got %v", results["test"])
+	}
+}
@@ -0,0 +1,33 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+var (
+	Mux = http.NewServeMux() // Mux is served by ServeHTTP, for endpoints such as the metrics
+)
+
+// ServeHTTP will serve Mux on addr until ctx is done. It does nothing if addr is empty.
+func ServeHTTP(ctx context.Context, addr string) {
+	if len(addr) == 0 {
+		return
+	}
+
+	server := &http.Server{Addr: addr, Handler: Mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("serving http", "addr", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("failed to serve http", "addr", addr, "err", err)
+	}
+}
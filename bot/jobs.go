@@ -0,0 +1,372 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+//
+// Durable jobs are scheduled by key, and are saved to config/jobs.json so that they survive restarts.
+// Unlike JobInfo, they aren't run by the Scheduler, but by RunDurableJobs, which checks for due jobs every second.
+// Plugins run durable jobs by registering a DurableJobHandler for a Kind, and scheduling jobs with that Kind.
+
+var (
+	durableJobs       = make(map[string]*DurableJob) // [key]DurableJob
+	durableHandlers   = make(map[string]DurableJobHandler)
+	durableJobsMutex  sync.Mutex
+	durableJobsDirty  = false
+	durableJobsPath   = "config/jobs.json"
+	DurableJobRetry   = time.Minute // DurableJobRetry is how long to wait before running a failed job again
+	DurableJobRetries = int64(5)    // DurableJobRetries is how many times a one-shot job is retried before it is dropped
+
+	// ErrJobNotReady can be returned by a DurableJobHandler when it cannot run the job yet, for example when its
+	// plugin is disabled. The job is run again after DurableJobRetry, without counting it as a failed attempt.
+	ErrJobNotReady = errors.New("job handler is not ready")
+)
+
+// MissedPolicy is what happens to a job that was due while the bot was not running
+type MissedPolicy string
+
+const (
+	MissedCatchUp MissedPolicy = "catch_up" // MissedCatchUp runs the job once, as soon as possible
+	MissedSkip    MissedPolicy = "skip"     // MissedSkip drops one-shot jobs, and moves recurring jobs to their next run
+)
+
+// DurableJob is a job that is saved across restarts, see ScheduleDurableJob
+type DurableJob struct {
+	Key      string          `json:"key"`                // Key is unique, and scheduling a job with an existing Key replaces it
+	Kind     string          `json:"kind"`               // Kind is the DurableJobHandler that runs the job
+	Payload  json.RawMessage `json:"payload,omitempty"`  // Payload is passed to the DurableJobHandler, see Unmarshal
+	RunAt    time.Time       `json:"run_at"`             // RunAt is the next time the job will run
+	Interval int64           `json:"interval,omitempty"` // Interval in seconds between runs of a recurring job, 0 for a one-shot job
+	Missed   MissedPolicy    `json:"missed,omitempty"`   // Missed is the MissedPolicy, MissedCatchUp by default
+	Attempts int64           `json:"attempts,omitempty"` // Attempts is how many times the job has failed in a row
+	running  bool
+}
+
+func (j DurableJob) String() string {
+	return fmt.Sprintf("[%s, %s, %v, %v, %s, %v]", j.Key, j.Kind, j.RunAt.Unix(), j.Interval, j.Missed, j.Attempts)
+}
+
+// Unmarshal will unmarshal the job's Payload into v
+func (j DurableJob) Unmarshal(v any) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+// DurableJobHandler runs a DurableJob. If it returns an error, the job is retried after DurableJobRetry.
+type DurableJobHandler func(job DurableJob) error
+
+// DurableJobHandlerInfo is used by features in order to register a DurableJobHandler
+type DurableJobHandlerInfo struct {
+	Fn   DurableJobHandler
+	Kind string
+}
+
+func (i DurableJobHandlerInfo) String() string {
+	return fmt.Sprintf("[%s, %p]", i.Kind, i.Fn)
+}
+
+// NewDurableJob will create a one-shot job with a payload of v, which runs at t
+func NewDurableJob(key, kind string, t time.Time, v any) (DurableJob, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return DurableJob{}, err
+	}
+
+	return DurableJob{Key: key, Kind: kind, Payload: payload, RunAt: t, Missed: MissedCatchUp}, nil
+}
+
+// RegisterDurableJobHandler will set the handler that runs jobs of kind
+func RegisterDurableJobHandler(kind string, fn DurableJobHandler) {
+	durableJobsMutex.Lock()
+	defer durableJobsMutex.Unlock()
+
+	durableHandlers[kind] = fn
+}
+
+// ClearDurableJobHandlers will remove all handlers. Jobs without a handler stay scheduled until one is registered again.
+func ClearDurableJobHandlers() {
+	durableJobsMutex.Lock()
+	defer durableJobsMutex.Unlock()
+
+	durableHandlers = make(map[string]DurableJobHandler)
+}
+
+// ScheduleDurableJob will schedule a job, replacing any job with the same Key
+func ScheduleDurableJob(job DurableJob) error {
+	if len(job.Key) == 0 {
+		return InternalError("ScheduleDurableJob", "scheduling job", "key is empty", nil)
+	}
+	if len(job.Kind) == 0 {
+		return InternalError("ScheduleDurableJob", "scheduling job", "kind is empty", nil)
+	}
+	if job.Interval < 0 {
+		return InternalError("ScheduleDurableJob", "scheduling job", "interval is negative", nil)
+	}
+	if len(job.Missed) == 0 {
+		job.Missed = MissedCatchUp
+	}
+
+	durableJobsMutex.Lock()
+	defer durableJobsMutex.Unlock()
+
+	job.running = false
+	durableJobs[job.Key] = &job
+	durableJobsDirty = true
+
+	slog.Debug("scheduled durable job", "job", job)
+	return nil
+}
+
+// CancelDurableJob will remove the job with key, and return if it existed
+func CancelDurableJob(key string) bool {
+	durableJobsMutex.Lock()
+	defer durableJobsMutex.Unlock()
+
+	if _, ok := durableJobs[key]; !ok {
+		return false
+	}
+
+	delete(durableJobs, key)
+	durableJobsDirty = true
+	return true
+}
+
+// DurableJobs will return a copy of the scheduled jobs, sorted by RunAt
+func DurableJobs() []DurableJob {
+	durableJobsMutex.Lock()
+	defer durableJobsMutex.Unlock()
+
+	jobs := make([]DurableJob, 0, len(durableJobs))
+	for _, job := range durableJobs {
+		jobs = append(jobs, *job)
+	}
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].RunAt.Before(jobs[j].RunAt)
+	})
+	return jobs
+}
+
+// LoadDurableJobs will load the saved jobs, and apply the MissedPolicy of any job that was due while the bot was not running
+func LoadDurableJobs() {
+	bytes, err := os.ReadFile(durableJobsPath)
+	if err != nil {
+		slog.Warn("error loading durable jobs", "err", err)
+		return
+	}
+
+	jobs := make([]DurableJob, 0)
+	if err := json.Unmarshal(bytes, &jobs); err != nil {
+		slog.Error("error unmarshalling durable jobs", "err", err)
+		return
+	}
+
+	now := time.Now()
+
+	durableJobsMutex.Lock()
+	defer durableJobsMutex.Unlock()
+
+	for n := range jobs {
+		job := &jobs[n]
+
+		if job.RunAt.Before(now) && job.Missed == MissedSkip {
+			if job.Interval == 0 {
+				slog.Info("skipping missed durable job", "job", job)
+				durableJobsDirty = true
+				continue
+			}
+
+			job.RunAt = nextRun(job.RunAt, job.Interval, now)
+			durableJobsDirty = true
+		}
+
+		durableJobs[job.Key] = job
+	}
+
+	slog.Info("loaded durable jobs", "count", len(durableJobs))
+}
+
+// SaveDurableJobs will save the scheduled jobs, if they have changed since they were last saved
+func SaveDurableJobs() {
+	defer ObserveSince(ConfigSaveDuration.WithLabelValues("jobs"), time.Now())
+	durableJobsMutex.Lock()
+	if !durableJobsDirty {
+		durableJobsMutex.Unlock()
+		return
+	}
+
+	jobs := make([]DurableJob, 0, len(durableJobs))
+	for _, job := range durableJobs {
+		jobs = append(jobs, *job)
+	}
+	durableJobsDirty = false
+	durableJobsMutex.Unlock()
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].Key < jobs[j].Key
+	})
+
+	bytes, err := json.MarshalIndent(jobs, "", "    ")
+	if err != nil {
+		slog.Error("failed to marshal durable jobs", "err", err)
+		return
+	}
+
+	if err = os.WriteFile(durableJobsPath, bytes, FileMode); err != nil {
+		slog.Error("failed to write durable jobs", "err", err)
+
+		durableJobsMutex.Lock()
+		durableJobsDirty = true
+		durableJobsMutex.Unlock()
+	}
+}
+
+// RunDurableJobs will run due jobs every second, and save them when they change, until ctx is done
+func RunDurableJobs(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, job := range dueDurableJobs(now) {
+				job := job
+				if !Dispatch(func() { runDurableJob(job.job, job.fn) }) {
+					unmarkDurableJob(job.job)
+				}
+			}
+
+			SaveDurableJobs()
+		}
+	}
+}
+
+type dueJob struct {
+	job DurableJob
+	fn  DurableJobHandler
+}
+
+// dueDurableJobs will mark the jobs that are due at now and have a handler as running, and return them
+func dueDurableJobs(now time.Time) []dueJob {
+	durableJobsMutex.Lock()
+	defer durableJobsMutex.Unlock()
+
+	due := make([]dueJob, 0)
+	for _, job := range durableJobs {
+		if job.running || job.RunAt.After(now) {
+			continue
+		}
+
+		if fn, ok := durableHandlers[job.Kind]; ok {
+			job.running = true
+			due = append(due, dueJob{job: *job, fn: fn})
+		}
+	}
+
+	return due
+}
+
+// unmarkDurableJob will mark job as not running, when it couldn't be started because the bot is shutting down
+func unmarkDurableJob(job DurableJob) {
+	durableJobsMutex.Lock()
+	defer durableJobsMutex.Unlock()
+
+	if current, ok := durableJobs[job.Key]; ok && current.RunAt.Equal(job.RunAt) {
+		current.running = false
+	}
+}
+
+func runDurableJob(job DurableJob, fn DurableJobHandler) {
+	err := func() (err error) {
+		defer func() {
+			if x := recover(); x != nil {
+				slog.Error("panic in durable job", "job", job.Key, "panic", x, "stack", string(debug.Stack()))
+				err = fmt.Errorf("%w: %v", ErrPanic, x)
+			}
+		}()
+
+		return fn(job)
+	}()
+
+	DurableJobRunsTotal.WithLabelValues(job.Kind, durableJobResult(err)).Inc()
+	now := time.Now()
+
+	durableJobsMutex.Lock()
+	defer durableJobsMutex.Unlock()
+
+	// The job was cancelled or replaced while it was running
+	current, ok := durableJobs[job.Key]
+	if !ok || !current.running || !current.RunAt.Equal(job.RunAt) {
+		return
+	}
+
+	current.running = false
+	durableJobsDirty = true
+
+	switch {
+	case errors.Is(err, ErrJobNotReady):
+		current.RunAt = now.Add(DurableJobRetry)
+	case err != nil && current.Interval == 0 && current.Attempts+1 >= DurableJobRetries:
+		slog.ErrorContext(Reported, "dropping durable job", "job", current, "attempts", current.Attempts+1, "err", err)
+		reportJobError(*current, err, "Dropped durable job", fmt.Sprintf("dropped after %v attempts", current.Attempts+1))
+		delete(durableJobs, job.Key)
+	case err != nil && current.Interval == 0:
+		slog.Warn("durable job failed, retrying", "job", current, "err", err)
+		reportJobError(*current, err, "Durable job", fmt.Sprintf("retrying in %s, attempt %v of %v", DurableJobRetry, current.Attempts+1, DurableJobRetries))
+		current.Attempts++
+		current.RunAt = now.Add(DurableJobRetry)
+	case current.Interval == 0:
+		delete(durableJobs, job.Key)
+	default:
+		if err != nil {
+			slog.Warn("recurring durable job failed", "job", current, "err", err)
+			reportJobError(*current, err, "Recurring durable job", "it will run again at its next interval")
+		}
+
+		// Recurring jobs only run once when they are caught up, instead of once for each missed run
+		current.Attempts = 0
+		current.RunAt = nextRun(current.RunAt, current.Interval, now)
+	}
+}
+
+// reportJobError will report a failed run of a durable job to the operator channel, unless it panicked, which was
+// already reported. Runs of the same Kind that fail the same way are counted together, see ErrorReport.Signature.
+func reportJobError(job DurableJob, err error, title, detail string) {
+	if errors.Is(err, ErrPanic) {
+		return
+	}
+
+	ReportError(ErrorReport{Source: "job", Title: title + " `" + job.Kind + "`", Message: err.Error(), Detail: "Job `" + job.Key + "` " + detail})
+}
+
+// durableJobResult will return the result of a durable job run with err, for DurableJobRunsTotal
+func durableJobResult(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, ErrJobNotReady):
+		return "not_ready"
+	default:
+		return "error"
+	}
+}
+
+// nextRun will return the first time after now, that is a multiple of interval seconds after t
+func nextRun(t time.Time, interval int64, now time.Time) time.Time {
+	step := time.Duration(interval) * time.Second
+	if !t.After(now) {
+		t = t.Add(step * (now.Sub(t)/step + 1))
+	}
+	return t
+}
@@ -0,0 +1,151 @@
+package bot
+
+import (
+	"embed"
+	"github.com/5HT2/taro-bot/util"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+var (
+	//go:embed locales/*.json
+	locales embed.FS // locales are the translations of messages from the bot and cmd packages
+)
+
+func init() {
+	sub, err := fs.Sub(locales, "locales")
+	if err == nil {
+		err = util.LoadTranslations(sub)
+	}
+	if err != nil {
+		slog.Error("failed to load translations", "err", err)
+	}
+}
+
+// Locale will return the locale that messages for user in guild are translated to.
+// A locale set by the user is used over the locale of the guild, and util.DefaultLocale is used if neither is set.
+func Locale(guild discord.GuildID, user discord.UserID) language.Tag {
+	locale := ""
+	C.Run(func(c *Config) {
+		if l, ok := c.UserLocales[int64(user)]; ok && user.IsValid() {
+			locale = l
+			return
+		}
+
+		// This doesn't use GuildContext, since that would create a config for guilds that don't have one
+		for _, g := range c.GuildConfigs {
+			if g.ID == int64(guild) {
+				locale = g.Locale
+				break
+			}
+		}
+	})
+
+	if len(locale) == 0 {
+		return util.DefaultLocale
+	}
+
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return util.DefaultLocale
+	}
+	return tag
+}
+
+// Printer will return a printer for the Locale of user in guild
+func Printer(guild discord.GuildID, user discord.UserID) *message.Printer {
+	return util.Printer(Locale(guild, user))
+}
+
+// SetGuildLocale will set the locale of the guild with id, or reset it to util.DefaultLocale if locale is empty
+func SetGuildLocale(fnName string, id discord.GuildID, locale string) (language.Tag, error) {
+	tag, err := parseLocale(fnName, locale)
+	if err != nil {
+		return tag, err
+	}
+
+	GuildContext(id, func(g *GuildConfig) (*GuildConfig, string) {
+		g.Locale = ""
+		if len(locale) > 0 {
+			g.Locale = tag.String()
+		}
+		return g, fnName
+	})
+
+	return tag, nil
+}
+
+// SetUserLocale will set the locale of the user with id in every guild, or reset it to the locale of the guild if
+// locale is empty
+func SetUserLocale(fnName string, id discord.UserID, locale string) (language.Tag, error) {
+	tag, err := parseLocale(fnName, locale)
+	if err != nil {
+		return tag, err
+	}
+
+	C.Run(func(c *Config) {
+		if len(locale) == 0 {
+			delete(c.UserLocales, int64(id))
+			return
+		}
+
+		if c.UserLocales == nil {
+			c.UserLocales = make(map[int64]string)
+		}
+		c.UserLocales[int64(id)] = tag.String()
+	})
+
+	return tag, nil
+}
+
+// Printer will return a printer for the locale of the user and guild of the command
+func (c Command) Printer() *message.Printer {
+	return Printer(c.E.GuildID, c.E.Author.ID)
+}
+
+// T will translate the message with key to the locale of the command, and format it with args like fmt.Sprintf
+func (c Command) T(key string, args ...interface{}) string {
+	return c.Printer().Sprintf(key, args...)
+}
+
+// Printer will return a printer for the locale of the user and guild of the message the response is for
+func (r Response) Printer() *message.Printer {
+	return Printer(r.E.GuildID, r.E.Author.ID)
+}
+
+// T will translate the message with key to the locale of the response, and format it with args like fmt.Sprintf
+func (r Response) T(key string, args ...interface{}) string {
+	return r.Printer().Sprintf(key, args...)
+}
+
+// Printer will return a printer for the locale of the user and guild the component was used in
+func (c Component) Printer() *message.Printer {
+	return Printer(c.E.GuildID, c.User().ID)
+}
+
+// LocaleNames will return the locales that have translations, as a sorted list of their tags
+func LocaleNames() string {
+	names := make([]string, 0)
+	for _, l := range util.Locales() {
+		names = append(names, "`"+l.String()+"`")
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func parseLocale(fnName, locale string) (language.Tag, error) {
+	if len(locale) == 0 {
+		return util.DefaultLocale, nil
+	}
+
+	tag, err := util.ParseLocale(locale)
+	if err != nil {
+		return tag, GenericSyntaxError(fnName, locale, "unknown locale, available locales are "+LocaleNames())
+	}
+	return tag, nil
+}
@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"github.com/5HT2/taro-bot/util"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"golang.org/x/text/language"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLocale(t *testing.T) {
+	guild, user := discord.GuildID(1), discord.UserID(2)
+	C.Run(func(c *Config) {
+		c.GuildConfigs = []GuildConfig{{ID: int64(guild), Locale: "de"}}
+		c.UserLocales = nil
+	})
+	t.Cleanup(func() {
+		C.Run(func(c *Config) {
+			c.GuildConfigs = nil
+			c.UserLocales = nil
+		})
+	})
+
+	if got := Locale(guild, user); got != language.German {
+		t.Errorf("expected the locale of the guild, got %v", got)
+	}
+	if got := Locale(discord.GuildID(3), user); got != util.DefaultLocale {
+		t.Errorf("expected the default locale for guilds without one, got %v", got)
+	}
+
+	if _, err := SetUserLocale("TestLocale", user, "en-GB"); err != nil {
+		t.Fatalf("setting user locale: %v", err)
+	}
+	if got := Locale(guild, user); got != language.BritishEnglish {
+		t.Errorf("expected the locale of the user to be used over the guild, got %v", got)
+	}
+	if _, err := SetUserLocale("TestLocale", user, "xx"); ErrorKindOf(err) != KindSyntax {
+		t.Errorf("expected a syntax error for a locale without translations, got %v", err)
+	}
+
+	// The core translations are embedded, and missing ones fall back to English
+	p := Printer(guild, 0)
+	if got := p.Sprintf("Slow down!"); got != "Langsamer!" {
+		t.Errorf("expected the message to be translated, got %q", got)
+	}
+	if got := util.FormattedTimeIn(p, 3661); got != "1 Stunde, 1 Minute, 1 Sekunde" {
+		t.Errorf("expected plural forms to be translated, got %q", got)
+	}
+	if got := util.FormattedNumIn(p, 1234567); got != "1.234.567" {
+		t.Errorf("expected German digit grouping, got %q", got)
+	}
+	if got := p.Sprintf("Not translated %v", 1); got != "Not translated 1" {
+		t.Errorf("expected the English text for missing translations, got %q", got)
+	}
+}
+
+func TestLoadTranslations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"fr.json": {Data: []byte(`{"Tags": "Étiquettes", "%d tags": {"one": "%d étiquette", "other": "%d étiquettes"}}`)},
+	}
+	if err := util.LoadTranslations(fsys); err != nil {
+		t.Fatalf("loading translations: %v", err)
+	}
+
+	p := util.Printer(language.French)
+	if got := p.Sprintf("Tags"); got != "Étiquettes" {
+		t.Errorf("expected the message to be translated, got %q", got)
+	}
+	if got := p.Sprintf("%d tags", 1) + ", " + p.Sprintf("%d tags", 2000); got != "1 étiquette, 2 000 étiquettes" {
+		t.Errorf("expected plural forms and French digit grouping, got %q", got)
+	}
+	if _, err := util.ParseLocale("fr-CA"); err != nil {
+		t.Errorf("expected regional locales of loaded translations to be accepted, got %v", err)
+	}
+
+	invalid := fstest.MapFS{"de.json": {Data: []byte(`{"Tags": {"one": "Tag"}}`)}}
+	if err := util.LoadTranslations(invalid); err == nil {
+		t.Errorf("expected plural forms without \"other\" to be rejected")
+	}
+}
@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"context"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+var (
+	logLevel     = new(slog.LevelVar)
+	pluginLevels = struct {
+		sync.RWMutex
+		levels map[string]slog.Level // [plugin ConfigDir]level
+	}{levels: make(map[string]slog.Level)}
+)
+
+// SetupLogging will make slog the default logger, at the debug level if debug is set, and writing JSON if json is set.
+// The log package writes to it too, so any log.Printf is logged at the info level.
+func SetupLogging(debug, json bool) {
+	if debug {
+		logLevel.Set(slog.LevelDebug)
+	} else {
+		logLevel.Set(slog.LevelInfo)
+	}
+
+	// The wrapping logHandler filters by level, so that plugins can log below the default level
+	options := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var handler slog.Handler = slog.NewTextHandler(os.Stderr, options)
+	if json {
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	}
+
+	slog.SetDefault(slog.New(&logHandler{Handler: handler}))
+}
+
+// SetLogLevels will override the log level for the plugins in levels, which are keyed by their ConfigDir, see Config.LogLevels
+func SetLogLevels(levels map[string]string) {
+	parsed := make(map[string]slog.Level)
+	for plugin, s := range levels {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(s)); err != nil {
+			slog.Warn("invalid log level", "plugin", plugin, "level", s, "err", err)
+			continue
+		}
+		parsed[plugin] = level
+	}
+
+	pluginLevels.Lock()
+	defer pluginLevels.Unlock()
+	pluginLevels.levels = parsed
+}
+
+// Fatal will log msg as an error, and exit
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// PluginLogger will return a logger for the plugin with configDir, which uses the plugin's log level
+func PluginLogger(configDir string) *slog.Logger {
+	return slog.With("plugin", configDir)
+}
+
+// EventLogger will return a logger with the guild, channel and user of e
+func EventLogger(e *gateway.MessageCreateEvent) *slog.Logger {
+	return slog.With("guild", e.GuildID, "channel", e.ChannelID, "user", e.Author.ID)
+}
+
+// Log will return a logger for the command, with the plugin that registered it and where it was used
+func (c Command) Log() *slog.Logger {
+	return EventLogger(c.E).With("plugin", c.Plugin, "command", c.Name)
+}
+
+// Log will return a logger for the response, with the plugin that registered it and the message it is responding to
+func (r Response) Log() *slog.Logger {
+	return EventLogger(r.E).With("plugin", r.Plugin, "message", r.E.ID)
+}
+
+// logHandler filters records by the log level of their plugin, or the default log level for records without one
+type logHandler struct {
+	slog.Handler
+	plugin string
+}
+
+func (h *logHandler) Enabled(_ context.Context, level slog.Level) bool {
+	// The plugin of a record might only be known from its attributes, so it is checked again in Handle
+	if len(h.plugin) == 0 {
+		return level >= minLogLevel()
+	}
+	return level >= pluginLogLevel(h.plugin)
+}
+
+func (h *logHandler) Handle(ctx context.Context, r slog.Record) error {
+	plugin := h.plugin
+	if len(plugin) == 0 {
+		r.Attrs(func(a slog.Attr) bool {
+			if a.Key == "plugin" {
+				plugin = a.Value.String()
+				return false
+			}
+			return true
+		})
+
+		if r.Level < pluginLogLevel(plugin) {
+			return nil
+		}
+	}
+
+	// Errors are reported to the operator channel, such as those from handlers, which have nowhere else to go
+	if r.Level >= slog.LevelError {
+		reportLog(ctx, plugin, r)
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	plugin := h.plugin
+	for _, a := range attrs {
+		if a.Key == "plugin" {
+			plugin = a.Value.String()
+		}
+	}
+
+	return &logHandler{Handler: h.Handler.WithAttrs(attrs), plugin: plugin}
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	return &logHandler{Handler: h.Handler.WithGroup(name), plugin: h.plugin}
+}
+
+// pluginLogLevel will return the log level of plugin, or the default log level if it doesn't have one
+func pluginLogLevel(plugin string) slog.Level {
+	pluginLevels.RLock()
+	defer pluginLevels.RUnlock()
+
+	if level, ok := pluginLevels.levels[plugin]; ok && len(plugin) > 0 {
+		return level
+	}
+	return logLevel.Level()
+}
+
+// minLogLevel will return the lowest log level that any plugin logs at
+func minLogLevel() slog.Level {
+	pluginLevels.RLock()
+	defer pluginLevels.RUnlock()
+
+	level := logLevel.Level()
+	for _, l := range pluginLevels.levels {
+		if l < level {
+			level = l
+		}
+	}
+	return level
+}
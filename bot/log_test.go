@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"bytes"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestPluginLogLevels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logLevel.Set(slog.LevelInfo)
+	logger := slog.New(&logHandler{Handler: slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})})
+
+	SetLogLevels(map[string]string{"starboard": "debug", "tags": "error", "broken": "loud"})
+	t.Cleanup(func() { SetLogLevels(nil) })
+
+	logger.Debug("default debug")
+	logger.Info("default info")
+	logger.With("plugin", "starboard").Debug("starboard debug")
+	logger.Debug("inline starboard debug", "plugin", "starboard")
+	logger.With("plugin", "tags").Warn("tags warn")
+	logger.With("plugin", "tags").Error("tags error")
+	logger.With("plugin", "broken").Debug("broken debug")
+
+	got := buf.String()
+	for _, msg := range []string{"default info", "starboard debug", "inline starboard debug", "tags error"} {
+		if !strings.Contains(got, msg) {
+			t.Errorf("expected %q to be logged, got:\n%s", msg, got)
+		}
+	}
+	for _, msg := range []string{"default debug", "tags warn", "broken debug"} {
+		if strings.Contains(got, msg) {
+			t.Errorf("expected %q to not be logged, got:\n%s", msg, got)
+		}
+	}
+}
+
+func TestGuildContextLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(&logHandler{Handler: slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})}))
+	logLevel.Set(slog.LevelDebug)
+
+	guild := discord.GuildID(1)
+	C.Run(func(c *Config) {
+		c.BotToken = "bot-secret"
+		c.DashboardToken = "dashboard-secret"
+		c.GuildConfigs = []GuildConfig{{ID: int64(guild)}}
+	})
+	t.Cleanup(func() {
+		slog.SetDefault(defaultLogger)
+		logLevel.Set(slog.LevelInfo)
+		C.Run(func(c *Config) {
+			c.BotToken = ""
+			c.DashboardToken = ""
+			c.GuildConfigs = nil
+		})
+	})
+
+	GuildContext(guild, func(g *GuildConfig) (*GuildConfig, string) {
+		return g, "TestGuildContextLog"
+	})
+
+	got := buf.String()
+	if !strings.Contains(got, "guild="+guild.String()) || !strings.Contains(got, "fn=TestGuildContextLog") {
+		t.Errorf("expected the guild operation to be logged with its guild, got:\n%s", got)
+	}
+	if strings.Contains(got, "secret") {
+		t.Errorf("expected the config to not be logged, got:\n%s", got)
+	}
+}
@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var (
+	CommandsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taro_commands_total",
+		Help: "Commands executed, by command name and plugin.",
+	}, []string{"command", "plugin"})
+	CommandErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taro_command_errors_total",
+		Help: "Commands that returned an error, by command name and plugin.",
+	}, []string{"command", "plugin"})
+	CommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "taro_command_duration_seconds",
+		Help: "Time taken to execute commands, by command name and plugin.",
+	}, []string{"command", "plugin"})
+	ResponsesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taro_responses_total",
+		Help: "Responses that matched a message and ran, by plugin.",
+	}, []string{"plugin"})
+	HandlersTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taro_handler_invocations_total",
+		Help: "Event handler invocations, by handler and plugin.",
+	}, []string{"handler", "plugin"})
+	GuildContextWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "taro_guild_context_wait_seconds",
+		Help:    "Time spent waiting for the config lock in GuildContext.",
+		Buckets: lockBuckets,
+	})
+	GuildContextHold = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "taro_guild_context_hold_seconds",
+		Help:    "Time the config lock was held for in GuildContext.",
+		Buckets: lockBuckets,
+	})
+	JobRunsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taro_job_runs_total",
+		Help: "Scheduled job runs, by job name.",
+	}, []string{"job"})
+	DurableJobRunsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taro_durable_job_runs_total",
+		Help: "Durable job runs, by kind and result.",
+	}, []string{"kind", "result"})
+	DiscordRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "taro_discord_request_duration_seconds",
+		Help: "Latency of Discord REST requests, by method, route and status code.",
+	}, []string{"method", "route", "code"})
+	DiscordRateLimitsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taro_discord_rate_limits_total",
+		Help: "Discord REST requests that were rate limited, by method and route.",
+	}, []string{"method", "route"})
+	GatewayReconnectsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taro_gateway_reconnects_total",
+		Help: "Gateway reconnects, by whether the session was resumed or a new one was started.",
+	}, []string{"type"})
+	WorkersRunning = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "taro_workers_running",
+		Help: "Commands, responses, handlers and durable jobs that are currently running.",
+	})
+	ConfigSaveDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "taro_config_save_duration_seconds",
+		Help:    "Time taken to save configs, by config.",
+		Buckets: lockBuckets,
+	}, []string{"config"})
+
+	lockBuckets     = []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1, 5}
+	routeIDRegex    = regexp.MustCompile(`/\d{15,}`)
+	routeTokenRegex = regexp.MustCompile(`/(interactions|webhooks)/:id/[^/]+`)
+	routeEmojiRegex = regexp.MustCompile(`/reactions/[^/]+`)
+)
+
+// ObserveSince will observe the seconds since start with o
+func ObserveSince(o prometheus.Observer, start time.Time) {
+	o.Observe(time.Since(start).Seconds())
+}
+
+// ServeMetrics will serve the Prometheus metrics on Mux
+func ServeMetrics() {
+	Mux.Handle("/metrics", promhttp.Handler())
+}
+
+// MetricsTransport is an http.RoundTripper that records the latency and rate limits of Discord REST requests
+type MetricsTransport struct {
+	http.RoundTripper
+}
+
+func (t MetricsTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := t.RoundTripper.RoundTrip(r)
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(res.StatusCode)
+	}
+
+	route := discordRoute(r.URL.Path)
+	DiscordRequestDuration.WithLabelValues(r.Method, route, code).Observe(time.Since(start).Seconds())
+	if err == nil && res.StatusCode == http.StatusTooManyRequests {
+		DiscordRateLimitsTotal.WithLabelValues(r.Method, route).Inc()
+	}
+
+	return res, err
+}
+
+// discordRoute will replace the IDs, tokens and emojis in path, so that the number of routes stays small
+func discordRoute(path string) string {
+	path = routeIDRegex.ReplaceAllString(path, "/:id")
+	path = routeTokenRegex.ReplaceAllString(path, "/$1/:id/:token")
+	return routeEmojiRegex.ReplaceAllString(path, "/reactions/:emoji")
+}
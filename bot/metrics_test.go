@@ -0,0 +1,18 @@
+package bot
+
+import "testing"
+
+func TestDiscordRoute(t *testing.T) {
+	tests := map[string]string{
+		"/api/v10/channels/123456789012345678/messages":                                            "/api/v10/channels/:id/messages",
+		"/api/v10/channels/123456789012345678/messages/223456789012345678/reactions/%E2%AD%90/@me": "/api/v10/channels/:id/messages/:id/reactions/:emoji/@me",
+		"/api/v10/interactions/123456789012345678/aW50ZXJhY3Rpb246c2VjcmV0/callback":               "/api/v10/interactions/:id/:token/callback",
+		"/api/v10/users/@me": "/api/v10/users/@me",
+	}
+
+	for path, expected := range tests {
+		if got := discordRoute(path); got != expected {
+			t.Errorf("discordRoute(%q) = %q, expected %q", path, got, expected)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/5HT2/taro-bot/util"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type reportedKey struct{}
+
+var (
+	// Reported is given to the slog ...Context functions when logging an error that was already passed to ReportError,
+	// so that it isn't reported again by the logger
+	Reported = context.WithValue(context.Background(), reportedKey{}, true)
+
+	DefaultErrorDigest = int64(3600)    // DefaultErrorDigest is the seconds between digests, see Config.ErrorDigest
+	ErrorReportBurst   = 5              // ErrorReportBurst is how many new errors are sent straight away each ErrorReportWindow
+	ErrorReportWindow  = time.Minute    // ErrorReportWindow is how often ErrorReportBurst resets
+	ErrorReportTTL     = 24 * time.Hour // ErrorReportTTL is how long an error is kept after it was last seen
+
+	reports = struct {
+		sync.Mutex
+		reports map[string]*ErrorReport // [signature]report
+		sent    []time.Time             // sent is when new errors were sent, for ErrorReportBurst
+	}{reports: make(map[string]*ErrorReport)}
+
+	signatureNumberRegex = regexp.MustCompile(`\d+`)
+	signatureQuoteRegex  = regexp.MustCompile("`[^`]*`|\"[^\"]*\"")
+)
+
+// ErrorReport is an error from a command, response, handler or job, which is reported to the operator channel.
+// Reports with the same Signature are counted together, so that an error that keeps happening is only sent once,
+// and then included in each digest with how many times it happened.
+type ErrorReport struct {
+	Source    string    // Source is what the error came from, such as "command", "panic" or "log"
+	Plugin    string    // Plugin is the ConfigDir of the plugin that the error came from, if any
+	Title     string    // Title is a short description of where the error happened
+	Message   string    // Message is the error itself, its numbers and quoted values aren't part of the Signature
+	Detail    string    // Detail is shown with the Message when the error is sent, such as a stack trace
+	Count     int64     // Count is how many times the error happened since FirstSeen
+	Pending   int64     // Pending is how many times the error happened since the last digest
+	FirstSeen time.Time // FirstSeen is when the error first happened, or since it was last seen over ErrorReportTTL ago
+	LastSeen  time.Time
+}
+
+// Signature will return what the report is deduplicated by
+func (r ErrorReport) Signature() string {
+	message, _, _ := strings.Cut(r.Message, "\n")
+	message = signatureQuoteRegex.ReplaceAllString(message, "_")
+	message = signatureNumberRegex.ReplaceAllString(message, "#")
+	return strings.Join([]string{r.Source, r.Plugin, r.Title, message}, "|")
+}
+
+// ReportError will count an error, and send it to the operator channel if it's the first time it happened.
+// Every error is included in the next digest, see RunErrorDigests.
+func ReportError(r ErrorReport) {
+	if report, send := recordError(r, time.Now()); send {
+		go NotifyOperators(report.embed())
+	}
+}
+
+// ReportCommandError will report an error returned by a command, if its ErrorKind is Reported.
+// Errors from panics aren't reported again, since the panic already was.
+func ReportCommandError(c Command, err error) {
+	if !ErrorKindOf(err).Reported() || errors.Is(err, ErrPanic) {
+		return
+	}
+
+	message, detail := err.Error(), ""
+	var e *Error
+	if errors.As(err, &e) {
+		message = e.Action + ": " + e.Err
+		if e.Cause != nil {
+			detail = e.Cause.Error()
+		}
+	}
+
+	ReportError(ErrorReport{Source: "command", Plugin: c.Plugin, Title: "Command `" + c.Name + "`", Message: message, Detail: detail})
+}
+
+// ErrorReports will return a copy of the reported errors, with the most recently seen first
+func ErrorReports() []ErrorReport {
+	reports.Lock()
+	defer reports.Unlock()
+
+	r := make([]ErrorReport, 0, len(reports.reports))
+	for _, report := range reports.reports {
+		r = append(r, *report)
+	}
+
+	sort.SliceStable(r, func(i, j int) bool {
+		return r[i].LastSeen.After(r[j].LastSeen)
+	})
+	return r
+}
+
+// NotifyOperators will send embed to the operator channel, if there is one
+func NotifyOperators(embed discord.Embed) {
+	channel := int64(0)
+	C.Run(func(c *Config) {
+		channel = c.OperatorChannel
+	})
+
+	if channel == 0 || Client == nil {
+		return
+	}
+
+	// This is a warning, so that failing to send an error doesn't report another one
+	if _, err := Client.SendEmbeds(discord.ChannelID(channel), embed); err != nil {
+		slog.Warn("failed to notify operators", "channel", channel, "err", err)
+	}
+}
+
+// RunErrorDigests will send a digest of the errors that happened since the last one every Config.ErrorDigest seconds,
+// until ctx is done
+func RunErrorDigests(ctx context.Context) {
+	for {
+		interval := DefaultErrorDigest
+		C.Run(func(c *Config) {
+			if c.ErrorDigest != 0 {
+				interval = c.ErrorDigest
+			}
+		})
+
+		// Check again in a minute if digests are disabled, in case they are enabled with operatorconfig
+		wait := time.Duration(interval) * time.Second
+		if interval < 0 {
+			wait = time.Minute
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+			if interval > 0 {
+				SendErrorDigest()
+			}
+		}
+	}
+}
+
+// SendErrorDigest will send the errors that happened since the last digest to the operator channel, if there were any
+func SendErrorDigest() {
+	if embed, ok := errorDigest(time.Now()); ok {
+		NotifyOperators(embed)
+	}
+}
+
+// recordError will count r, and return the updated report and if it should be sent straight away
+func recordError(r ErrorReport, now time.Time) (ErrorReport, bool) {
+	signature := r.Signature()
+
+	reports.Lock()
+	defer reports.Unlock()
+
+	report, ok := reports.reports[signature]
+	if !ok {
+		report = &r
+		report.FirstSeen = now
+		reports.reports[signature] = report
+	}
+
+	report.Message = r.Message
+	report.Detail = r.Detail
+	report.Count++
+	report.Pending++
+	report.LastSeen = now
+
+	if ok {
+		return *report, false
+	}
+
+	// New errors past the burst are only sent in the next digest, so that an outage doesn't flood the channel
+	sent := make([]time.Time, 0, len(reports.sent))
+	for _, t := range reports.sent {
+		if now.Sub(t) < ErrorReportWindow {
+			sent = append(sent, t)
+		}
+	}
+	send := len(sent) < ErrorReportBurst
+	if send {
+		sent = append(sent, now)
+	}
+	reports.sent = sent
+
+	return *report, send
+}
+
+// errorDigest will return the digest of the errors that happened since the last one, and reset their Pending count.
+// Errors that haven't been seen in ErrorReportTTL are removed, so they are sent straight away if they happen again.
+func errorDigest(now time.Time) (discord.Embed, bool) {
+	reports.Lock()
+	defer reports.Unlock()
+
+	pending := make([]ErrorReport, 0)
+	for signature, report := range reports.reports {
+		if report.Pending > 0 {
+			pending = append(pending, *report)
+			report.Pending = 0
+		} else if now.Sub(report.LastSeen) > ErrorReportTTL {
+			delete(reports.reports, signature)
+		}
+	}
+
+	if len(pending) == 0 {
+		return discord.Embed{}, false
+	}
+
+	sort.SliceStable(pending, func(i, j int) bool {
+		if pending[i].Pending != pending[j].Pending {
+			return pending[i].Pending > pending[j].Pending
+		}
+		return pending[i].LastSeen.After(pending[j].LastSeen)
+	})
+
+	total := int64(0)
+	lines := make([]string, 0, len(pending))
+	for _, r := range pending {
+		total += r.Pending
+		line := fmt.Sprintf("**%s**%s: %v× (%v× total), first <t:%v:R>, last <t:%v:R>",
+			r.Title, r.pluginSuffix(), r.Pending, r.Count, r.FirstSeen.Unix(), r.LastSeen.Unix())
+		if len(r.Message) > 0 {
+			line += "\n> " + firstLine(r.Message, 200)
+		}
+		lines = append(lines, line)
+	}
+
+	return discord.Embed{
+		Title:       "Error Digest",
+		Description: util.HeadLinesLimit(strings.Join(lines, "\n"), 4000),
+		Footer:      &discord.EmbedFooter{Text: fmt.Sprintf("%s, %s", util.JoinIntAndStr(int(total), "error"), util.JoinIntAndStr(len(pending), "kind"))},
+		Color:       WarnColor,
+	}, true
+}
+
+// reportLog will report an error logged with slog, unless it was logged with the Reported context
+func reportLog(ctx context.Context, plugin string, r slog.Record) {
+	if ctx != nil && ctx.Value(reportedKey{}) != nil {
+		return
+	}
+
+	message := ""
+	attrs := make([]string, 0)
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == "err" && len(message) == 0 {
+			message = a.Value.String()
+		} else if a.Key != "plugin" {
+			attrs = append(attrs, a.String())
+		}
+		return true
+	})
+
+	ReportError(ErrorReport{Source: "log", Plugin: plugin, Title: r.Message, Message: message, Detail: strings.Join(attrs, "\n")})
+}
+
+func (r ErrorReport) embed() discord.Embed {
+	description := r.Message
+	if len(r.Detail) > 0 {
+		description += "\n```\n" + util.HeadLinesLimit(r.Detail, 3500) + "\n```"
+	}
+
+	return discord.Embed{
+		Title:       util.HeadLinesLimit(r.Title+r.pluginSuffix(), 256),
+		Description: util.HeadLinesLimit(description, 4000),
+		Footer:      &discord.EmbedFooter{Text: "Repeats of this error will be in the next digest"},
+		Color:       ErrorColor,
+	}
+}
+
+func (r ErrorReport) pluginSuffix() string {
+	if len(r.Plugin) == 0 {
+		return ""
+	}
+	return " (" + r.Plugin + ")"
+}
+
+func firstLine(s string, limit int) string {
+	s, _, _ = strings.Cut(s, "\n")
+	return util.HeadLinesLimit(s, limit)
+}
@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func resetReports() {
+	reports.Lock()
+	defer reports.Unlock()
+	reports.reports = make(map[string]*ErrorReport)
+	reports.sent = nil
+}
+
+func TestRecordError(t *testing.T) {
+	resetReports()
+	now := time.Now()
+
+	// Errors that only differ by ids or quoted values are counted together
+	first, send := recordError(ErrorReport{Source: "log", Plugin: "role-menu", Title: "failed to add reaction role", Message: "role 123 not found for `emoji`"}, now)
+	if !send || first.Count != 1 {
+		t.Errorf("expected a new error to be sent straight away, got %v %+v", send, first)
+	}
+
+	second, send := recordError(ErrorReport{Source: "log", Plugin: "role-menu", Title: "failed to add reaction role", Message: "role 456 not found for `other`"}, now.Add(time.Minute))
+	if send || second.Count != 2 || !second.FirstSeen.Equal(now) || !second.LastSeen.Equal(now.Add(time.Minute)) {
+		t.Errorf("expected a repeated error to be counted without sending it, got %v %+v", send, second)
+	}
+
+	if _, send := recordError(ErrorReport{Source: "log", Plugin: "starboard", Title: "failed to add reaction role"}, now); !send {
+		t.Errorf("expected an error from another plugin to be sent")
+	}
+
+	// Only ErrorReportBurst new errors are sent in each ErrorReportWindow
+	for i := 0; i < ErrorReportBurst; i++ {
+		recordError(ErrorReport{Source: "command", Title: strings.Repeat("a", i+1)}, now)
+	}
+	if _, send := recordError(ErrorReport{Source: "command", Title: "burst"}, now); send {
+		t.Errorf("expected new errors past the burst to wait for the digest")
+	}
+	if _, send := recordError(ErrorReport{Source: "command", Title: "later"}, now.Add(ErrorReportWindow)); !send {
+		t.Errorf("expected new errors to be sent again after the window")
+	}
+}
+
+func TestReportCommandError(t *testing.T) {
+	resetReports()
+
+	ReportCommandError(Command{Name: "tag"}, SyntaxError("TagCommand", "add"))
+	if n := len(ErrorReports()); n != 0 {
+		t.Errorf("expected a bot.Error from using a command wrong not to be reported, got %v", n)
+	}
+
+	ReportCommandError(Command{Name: "tag"}, fmt.Errorf("failed to save"))
+	if n := len(ErrorReports()); n != 1 {
+		t.Errorf("expected other errors to be reported, got %v", n)
+	}
+}
+
+func TestErrorDigest(t *testing.T) {
+	resetReports()
+	now := time.Now()
+
+	if _, ok := errorDigest(now); ok {
+		t.Errorf("expected no digest without any errors")
+	}
+
+	for i := 0; i < 3; i++ {
+		recordError(ErrorReport{Source: "command", Plugin: "tags", Title: "Command `tag`", Message: "failed to save"}, now)
+	}
+	recordError(ErrorReport{Source: "log", Title: "failed to write config"}, now)
+
+	embed, ok := errorDigest(now)
+	if !ok {
+		t.Fatalf("expected a digest")
+	}
+	if !strings.HasPrefix(embed.Description, "**Command `tag`** (tags): 3× (3× total)") || !strings.Contains(embed.Description, "> failed to save") {
+		t.Errorf("expected the most frequent error first with its counts, got %q", embed.Description)
+	}
+	if embed.Footer.Text != "4 errors, 2 kinds" {
+		t.Errorf("expected the totals in the footer, got %q", embed.Footer.Text)
+	}
+
+	if _, ok := errorDigest(now); ok {
+		t.Errorf("expected no digest without new errors")
+	}
+
+	// Errors are forgotten after ErrorReportTTL, so that they are sent straight away again
+	errorDigest(now.Add(ErrorReportTTL + time.Minute))
+	if n := len(ErrorReports()); n != 0 {
+		t.Errorf("expected old errors to be removed, got %v", n)
+	}
+}
+
+func TestReportJobError(t *testing.T) {
+	resetReports()
+	t.Cleanup(func() {
+		durableJobsMutex.Lock()
+		delete(durableJobs, "recurring")
+		durableJobsMutex.Unlock()
+	})
+
+	// A recurring job that keeps failing is reported once, and counted each time after that
+	for i := 0; i < 3; i++ {
+		job := DurableJob{Key: "recurring", Kind: "digest", RunAt: time.Now(), Interval: 60, running: true}
+		durableJobsMutex.Lock()
+		durableJobs[job.Key] = &job
+		durableJobsMutex.Unlock()
+
+		runDurableJob(job, func(DurableJob) error { return fmt.Errorf("upstream returned %v", 500+i) })
+	}
+
+	if r := ErrorReports(); len(r) != 1 || r[0].Source != "job" || r[0].Count != 3 {
+		t.Errorf("expected one job error counted 3 times, got %+v", r)
+	}
+}
@@ -3,9 +3,8 @@ package bot
 import (
 	"context"
 	"github.com/diamondburned/arikawa/v3/discord"
-	"github.com/diamondburned/arikawa/v3/state"
 	"github.com/go-co-op/gocron"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"sync"
@@ -13,14 +12,17 @@ import (
 )
 
 var (
-	Commands  = make([]CommandInfo, 0)
-	Responses = make([]ResponseInfo, 0)
-	Handlers  = make([]HandlerInfo, 0)
-	Jobs      = make([]JobInfo, 0)
-	Mutex     = sync.Mutex{}
+	Version = "1.0.0" // Version of the bot, plugin manifests can require a minimum version with `host_version`
+
+	Commands   = make([]CommandInfo, 0)
+	Responses  = make([]ResponseInfo, 0)
+	Components = make([]ComponentInfo, 0)
+	Handlers   = make([]HandlerInfo, 0)
+	Jobs       = make([]JobInfo, 0)
+	Mutex      = sync.Mutex{}
 
 	HttpClient     = http.Client{Timeout: 5 * time.Second}
-	Client         state.State
+	Client         Discord
 	Ctx            = context.Background()
 	User           *discord.User
 	PermissionsHex = 278404582480 // this is currently only used in base.go, but it is in shared.go because it is bot-level and should be set by the person maintaining the bot code
@@ -42,10 +44,10 @@ func getTimeZone() *time.Location {
 
 	l, err := time.LoadLocation(tzEnv)
 	if err != nil {
-		log.Printf("error loading timezone, defaulting to UTC: %v\n", err)
+		slog.Warn("error loading timezone, defaulting to UTC", "err", err)
 		return time.UTC
 	}
 
-	log.Printf("using location \"%s\" for timezone\n", l)
+	slog.Info("using location for timezone", "location", l)
 	return l
 }
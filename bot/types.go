@@ -21,8 +21,12 @@ type CommandInfo struct {
 	FnName      string
 	Name        string
 	Description string
+	Usage       string // Usage is the args of the command, such as "<name> [content]", shown by help and with syntax errors
 	Aliases     []string
 	GuildOnly   bool
+	Permission  string     // Permission is the cmd.Permission needed to use the command, such as "moderate", or empty for everyone
+	Cooldowns   []Cooldown // Cooldowns are how often the command can be used, bot operators are not limited by them
+	Plugin      string     // Plugin is the ConfigDir of the plugin that registered the command, set when registering
 }
 
 // Command is passed to CommandInfo.Fn's arguments when a Command is executed.
@@ -31,6 +35,7 @@ type Command struct {
 	FnName string
 	Name   string
 	Args   []string
+	Plugin string // Plugin is the ConfigDir of the plugin that registered the command
 }
 
 func (i CommandInfo) String() string {
@@ -54,21 +59,48 @@ func (i CommandInfo) MarkdownString() string {
 // ResponseInfo is the info a response provides to register itself.
 // Fn is the function that is executed to complete the Response.
 // The Regexes are used to call the response via Discord.
+// Matching responses run in order of Priority, and an Exclusive response, or one that calls Response.Consume, stops
+// the responses after it from running. Responses don't run for commands, unless AllowCommands is set.
 type ResponseInfo struct {
-	Fn           func(Response) `json:"fn"`
-	Regexes      []string       `json:"regexes"`
-	MatchMin     int            `json:"match_min"`
-	LockChannels []int64        `json:"lock_channels,omitempty"`
-	LockUsers    []int64        `json:"lock_users,omitempty"`
+	Fn            func(Response) `json:"fn"`
+	Regexes       []string       `json:"regexes"`
+	MatchMin      int            `json:"match_min"`
+	LockChannels  []int64        `json:"lock_channels,omitempty"`
+	LockUsers     []int64        `json:"lock_users,omitempty"`
+	Priority      int            `json:"priority,omitempty"`       // Priority is higher for responses that run first, 0 by default
+	Exclusive     bool           `json:"exclusive,omitempty"`      // Exclusive responses stop lower priority responses when they run
+	AllowCommands bool           `json:"allow_commands,omitempty"` // AllowCommands will run the response for messages that are commands
+	Cooldowns     []Cooldown     `json:"cooldowns,omitempty"`      // Cooldowns are how often the response runs, it is skipped while they are active
+	NoRateLimit   bool           `json:"no_rate_limit,omitempty"`  // NoRateLimit will run the response without taking from the global rate limit, for responses that match most messages
+	Plugin        string         `json:"plugin,omitempty"`         // Plugin is the ConfigDir of the plugin that registered the response, set when registering
 }
 
 func (i ResponseInfo) String() string {
-	return fmt.Sprintf("[%p, %v, %s]", i.Fn, i.MatchMin, i.Regexes)
+	return fmt.Sprintf("[%p, %v, %s, %v, %v, %v]", i.Fn, i.MatchMin, i.Regexes, i.Priority, i.Exclusive, i.AllowCommands)
 }
 
 // Response is passed to Response.Fn's arguments when a Response is executed.
 type Response struct {
-	E *gateway.MessageCreateEvent
+	E        *gateway.MessageCreateEvent
+	Plugin   string // Plugin is the ConfigDir of the plugin that registered the response, set when it runs
+	consumed *bool
+}
+
+// NewResponse will create a Response for e, use Consumed to check if it was consumed by the response
+func NewResponse(e *gateway.MessageCreateEvent) Response {
+	return Response{E: e, consumed: new(bool)}
+}
+
+// Consume will stop the responses after this one from running for the message
+func (r Response) Consume() {
+	if r.consumed != nil {
+		*r.consumed = true
+	}
+}
+
+// Consumed will return if Consume was called
+func (r Response) Consumed() bool {
+	return r.consumed != nil && *r.consumed
 }
 
 //
@@ -0,0 +1,135 @@
+package bot
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	// DefaultShutdownTimeout is the seconds that shutting down waits for running work, see Config.ShutdownTimeout.
+	// This is less than the 10 seconds that `docker stop` waits, so that there is time to save afterwards.
+	DefaultShutdownTimeout = int64(8)
+
+	workers = struct {
+		sync.Mutex
+		running  int
+		draining bool
+		idle     chan struct{} // idle is closed once nothing is running while draining
+	}{idle: make(chan struct{})}
+
+	shuttingDown, stopWork = context.WithCancel(context.Background())
+)
+
+// ShutdownTimeout will return how long shutting down waits for running work, before saving and stopping anyway
+func ShutdownTimeout() time.Duration {
+	timeout := DefaultShutdownTimeout
+	C.Run(func(c *Config) {
+		if c.ShutdownTimeout > 0 {
+			timeout = c.ShutdownTimeout
+		}
+	})
+	return time.Duration(timeout) * time.Second
+}
+
+// Dispatch will run fn in a new goroutine that Drain waits for.
+// It returns false without running fn if the bot is shutting down, so that new events aren't handled.
+func Dispatch(fn func()) bool {
+	if !startWork() {
+		return false
+	}
+
+	go func() {
+		defer finishWork()
+		fn()
+	}()
+	return true
+}
+
+// Track will run fn in the current goroutine, and Drain will wait for it like with Dispatch.
+// This is used for work that is already in its own goroutine, such as the gateway handlers of plugins.
+func Track(fn func()) bool {
+	if !startWork() {
+		return false
+	}
+
+	defer finishWork()
+	fn()
+	return true
+}
+
+// ShuttingDown will return a context that is done once Drain is called, so that long-running work can stop early
+func ShuttingDown() context.Context {
+	return shuttingDown
+}
+
+// Running will return how much work started by Dispatch and Track is still running
+func Running() int {
+	workers.Lock()
+	defer workers.Unlock()
+	return workers.running
+}
+
+// Drain will stop Dispatch and Track from starting new work, and wait until the running work is done, or timeout has
+// passed. It returns false if work was still running after timeout.
+func Drain(timeout time.Duration) bool {
+	workers.Lock()
+	running, idle := workers.running, workers.idle
+	if !workers.draining && running == 0 {
+		close(idle)
+	}
+	workers.draining = true
+	workers.Unlock()
+
+	stopWork()
+	slog.Info("waiting for running work", "running", running, "timeout", timeout)
+
+	select {
+	case <-idle:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// StopScheduler will stop the Scheduler, and wait until its running jobs are done, or timeout has passed.
+// It returns false if jobs were still running after timeout.
+func StopScheduler(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		Scheduler.Stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func startWork() bool {
+	workers.Lock()
+	defer workers.Unlock()
+
+	if workers.draining {
+		return false
+	}
+
+	workers.running++
+	WorkersRunning.Inc()
+	return true
+}
+
+func finishWork() {
+	workers.Lock()
+	defer workers.Unlock()
+
+	workers.running--
+	if workers.draining && workers.running == 0 {
+		close(workers.idle)
+	}
+	WorkersRunning.Dec()
+}
@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func resetWorkers() {
+	workers.Lock()
+	defer workers.Unlock()
+	workers.draining = false
+	workers.idle = make(chan struct{})
+	shuttingDown, stopWork = context.WithCancel(context.Background())
+}
+
+func TestDrain(t *testing.T) {
+	t.Cleanup(resetWorkers)
+
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	if !Dispatch(func() {
+		<-release
+		close(finished)
+	}) {
+		t.Fatalf("expected work to be dispatched before shutting down")
+	}
+
+	tracked := false
+	Track(func() { tracked = true })
+	if !tracked || Running() != 1 {
+		t.Errorf("expected tracked work to run straight away, and only the dispatched work to be running, got %v", Running())
+	}
+
+	if Drain(10 * time.Millisecond) {
+		t.Errorf("expected draining to time out while work is running")
+	}
+	if ShuttingDown().Err() == nil {
+		t.Errorf("expected the shutting down context to be done")
+	}
+	if Dispatch(func() { t.Errorf("expected no new work to run while shutting down") }) {
+		t.Errorf("expected new work to be refused while shutting down")
+	}
+
+	close(release)
+	if !Drain(time.Second) {
+		t.Errorf("expected draining to finish once the work is done")
+	}
+	select {
+	case <-finished:
+	default:
+		t.Errorf("expected the running work to finish before draining returns")
+	}
+	if n := Running(); n != 0 {
+		t.Errorf("expected no running work, got %v", n)
+	}
+}
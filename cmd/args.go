@@ -310,5 +310,5 @@ func checkArgExists(a []string, pos int, fn string) (s string, err *bot.Error) {
 
 	// the position in the command the user is giving
 	pos += 1
-	return "", bot.GenericError(fn, "getting arg "+strconv.Itoa(pos), "arg is missing")
+	return "", &bot.Error{Func: fn, Action: "getting arg " + strconv.Itoa(pos), Err: "arg is missing", Kind: bot.KindSyntax}
 }
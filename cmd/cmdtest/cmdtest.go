@@ -0,0 +1,177 @@
+// Package cmdtest runs commands, responses, handlers and components against a fakediscord.Discord, so that plugins can be
+// tested without connecting to Discord. A test creates a Harness with the plugins it tests, sends messages, reacts and
+// clicks buttons with it, and then checks the messages and state of the fake.
+package cmdtest
+
+import (
+	"github.com/5HT2/taro-bot/bot"
+	"github.com/5HT2/taro-bot/bot/fakediscord"
+	"github.com/5HT2/taro-bot/cmd"
+	"github.com/5HT2/taro-bot/plugins"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"strings"
+	"testing"
+)
+
+// Harness is a guild with a text channel on a fake Discord, where the bot runs the registered plugins.
+// Everything it does is synchronous, so the results can be checked as soon as a method returns.
+type Harness struct {
+	T       *testing.T
+	Discord *fakediscord.Discord
+	Guild   discord.Guild
+	Channel discord.Channel
+	User    discord.Member // User is a member without any permissions, who sends messages by default
+	Owner   discord.Member // Owner is the owner of the guild, who has every permission except bot operator
+}
+
+// New will create a Harness, and register ps as the only plugins. The bot is reset when the test finishes.
+func New(t *testing.T, ps ...*plugins.Plugin) *Harness {
+	d := fakediscord.New("taro")
+	h := &Harness{T: t, Discord: d}
+
+	bot.Client = d
+	bot.User, _ = d.Me()
+	bot.C.Run(func(c *bot.Config) {
+		c.PrefixCache = make(map[int64]string)
+		c.GuildConfigs = nil
+		c.OperatorIDs = nil
+		c.OperatorChannel = 0
+		c.UserLocales = nil
+	})
+
+	h.Owner = h.NewMember("owner")
+	h.User = h.NewMember("user")
+
+	h.Guild = discord.Guild{ID: discord.GuildID(d.NewID()), Name: "Test Guild", OwnerID: h.Owner.User.ID}
+	h.Channel = discord.Channel{ID: discord.ChannelID(d.NewID()), GuildID: h.Guild.ID, Type: discord.GuildText, Name: "general"}
+	d.PutGuild(h.Guild)
+	d.PutChannel(h.Channel)
+	d.PutMember(h.Guild.ID, h.Owner)
+	d.PutMember(h.Guild.ID, h.User)
+
+	// This adds the guild's config, like when the bot joins a guild
+	bot.GuildContext(h.Guild.ID, func(g *bot.GuildConfig) (*bot.GuildConfig, string) {
+		return g, "cmdtest.New"
+	})
+
+	plugins.RegisterPlugins(ps...)
+
+	t.Cleanup(func() {
+		plugins.Shutdown()
+		plugins.RegisterPlugins()
+	})
+
+	return h
+}
+
+// NewMember will create a user named username, and add them to the guild if it exists
+func (h *Harness) NewMember(username string) discord.Member {
+	m := discord.Member{User: discord.User{ID: discord.UserID(h.Discord.NewID()), Username: username, Discriminator: "0001"}}
+	if h.Guild.ID.IsValid() {
+		h.Discord.PutMember(h.Guild.ID, m)
+	} else {
+		h.Discord.PutUser(m.User)
+	}
+	return m
+}
+
+// Operator will make m a bot operator
+func (h *Harness) Operator(m discord.Member) {
+	bot.C.Run(func(c *bot.Config) {
+		c.OperatorIDs = append(c.OperatorIDs, int64(m.User.ID))
+	})
+}
+
+// Send will send content to the channel as User, and run the commands and responses for it
+func (h *Harness) Send(content string) *gateway.MessageCreateEvent {
+	return h.SendAs(h.User, content)
+}
+
+// SendAs will send content to the channel as m, and run the commands and responses for it
+func (h *Harness) SendAs(m discord.Member, content string) *gateway.MessageCreateEvent {
+	msg := h.Discord.PutMessage(discord.Message{
+		ChannelID: h.Channel.ID,
+		GuildID:   h.Guild.ID,
+		Author:    m.User,
+		Content:   content,
+	})
+
+	e := &gateway.MessageCreateEvent{Message: msg, Member: &m}
+	cmd.CommandHandler(e)
+	cmd.ResponseHandler(e)
+	return e
+}
+
+// React will add a reaction with a unicode emoji to msg as m, and dispatch the event to the handlers of plugins
+func (h *Harness) React(msg discord.Message, emoji discord.APIEmoji, m discord.Member) {
+	if err := h.Discord.PutReaction(msg.ChannelID, msg.ID, emoji, m.User); err != nil {
+		h.T.Fatalf("reacting to %v: %v", msg.ID, err)
+	}
+
+	h.Discord.Dispatch(&gateway.MessageReactionAddEvent{
+		UserID:    m.User.ID,
+		ChannelID: msg.ChannelID,
+		MessageID: msg.ID,
+		Emoji:     discord.Emoji{Name: string(emoji)},
+		GuildID:   msg.GuildID,
+		Member:    &m,
+	})
+}
+
+// Click will click the button with id on msg as m
+func (h *Harness) Click(msg discord.Message, id discord.ComponentID, m discord.Member) {
+	cmd.InteractionHandler(&gateway.InteractionCreateEvent{InteractionEvent: discord.InteractionEvent{
+		ID:        discord.InteractionID(h.Discord.NewID()),
+		Data:      &discord.ButtonInteraction{CustomID: id},
+		ChannelID: msg.ChannelID,
+		Token:     "token",
+		Message:   &msg,
+		Member:    &m,
+		GuildID:   msg.GuildID,
+	}})
+}
+
+// Replies will return the messages that the bot has sent to the channel, oldest first
+func (h *Harness) Replies() []discord.Message {
+	replies := make([]discord.Message, 0)
+	for _, m := range h.Discord.Messages(h.Channel.ID) {
+		if m.Author.ID == bot.User.ID {
+			replies = append(replies, m)
+		}
+	}
+	return replies
+}
+
+// LastReply will return the last message that the bot has sent to the channel, and fail the test if there isn't one
+func (h *Harness) LastReply() discord.Message {
+	h.T.Helper()
+
+	replies := h.Replies()
+	if len(replies) == 0 {
+		h.T.Fatalf("expected the bot to reply")
+	}
+	return replies[len(replies)-1]
+}
+
+// Text will return the content of msg, and the titles, descriptions and fields of its embeds, one on each line
+func Text(msg discord.Message) string {
+	lines := make([]string, 0)
+	if len(msg.Content) > 0 {
+		lines = append(lines, msg.Content)
+	}
+
+	for _, e := range msg.Embeds {
+		for _, s := range []string{e.Title, e.Description} {
+			if len(s) > 0 {
+				lines = append(lines, s)
+			}
+		}
+
+		for _, f := range e.Fields {
+			lines = append(lines, f.Name+": "+f.Value)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
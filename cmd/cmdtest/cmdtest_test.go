@@ -0,0 +1,184 @@
+package cmdtest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/5HT2/taro-bot/bot"
+	"github.com/5HT2/taro-bot/cmd"
+	"github.com/5HT2/taro-bot/plugins"
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testPlugin() *plugins.Plugin {
+	return &plugins.Plugin{
+		Name:      "Test",
+		ConfigDir: "test",
+		Commands: []bot.CommandInfo{{
+			Fn: func(c bot.Command) error {
+				_, err := cmd.SendEmbed(c.E, "Pong", strings.Join(c.Args, " "), bot.DefaultColor)
+				return err
+			},
+			FnName: "PingCommand",
+			Name:   "ping",
+		}, {
+			Fn: func(c bot.Command) error {
+				lines := make([]string, 0)
+				for n := 0; n < 40; n++ {
+					lines = append(lines, fmt.Sprintf("line %v", n))
+				}
+				_, err := cmd.SendPaginator(c.E, cmd.PageLines("Lines", lines, bot.DefaultColor))
+				return err
+			},
+			FnName: "LinesCommand",
+			Name:   "lines",
+		}, {
+			Fn: func(c bot.Command) error {
+				_, err := cmd.SendEmbed(c.E, "Secret", "", bot.DefaultColor)
+				return err
+			},
+			FnName:     "SecretCommand",
+			Name:       "secret",
+			Permission: "moderate",
+		}, {
+			Fn: func(c bot.Command) error {
+				if _, err := cmd.ParseInt64Arg(c.Args, 1); err != nil {
+					return err
+				}
+				return bot.InternalError(c.FnName, "counting", "couldn't count", errors.New("counter is broken"))
+			},
+			FnName: "CountCommand",
+			Name:   "count",
+			Usage:  "<number>",
+		}},
+		Responses: []bot.ResponseInfo{{
+			Fn: func(r bot.Response) {
+				_, _ = cmd.SendMessage(r.E, "hello!")
+			},
+			Regexes:  []string{"^hi$"},
+			MatchMin: 1,
+		}},
+		Handlers: []bot.HandlerInfo{{
+			Fn: func(i interface{}) {
+				e := i.(*gateway.MessageReactionAddEvent)
+				_, _ = bot.Client.SendMessage(e.ChannelID, "reacted with "+e.Emoji.Name)
+			},
+			FnName: "ReactionHandler",
+			FnType: reflect.TypeOf(func(*gateway.MessageReactionAddEvent) {}),
+		}},
+	}
+}
+
+func TestCommand(t *testing.T) {
+	h := New(t, testPlugin())
+
+	h.Send(bot.DefaultPrefix + "ping a b")
+	if got := Text(h.LastReply()); got != "Pong\na b" {
+		t.Errorf("expected the ping command to reply, got %q", got)
+	}
+
+	h.Send(bot.DefaultPrefix + "pingg")
+	if got := Text(h.LastReply()); !strings.Contains(got, "ping") {
+		t.Errorf("expected ping to be suggested, got %q", got)
+	}
+}
+
+func TestResponse(t *testing.T) {
+	h := New(t, testPlugin())
+
+	h.Send("hi")
+	if got := h.LastReply().Content; got != "hello!" {
+		t.Errorf("expected the response to reply, got %q", got)
+	}
+
+	h.Send("hi there")
+	if got := len(h.Replies()); got != 1 {
+		t.Errorf("expected only one reply, got %v", got)
+	}
+}
+
+func TestPermission(t *testing.T) {
+	h := New(t, testPlugin())
+
+	h.Send(bot.DefaultPrefix + "secret")
+	if got := h.LastReply().Embeds[0]; got.Color != bot.WarnColor || !strings.Contains(got.Description, "missing the \"moderate\" permission") {
+		t.Errorf("expected the user to be missing the permission, got %q", got.Description)
+	}
+
+	h.SendAs(h.Owner, bot.DefaultPrefix+"secret")
+	if got := Text(h.LastReply()); got != "Secret" {
+		t.Errorf("expected the owner to be able to use the command, got %q", got)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	h := New(t, testPlugin())
+
+	h.Send(bot.DefaultPrefix + "count")
+	got := h.LastReply().Embeds[0]
+	if got.Color != bot.WarnColor || len(got.Fields) != 1 || got.Fields[0].Value != "`count <number>`" {
+		t.Errorf("expected a syntax error with the usage of the command, got %+v", got)
+	}
+
+	h.Send(bot.DefaultPrefix + "count 1")
+	got = h.LastReply().Embeds[0]
+	if got.Color != bot.ErrorColor || got.Description != "couldn't count" {
+		t.Errorf("expected an internal error without its cause, got %+v", got)
+	}
+
+	reported := false
+	for _, r := range bot.ErrorReports() {
+		if r.Source == "command" && r.Plugin == "test" && r.Detail == "counter is broken" {
+			reported = true
+		}
+	}
+	if !reported {
+		t.Errorf("expected the internal error to be reported with its cause")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h := New(t, testPlugin())
+
+	e := h.Send("react to me")
+	h.React(e.Message, "⭐", h.User)
+
+	if got := h.LastReply().Content; got != "reacted with ⭐" {
+		t.Errorf("expected the handler to run, got %q", got)
+	}
+}
+
+func TestPaginator(t *testing.T) {
+	h := New(t, testPlugin())
+
+	h.Send(bot.DefaultPrefix + "lines")
+	msg := h.LastReply()
+	if len(msg.Components) != 1 {
+		t.Fatalf("expected the paginator to have buttons")
+	}
+
+	row := msg.Components[0].(*discord.ActionRowComponent)
+	next := (*row)[1].(*discord.ButtonComponent).CustomID
+
+	// Only the user that ran the command can turn the pages
+	h.Click(msg, next, h.Owner)
+	h.Click(msg, next, h.User)
+
+	responses := h.Discord.Responses()
+	if len(responses) != 2 {
+		t.Fatalf("expected 2 responses, got %v", len(responses))
+	}
+
+	if r := responses[0].Response; r.Type != api.MessageInteractionWithSource || r.Data.Flags != discord.EphemeralMessage {
+		t.Errorf("expected the owner to be told that they can't turn the pages, got %v", r.Type)
+	}
+
+	r := responses[1].Response
+	if r.Type != api.UpdateMessage || r.Data.Embeds == nil || !strings.HasPrefix((*r.Data.Embeds)[0].Description, "line 15") {
+		t.Errorf("expected the second page, got %v", r.Data)
+	}
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"github.com/5HT2/taro-bot/bot"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"golang.org/x/text/message"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+var (
+	commandIndex      map[string]bot.CommandInfo // [name or alias]CommandInfo
+	commandIndexMutex sync.RWMutex
+
+	SuggestionCooldown = []bot.Cooldown{{Scope: bot.CooldownChannel, Duration: 10 * time.Second}}
+	maxSuggestions     = 3
+)
+
+// IndexCommands will index bot.Commands by their names and aliases, and has to be called whenever they change.
+// When two commands share a name or alias, the one that was registered first is used.
+func IndexCommands() {
+	index := make(map[string]bot.CommandInfo, len(bot.Commands))
+	add := func(name string, cmd bot.CommandInfo) {
+		if _, ok := index[name]; !ok {
+			index[name] = cmd
+		}
+	}
+
+	for _, cmd := range bot.Commands {
+		add(cmd.Name, cmd)
+		for _, alias := range cmd.Aliases {
+			add(alias, cmd)
+		}
+	}
+
+	commandIndexMutex.Lock()
+	defer commandIndexMutex.Unlock()
+	commandIndex = index
+}
+
+// suggestCommands will reply with commands that have a name similar to cmdName, which is not a command
+func suggestCommands(e *gateway.MessageCreateEvent, cmdName string) {
+	// Suggestions are only sent in guilds, because every DM is parsed as a command
+	if !e.GuildID.IsValid() || !isCommandName(cmdName) {
+		return
+	}
+
+	suggestions := similarCommands(cmdName)
+	if len(suggestions) == 0 {
+		return
+	}
+
+	disabled := false
+	bot.GuildContext(e.GuildID, func(g *bot.GuildConfig) (*bot.GuildConfig, string) {
+		disabled = g.NoSuggestions
+		return g, "suggestCommands"
+	})
+
+	if disabled {
+		return
+	}
+
+	if remaining, _ := bot.UseCooldowns("suggestions", SuggestionCooldown, e); remaining > 0 {
+		return
+	}
+
+	for n, s := range suggestions {
+		suggestions[n] = "`" + s + "`"
+	}
+
+	p := bot.Printer(e.GuildID, e.Author.ID)
+	if _, err := SendEmbed(e, "", p.Sprintf("Unknown command `%s`, did you mean %s?", cmdName, joinOr(p, suggestions)), bot.WarnColor); err != nil {
+		bot.EventLogger(e).Error("error sending command suggestions", "err", err)
+	}
+}
+
+// similarCommands will return the names and aliases closest to name, that are within a small edit distance
+func similarCommands(name string) []string {
+	commandIndexMutex.RLock()
+	defer commandIndexMutex.RUnlock()
+
+	// Short names have fewer letters to get wrong, so they are allowed fewer mistakes
+	maxDistance := 1
+	if len(name) > 4 {
+		maxDistance = 2
+	}
+
+	type suggestion struct {
+		name     string
+		distance int
+	}
+
+	// Aliases are suggested by their command's name, so each command is only suggested once, by its closest key
+	distances := make(map[string]int) // [command name]distance
+	for key, cmd := range commandIndex {
+		if d := levenshtein(name, key); d <= maxDistance {
+			if prev, ok := distances[cmd.Name]; !ok || d < prev {
+				distances[cmd.Name] = d
+			}
+		}
+	}
+
+	suggestions := make([]suggestion, 0, len(distances))
+	for cmdName, d := range distances {
+		suggestions = append(suggestions, suggestion{name: cmdName, distance: d})
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].distance != suggestions[j].distance {
+			return suggestions[i].distance < suggestions[j].distance
+		}
+		return suggestions[i].name < suggestions[j].name
+	})
+
+	names := make([]string, 0, maxSuggestions)
+	for n, s := range suggestions {
+		if n >= maxSuggestions {
+			break
+		}
+		names = append(names, s.name)
+	}
+	return names
+}
+
+// isCommandName will return if s looks like an attempt at a command, instead of something like "..." or ".5"
+func isCommandName(s string) bool {
+	if len(s) < 3 {
+		return false
+	}
+
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// levenshtein will return the number of single character edits needed to change a into b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			// Deleting, inserting or substituting a character
+			cur[j] = prev[j] + 1
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+			if prev[j-1]+cost < cur[j] {
+				cur[j] = prev[j-1] + cost
+			}
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// joinOr will join s like "a, b or c", translated by p
+func joinOr(p *message.Printer, s []string) string {
+	if len(s) <= 1 {
+		return strings.Join(s, "")
+	}
+	return p.Sprintf("%s or %s", strings.Join(s[:len(s)-1], ", "), s[len(s)-1])
+}
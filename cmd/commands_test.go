@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"github.com/5HT2/taro-bot/bot"
+	"strings"
+	"testing"
+)
+
+func TestSimilarCommands(t *testing.T) {
+	bot.Commands = []bot.CommandInfo{
+		{Name: "messagetop", Aliases: []string{"msgtop", "leaderboard"}},
+		{Name: "systemstats", Aliases: []string{"stats", "stat", "sysstat"}},
+		{Name: "starboard"},
+		{Name: "help", Aliases: []string{"h"}},
+	}
+	IndexCommands()
+
+	tests := map[string]string{
+		"leaderbord": "messagetop",
+		"stasts":     "systemstats",
+		"starbord":   "starboard",
+		"hlep":       "",
+		"helpp":      "help",
+		"frog":       "",
+	}
+
+	for name, expected := range tests {
+		if got := strings.Join(similarCommands(name), " "); got != expected {
+			t.Errorf("%s: expected %q, got %q", name, expected, got)
+		}
+	}
+
+	if cmd := getCommandWithName("stat"); cmd == nil || cmd.Name != "systemstats" {
+		t.Errorf("expected stat to be an alias of systemstats, got %v", cmd)
+	}
+}
+
+func TestSimilarCommandsClosestKey(t *testing.T) {
+	bot.Commands = []bot.CommandInfo{
+		{Name: "rank", Aliases: []string{"rinkss", "rankss", "ranksss"}},
+		{Name: "banks"},
+		{Name: "tanks"},
+		{Name: "yanks"},
+	}
+	IndexCommands()
+
+	// rank is one edit from ranks, so it has to be ranked by that instead of by an alias that is further away,
+	// no matter which of its keys is found first
+	for i := 0; i < 20; i++ {
+		if got := strings.Join(similarCommands("ranks"), " "); got != "banks rank tanks" {
+			t.Fatalf("expected the closest commands in order, got %q", got)
+		}
+	}
+}
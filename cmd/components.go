@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"github.com/5HT2/taro-bot/bot"
+	"github.com/5HT2/taro-bot/util"
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"log/slog"
+	"strings"
+)
+
+var (
+	// components are the components that cmd itself uses, and can't be replaced by plugins
+	components = []bot.ComponentInfo{
+		{Fn: paginatorComponent, FnName: "paginatorComponent", Prefix: paginatorPrefix},
+	}
+)
+
+// InteractionHandler will run the ComponentInfo registered for the prefix of a used button, select menu or modal
+func InteractionHandler(e *gateway.InteractionCreateEvent) {
+	defer util.LogPanic()
+
+	var c bot.Component
+	switch data := e.Data.(type) {
+	case *discord.ButtonInteraction:
+		c = bot.NewComponent(e, string(data.CustomID))
+	case *discord.SelectInteraction:
+		c = bot.NewComponent(e, string(data.CustomID))
+		c.Values = data.Values
+	case *discord.ModalInteraction:
+		c = bot.NewComponent(e, string(data.CustomID))
+		for _, container := range data.Components {
+			row, ok := container.(*discord.ActionRowComponent)
+			if !ok {
+				continue
+			}
+
+			for _, component := range *row {
+				if input, ok := component.(*discord.TextInputComponent); ok {
+					c.Fields[string(input.CustomID)] = input.Value
+				}
+			}
+		}
+	default:
+		return
+	}
+
+	info := getComponentWithID(c.ID)
+	if info == nil {
+		slog.Warn("no component registered", "component", c.ID)
+		return
+	}
+	c.FnName = info.FnName
+
+	if err := info.Fn(c); err != nil {
+		slog.Info("error with component", "component", c.ID, "err", err)
+		sendComponentError(c, err)
+		return
+	}
+
+	// Every interaction has to be responded to, otherwise Discord shows that it failed
+	if !c.Responded() {
+		if err := c.Respond(api.InteractionResponse{Type: api.DeferredMessageUpdate}); err != nil {
+			slog.Error("error acknowledging component", "component", c.ID, "err", err)
+		}
+	}
+}
+
+// getComponentWithID will return the ComponentInfo whose prefix is the start of id, checking the components of cmd first
+func getComponentWithID(id string) *bot.ComponentInfo {
+	prefix, _, _ := strings.Cut(id, ":")
+
+	for _, component := range append(components, bot.Components...) {
+		if component.Prefix == prefix {
+			return &component
+		}
+	}
+
+	return nil
+}
+
+// sendComponentError will show err to the user of c, or in the channel if the interaction was already responded to
+func sendComponentError(c bot.Component, err error) {
+	if c.Responded() {
+		_, _ = SendCustomEmbed(c.E.ChannelID, ErrorEmbed(c.Printer(), c.FnName, "", err))
+		return
+	}
+
+	if err := RespondEmbed(c, ErrorEmbed(c.Printer(), c.FnName, "", err), true); err != nil {
+		slog.Error("error sending component error", "err", err)
+	}
+}
+
+// RespondEmbed will respond to c with a new message, which only the user of c can see if ephemeral is set
+func RespondEmbed(c bot.Component, embed discord.Embed, ephemeral bool) error {
+	data := &api.InteractionResponseData{Embeds: &[]discord.Embed{embed}}
+	if ephemeral {
+		data.Flags = discord.EphemeralMessage
+	}
+
+	return c.Respond(api.InteractionResponse{Type: api.MessageInteractionWithSource, Data: data})
+}
+
+// UpdateComponentMessage will respond to c by editing the message that the component is attached to.
+// A nil components leaves the components of the message unchanged.
+func UpdateComponentMessage(c bot.Component, content string, embeds []discord.Embed, components *discord.ContainerComponents) error {
+	data := &api.InteractionResponseData{Embeds: &embeds, Components: components}
+	if len(content) > 0 {
+		data.Content = option.NewNullableString(content)
+	}
+
+	return c.Respond(api.InteractionResponse{Type: api.UpdateMessage, Data: data})
+}
+
+// ShowModal will respond to c by showing a modal with inputs, which is handled by the ComponentInfo registered for id
+func ShowModal(c bot.Component, id discord.ComponentID, title string, inputs ...*discord.TextInputComponent) error {
+	components := make(discord.ContainerComponents, len(inputs))
+	for n, input := range inputs {
+		components[n] = &discord.ActionRowComponent{input}
+	}
+
+	return c.Respond(api.InteractionResponse{
+		Type: api.ModalResponse,
+		Data: &api.InteractionResponseData{
+			CustomID:   option.NewNullableString(string(id)),
+			Title:      option.NewNullableString(title),
+			Components: &components,
+		},
+	})
+}
+
+// SendComponents will send a message with components to the channel of e, which is tracked like other replies to e
+func SendComponents(e *gateway.MessageCreateEvent, content string, embeds []discord.Embed, components discord.ContainerComponents) (*discord.Message, error) {
+	msg, err := sendReplyComponents(e, content, embeds, components)
+	if err != nil {
+		slog.Error("error sending components", "err", err)
+	}
+	return msg, err
+}
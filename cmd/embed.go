@@ -6,7 +6,8 @@ import (
 	"github.com/5HT2/taro-bot/util"
 	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/diamondburned/arikawa/v3/gateway"
-	"log"
+	"golang.org/x/text/message"
+	"log/slog"
 	"strings"
 )
 
@@ -16,7 +17,7 @@ func SendCustomEmbed(c discord.ChannelID, e ...discord.Embed) (*discord.Message,
 		e...,
 	)
 	if err != nil {
-		log.Printf("Error sending embed: %v (%v)", err, e)
+		slog.Error("error sending embed", "channel", c, "embeds", e, "err", err)
 	}
 	return msg, err
 }
@@ -27,17 +28,58 @@ func SendCustomMessage(c discord.ChannelID, content string) (*discord.Message, e
 		content,
 	)
 	if err != nil {
-		log.Printf("Error sending message: %v", err)
+		slog.Error("error sending message", "channel", c, "err", err)
 	}
 	return msg, err
 }
 
 func SendExternalErrorEmbed(c discord.ChannelID, cmdName string, err error) (*discord.Message, error) {
-	return SendCustomEmbed(c, MakeEmbed("Error running `"+cmdName+"`", err.Error(), bot.ErrorColor))
+	return SendCustomEmbed(c, ErrorEmbed(util.Printer(util.DefaultLocale), cmdName, "", err))
 }
 
+// SendErrorEmbed will reply to c with err, and the usage of the command for syntax errors
 func SendErrorEmbed(c bot.Command, err error) {
-	_, _ = SendEmbed(c.E, "Error running `"+c.Name+"`", err.Error(), bot.ErrorColor)
+	usage := ""
+	if info := getCommandWithName(c.Name); info != nil && len(info.Usage) > 0 {
+		usage = info.Name + " " + info.Usage
+	}
+
+	embed := ErrorEmbed(c.Printer(), c.Name, usage, err)
+	if _, sendErr := sendReply(c.E, "", embed); sendErr != nil {
+		c.Log().Error("error sending error embed", "err", sendErr)
+	}
+}
+
+// ErrorEmbed will return an embed showing the user error of err from the command or component with name, translated by p.
+// Its color and hint depend on the bot.ErrorKind of err, and syntax errors show usage if it isn't empty.
+func ErrorEmbed(p *message.Printer, name, usage string, err error) discord.Embed {
+	kind := bot.ErrorKindOf(err)
+	embed := MakeEmbed(p.Sprintf("Error running `%s`", name), bot.UserError(err), bot.ErrorColor)
+
+	hint := ""
+	switch kind {
+	case bot.KindSyntax:
+		embed.Color = bot.WarnColor
+		hint = p.Sprintf("Use `help %s` to see how to use it", name)
+	case bot.KindPermission:
+		embed.Color = bot.WarnColor
+		hint = p.Sprintf("Ask a moderator of this server if you think you should be able to do this")
+	case bot.KindNotFound:
+		embed.Color = bot.WarnColor
+		hint = p.Sprintf("Check the spelling, or look for it with `help`")
+	case bot.KindUpstream:
+		hint = p.Sprintf("Discord or an external service failed, try again later")
+	case bot.KindInternal:
+		hint = p.Sprintf("This is a bug, the bot operators have been notified")
+	}
+
+	if kind == bot.KindSyntax && len(usage) > 0 {
+		embed.Fields = append(embed.Fields, discord.EmbedField{Name: p.Sprintf("Usage"), Value: "`" + usage + "`"})
+	}
+	if len(hint) > 0 {
+		embed.Footer = &discord.EmbedFooter{Text: hint}
+	}
+	return embed
 }
 
 func SendEmbed(e *gateway.MessageCreateEvent, title, description string, color discord.Color) (*discord.Message, error) {
@@ -47,23 +89,17 @@ func SendEmbed(e *gateway.MessageCreateEvent, title, description string, color d
 func SendEmbedFooter(e *gateway.MessageCreateEvent, title, description, footer string, color discord.Color) (*discord.Message, error) {
 	embed := MakeEmbed(title, description, color)
 	embed.Footer = &discord.EmbedFooter{Text: footer}
-	msg, err := bot.Client.SendEmbeds(
-		e.ChannelID,
-		embed,
-	)
+	msg, err := sendReply(e, "", embed)
 	if err != nil {
-		log.Printf("Error sending embed: %v (%v)", err, embed)
+		bot.EventLogger(e).Error("error sending embed", "embed", embed, "err", err)
 	}
 	return msg, err
 }
 
 func SendMessage(e *gateway.MessageCreateEvent, content string) (*discord.Message, error) {
-	msg, err := bot.Client.SendMessage(
-		e.ChannelID,
-		content,
-	)
+	msg, err := sendReply(e, content)
 	if err != nil {
-		log.Printf("Error sending message: %v", err)
+		bot.EventLogger(e).Error("error sending message", "err", err)
 	}
 	return msg, err
 }
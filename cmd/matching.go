@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/5HT2/taro-bot/bot"
+	"log/slog"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+var (
+	responseIndex      []compiledResponse
+	responseIndexMutex sync.RWMutex
+)
+
+// compiledResponse is a bot.ResponseInfo with its Regexes compiled, see CompileResponses
+type compiledResponse struct {
+	info    bot.ResponseInfo
+	key     string // key is unique for each response, and is used for its Cooldowns, see responseKey
+	regexes []compiledRegex
+}
+
+// compiledRegex is a compiled regex, with a prefilter that is checked before running the regex
+type compiledRegex struct {
+	re       *regexp.Regexp
+	literals []literal // literals are required for re to match, a message has to contain at least one of them
+	anyChar  bool      // anyChar is if re matches any message that has a character in it, such as "."
+}
+
+// literal is a string that a regex needs to match, lowercase if fold is set
+type literal struct {
+	s    string
+	fold bool
+}
+
+// matchText is the content being matched, with a lowercase copy that is only made when needed
+type matchText struct {
+	content string
+	lower   string
+	lowered bool
+}
+
+func (m *matchText) contains(l literal) bool {
+	if !l.fold {
+		return strings.Contains(m.content, l.s)
+	}
+
+	if !m.lowered {
+		m.lower = strings.ToLower(m.content)
+		m.lowered = true
+	}
+	return strings.Contains(m.lower, l.s)
+}
+
+// CompileResponses will compile the Regexes of bot.Responses, and has to be called whenever they change.
+// DISCORD_BOT_ID is replaced with the bot's user id in each regex here, so bot.User has to be set first.
+func CompileResponses() {
+	botID := "0"
+	if bot.User != nil {
+		botID = bot.User.ID.String()
+	}
+
+	index := make([]compiledResponse, 0, len(bot.Responses))
+	keys := make(map[string]int)
+	for _, response := range bot.Responses {
+		c := compiledResponse{
+			info:    response,
+			key:     responseKey(response, keys),
+			regexes: make([]compiledRegex, 0, len(response.Regexes)),
+		}
+
+		for _, regex := range response.Regexes {
+			// Allow using a variable in the regex to represent the current bot user
+			regex = strings.ReplaceAll(regex, "DISCORD_BOT_ID", botID)
+
+			compiled, err := compileRegex(regex)
+			if err != nil {
+				// The regex can never match, but it is kept so that MatchMin still counts it
+				slog.Error("error compiling response regex", "regex", regex, "err", err)
+			}
+			c.regexes = append(c.regexes, compiled)
+		}
+
+		index = append(index, c)
+	}
+
+	// Higher priority responses run first, and responses with the same priority run in the order they were registered
+	sort.SliceStable(index, func(i, j int) bool {
+		return index[i].info.Priority > index[j].info.Priority
+	})
+
+	responseIndexMutex.Lock()
+	defer responseIndexMutex.Unlock()
+	responseIndex = index
+}
+
+// responseKey will return the key of the Cooldowns of response, made from its plugin and regexes, so that it stays the
+// same when plugins are loaded or disabled. Responses of a plugin with the same regexes are numbered in the order they
+// were registered, using the keys that were already returned in used.
+func responseKey(response bot.ResponseInfo, used map[string]int) string {
+	key := "response " + response.Plugin + " " + strings.Join(response.Regexes, " ")
+	used[key]++
+	if n := used[key]; n > 1 {
+		key += fmt.Sprintf(" #%v", n)
+	}
+	return key
+}
+
+// matchResponses will return the responses that match content, sorted by priority
+func matchResponses(content string) []compiledResponse {
+	responseIndexMutex.RLock()
+	index := responseIndex
+	responseIndexMutex.RUnlock()
+
+	if index == nil {
+		CompileResponses()
+		return matchResponses(content)
+	}
+
+	m := &matchText{content: content}
+	matched := make([]compiledResponse, 0)
+
+	for _, response := range index {
+		if response.match(m) {
+			matched = append(matched, response)
+		}
+	}
+
+	return matched
+}
+
+// match will return if at least MatchMin of the regexes match m
+func (c compiledResponse) match(m *matchText) bool {
+	matched := 0
+	for n, regex := range c.regexes {
+		if regex.match(m) {
+			matched += 1
+		}
+
+		if matched >= c.info.MatchMin {
+			return true
+		}
+
+		// Stop early if the remaining regexes can't reach MatchMin
+		if matched+len(c.regexes)-n-1 < c.info.MatchMin {
+			return false
+		}
+	}
+
+	return false
+}
+
+func (r compiledRegex) match(m *matchText) bool {
+	if r.re == nil {
+		return false
+	}
+
+	if r.anyChar {
+		return len(strings.Trim(m.content, "\n")) > 0
+	}
+
+	if r.literals != nil {
+		found := false
+		for _, l := range r.literals {
+			if m.contains(l) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return r.re.MatchString(m.content)
+}
+
+func compileRegex(regex string) (compiledRegex, error) {
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return compiledRegex{}, err
+	}
+
+	c := compiledRegex{re: re}
+
+	// This is the same syntax that regexp.Compile uses, so it can't fail here
+	if parsed, err := syntax.Parse(regex, syntax.Perl); err == nil {
+		parsed = parsed.Simplify()
+		c.anyChar = parsed.Op == syntax.OpAnyCharNotNL
+		c.literals, _ = requiredLiterals(parsed)
+	}
+
+	return c, nil
+}
+
+// requiredLiterals will return a list of literals, where one of them has to be in a string for re to match it.
+// It returns false if there isn't one, for example when re starts with a character class.
+func requiredLiterals(re *syntax.Regexp) ([]literal, bool) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			// Some runes fold to more than one other rune, such as k, K and the Kelvin sign, which ToLower can't match
+			for _, r := range re.Rune {
+				if unicode.SimpleFold(unicode.SimpleFold(r)) != r {
+					return nil, false
+				}
+			}
+			return []literal{{s: strings.ToLower(string(re.Rune)), fold: true}}, true
+		}
+		return []literal{{s: string(re.Rune)}}, true
+	case syntax.OpCapture, syntax.OpPlus:
+		return requiredLiterals(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min >= 1 {
+			return requiredLiterals(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		// Any part of a concat is required, so use the one with the longest shortest literal, which filters the most
+		var best []literal
+		for _, sub := range re.Sub {
+			if literals, ok := requiredLiterals(sub); ok && shortestLiteral(literals) > shortestLiteral(best) {
+				best = literals
+			}
+		}
+		return best, best != nil
+	case syntax.OpAlternate:
+		// Each alternative can match, so each of them has to have literals
+		all := make([]literal, 0)
+		for _, sub := range re.Sub {
+			literals, ok := requiredLiterals(sub)
+			if !ok {
+				return nil, false
+			}
+			all = append(all, literals...)
+		}
+		return all, true
+	}
+
+	return nil, false
+}
+
+func shortestLiteral(literals []literal) int {
+	if literals == nil {
+		return 0
+	}
+
+	shortest := -1
+	for _, l := range literals {
+		if shortest == -1 || len(l.s) < shortest {
+			shortest = len(l.s)
+		}
+	}
+	return shortest
+}
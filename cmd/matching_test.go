@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/5HT2/taro-bot/bot"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupResponses will register responses similar to the default plugins, and n extra keyword responses
+func setupResponses(n int) {
+	bot.User = &discord.User{ID: 123456789012345678}
+	bot.Responses = []bot.ResponseInfo{
+		{Regexes: []string{"<@!?DISCORD_BOT_ID>", "(prefix|help)"}, MatchMin: 2},
+		{Regexes: []string{"<@!?DISCORD_BOT_ID>", "(test|help)"}, MatchMin: 2},
+		{Regexes: []string{`http(s)?://t([ex])nor\.[A-z]+/view/.*`}, MatchMin: 1},
+		{Regexes: []string{`https?://open\.spotify\.com/track/[a-zA-Z0-9]+`}, MatchMin: 1},
+		{Regexes: []string{"."}, MatchMin: 1},
+		{Regexes: []string{`(?i)\bgood (morning|night)\b`}, MatchMin: 1},
+		{Regexes: []string{`[0-9]{4}-[0-9]{2}-[0-9]{2}`}, MatchMin: 1},
+	}
+
+	for i := 0; i < n; i++ {
+		bot.Responses = append(bot.Responses, bot.ResponseInfo{
+			Regexes:  []string{fmt.Sprintf(`\bkeyword%d\b`, i), "(?i)please"},
+			MatchMin: 2,
+		})
+	}
+
+	CompileResponses()
+}
+
+// testMessages will return n messages, mostly regular chat with some that match responses
+func testMessages(n int) []string {
+	words := strings.Fields("the quick brown fox jumps over lazy dog hello there how are you doing today lol " +
+		"what is going on I think that is a great idea can we meet at noon maybe tomorrow instead")
+	special := []string{
+		"<@123456789012345678> help",
+		"<@!123456789012345678> what is the prefix",
+		"https://tenor.com/view/cat-dance-gif-12345",
+		"listen to https://open.spotify.com/track/4uLU6hMCjMI75M1A2tKUQC",
+		"GOOD MORNING everyone",
+		"it happened on 2022-10-05",
+		"keyword7 please",
+		"Keyword7 PLEASE",
+		"",
+		"\n\n",
+	}
+
+	r := rand.New(rand.NewSource(1))
+	messages := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		if i%10 == 0 {
+			messages = append(messages, special[r.Intn(len(special))])
+			continue
+		}
+
+		msg := make([]string, 3+r.Intn(20))
+		for j := range msg {
+			msg[j] = words[r.Intn(len(words))]
+		}
+		messages = append(messages, strings.Join(msg, " "))
+	}
+
+	return messages
+}
+
+// matchResponsesUncompiled is how responses used to be matched, with every regex compiled for every message
+func matchResponsesUncompiled(content string) []bot.ResponseInfo {
+	matched := make([]bot.ResponseInfo, 0)
+	for _, response := range bot.Responses {
+		n := 0
+		for _, regex := range response.Regexes {
+			regex = strings.ReplaceAll(regex, "DISCORD_BOT_ID", bot.User.ID.String())
+			if found, _ := regexp.MatchString(regex, content); found {
+				n += 1
+			}
+
+			if n >= response.MatchMin {
+				matched = append(matched, response)
+				break
+			}
+		}
+	}
+
+	return matched
+}
+
+func TestMatchResponses(t *testing.T) {
+	setupResponses(50)
+	bot.Responses = append(bot.Responses,
+		bot.ResponseInfo{Regexes: []string{"(?i)kelvin"}, MatchMin: 1},
+		bot.ResponseInfo{Regexes: []string{"(?i)ſtop|STOP"}, MatchMin: 1},
+		bot.ResponseInfo{Regexes: []string{"a+b{2,}c?"}, MatchMin: 1},
+		bot.ResponseInfo{Regexes: []string{"("}, MatchMin: 1},
+	)
+	CompileResponses()
+
+	messages := append(testMessages(2000), "Kelvin", "KELVIN", "stop", "abb", "ac")
+	for _, msg := range messages {
+		expected := matchResponsesUncompiled(msg)
+		got := matchResponses(msg)
+
+		if len(expected) != len(got) {
+			t.Fatalf("%q: expected %v responses, got %v", msg, len(expected), len(got))
+		}
+
+		for n := range expected {
+			if strings.Join(expected[n].Regexes, " ") != strings.Join(got[n].info.Regexes, " ") {
+				t.Fatalf("%q: expected %v, got %v", msg, expected[n].Regexes, got[n].info.Regexes)
+			}
+		}
+	}
+}
+
+func TestMatchResponsesPriority(t *testing.T) {
+	bot.User = &discord.User{ID: 123456789012345678}
+	bot.Responses = []bot.ResponseInfo{
+		{Regexes: []string{"a"}, MatchMin: 1},
+		{Regexes: []string{"b"}, MatchMin: 1, Priority: -1},
+		{Regexes: []string{"c"}, MatchMin: 1, Priority: 10},
+		{Regexes: []string{"d"}, MatchMin: 1},
+	}
+	CompileResponses()
+
+	got := make([]string, 0)
+	for _, response := range matchResponses("abcd") {
+		got = append(got, response.info.Regexes[0])
+	}
+
+	if expected := "c a d b"; strings.Join(got, " ") != expected {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestResponseKeys(t *testing.T) {
+	bot.User = &discord.User{ID: 123456789012345678}
+	tags := bot.ResponseInfo{Regexes: []string{"."}, MatchMin: 1, Plugin: "tags"}
+	bot.Responses = []bot.ResponseInfo{tags}
+	CompileResponses()
+	expected := matchResponses("hello")[0].key
+
+	// Loading another plugin before it shouldn't move the cooldowns of the response
+	bot.Responses = []bot.ResponseInfo{{Regexes: []string{"."}, MatchMin: 1, Plugin: "message-roles"}, tags, tags}
+	CompileResponses()
+
+	got := matchResponses("hello")
+	if got[1].key != expected {
+		t.Errorf("expected the key to stay %q, got %q", expected, got[1].key)
+	}
+	if got[0].key == got[1].key || got[1].key == got[2].key {
+		t.Errorf("expected unique keys, got %q, %q and %q", got[0].key, got[1].key, got[2].key)
+	}
+}
+
+func benchmarkMatch[T any](b *testing.B, responses int, match func(string) []T) {
+	setupResponses(responses)
+	messages := testMessages(1000)
+
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		match(messages[i%len(messages)])
+	}
+
+	b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "msgs/s")
+}
+
+func BenchmarkMatchResponses(b *testing.B) {
+	for _, n := range []int{0, 50, 500} {
+		b.Run(fmt.Sprintf("compiled/%v", n), func(b *testing.B) {
+			benchmarkMatch(b, n, matchResponses)
+		})
+		b.Run(fmt.Sprintf("uncompiled/%v", n), func(b *testing.B) {
+			benchmarkMatch(b, n, matchResponsesUncompiled)
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"github.com/5HT2/taro-bot/bot"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"golang.org/x/text/message"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+//
+// Paginators are sent with buttons to turn their pages, which only the user that ran the command can use.
+// The pages are saved as the component state of the paginator, keyed by the command message, so that editing the command
+// replaces its pages, and they expire once nobody has turned them for PaginatorTimeout.
+
+var (
+	PaginatorTimeout = 5 * time.Minute // PaginatorTimeout is how long the pages of a paginator can be turned after they were last turned
+	maxPageLength    = 2048            // maxPageLength is the most characters in the description of a page made by PageLines or PageText
+	maxPageLines     = 15              // maxPageLines is the most lines in a page made by PageLines
+	maxPageFields    = 6               // maxPageFields is the most fields in a page made by PageFields
+	maxFieldsLength  = 4000            // maxFieldsLength is the most characters in the fields of a page made by PageFields
+)
+
+const (
+	paginatorPrefix = "page"
+	pagePrev        = "prev"
+	pageNext        = "next"
+)
+
+type paginatorState struct {
+	Pages  []discord.Embed `json:"pages"`
+	Page   int             `json:"page"`
+	Author discord.UserID  `json:"author"`
+}
+
+// SendPaginator will send the first of pages, with buttons that the author of e can use to turn the pages
+func SendPaginator(e *gateway.MessageCreateEvent, pages []discord.Embed) (*discord.Message, error) {
+	if len(pages) == 0 {
+		return nil, bot.InternalError("SendPaginator", "sending pages", "there are no pages", nil)
+	}
+
+	pages = numberPages(bot.Printer(e.GuildID, e.Author.ID), pages)
+	id := bot.ComponentID(paginatorPrefix, e.ID.String())
+
+	if len(pages) == 1 {
+		// A command that used to have more pages before it was edited shouldn't keep them
+		bot.DeleteComponentState(string(id))
+		return sendReplyComponents(e, "", pages, nil)
+	}
+
+	if err := bot.SetComponentState(id, paginatorState{Pages: pages, Author: e.Author.ID}, PaginatorTimeout); err != nil {
+		return nil, err
+	}
+
+	return sendReplyComponents(e, "", pages[:1], paginatorButtons(e.ID.String()))
+}
+
+// paginatorComponent will turn the page of a paginator, when its author uses one of its buttons
+func paginatorComponent(c bot.Component) error {
+	key, button, _ := strings.Cut(c.Key, ":")
+	id := bot.ComponentID(paginatorPrefix, key)
+
+	var p paginatorState
+	if ok, err := bot.ComponentState(string(id), &p); err != nil {
+		return err
+	} else if !ok {
+		// The pages have expired, so remove the buttons that don't work anymore
+		embeds := make([]discord.Embed, 0)
+		if c.E.Message != nil {
+			embeds = c.E.Message.Embeds
+		}
+		return UpdateComponentMessage(c, "", embeds, &discord.ContainerComponents{})
+	}
+
+	if c.User().ID != p.Author {
+		return RespondEmbed(c, MakeEmbed("", c.Printer().Sprintf("Only the person who used the command can turn these pages."), bot.WarnColor), true)
+	}
+
+	if button == pagePrev {
+		p.Page = (p.Page - 1 + len(p.Pages)) % len(p.Pages)
+	} else {
+		p.Page = (p.Page + 1) % len(p.Pages)
+	}
+
+	if err := bot.SetComponentState(id, p, PaginatorTimeout); err != nil {
+		return err
+	}
+
+	return UpdateComponentMessage(c, "", p.Pages[p.Page:p.Page+1], nil)
+}
+
+// paginatorButtons will return the buttons of the paginator for the command message with key
+func paginatorButtons(key string) discord.ContainerComponents {
+	return discord.ContainerComponents{&discord.ActionRowComponent{
+		&discord.ButtonComponent{
+			Style:    discord.SecondaryButtonStyle(),
+			CustomID: bot.ComponentID(paginatorPrefix, key+":"+pagePrev),
+			Emoji:    &discord.ComponentEmoji{Name: "◀️"},
+		},
+		&discord.ButtonComponent{
+			Style:    discord.SecondaryButtonStyle(),
+			CustomID: bot.ComponentID(paginatorPrefix, key+":"+pageNext),
+			Emoji:    &discord.ComponentEmoji{Name: "▶️"},
+		},
+	}}
+}
+
+// PageLines will split lines into pages, which each have a title and color
+func PageLines(title string, lines []string, color discord.Color) []discord.Embed {
+	pages := make([]discord.Embed, 0)
+	page := make([]string, 0)
+	length := 0
+
+	for _, line := range splitLines(lines, maxPageLength) {
+		if len(page) > 0 && (len(page) >= maxPageLines || length+len(line)+1 > maxPageLength) {
+			pages = append(pages, MakeEmbed(title, strings.Join(page, "\n"), color))
+			page = make([]string, 0)
+			length = 0
+		}
+
+		page = append(page, line)
+		length += len(line) + 1
+	}
+
+	if len(page) > 0 || len(pages) == 0 {
+		pages = append(pages, MakeEmbed(title, strings.Join(page, "\n"), color))
+	}
+
+	return pages
+}
+
+// PageText will split text into pages, which each have a title and color. Lines are only split if they don't fit on a page.
+func PageText(title, text string, color discord.Color) []discord.Embed {
+	return pageText(title, text, color, "", "")
+}
+
+// PageCode will split text into pages like PageText, and put each page in a code block
+func PageCode(title, text string, color discord.Color) []discord.Embed {
+	return pageText(title, text, color, "```\n", "\n```")
+}
+
+func pageText(title, text string, color discord.Color, prefix, suffix string) []discord.Embed {
+	limit := maxPageLength - len(prefix) - len(suffix)
+	pages := make([]discord.Embed, 0)
+	page := ""
+
+	for _, line := range splitLines(strings.Split(text, "\n"), limit) {
+		if len(page) > 0 && len(page)+len(line)+1 > limit {
+			pages = append(pages, MakeEmbed(title, prefix+page+suffix, color))
+			page = ""
+		}
+
+		if len(page) > 0 {
+			page += "\n"
+		}
+		page += line
+	}
+
+	if len(page) > 0 || len(pages) == 0 {
+		pages = append(pages, MakeEmbed(title, prefix+page+suffix, color))
+	}
+
+	return pages
+}
+
+// PageFields will split fields into pages, which each have a title and color
+func PageFields(title string, fields []discord.EmbedField, color discord.Color) []discord.Embed {
+	pages := make([]discord.Embed, 0)
+	page := MakeEmbed(title, "", color)
+	length := 0
+
+	for _, field := range fields {
+		fieldLength := len(field.Name) + len(field.Value)
+		if len(page.Fields) > 0 && (len(page.Fields) >= maxPageFields || length+fieldLength > maxFieldsLength) {
+			pages = append(pages, page)
+			page = MakeEmbed(title, "", color)
+			length = 0
+		}
+
+		page.Fields = append(page.Fields, field)
+		length += fieldLength
+	}
+
+	if len(page.Fields) > 0 || len(pages) == 0 {
+		pages = append(pages, page)
+	}
+
+	return pages
+}
+
+// splitLines will split the lines that are longer than limit, so that every line fits on a page
+func splitLines(lines []string, limit int) []string {
+	split := make([]string, 0, len(lines))
+
+	for _, line := range lines {
+		for len(line) > limit {
+			// Don't split a character in half
+			n := limit
+			for n > 0 && !utf8.RuneStart(line[n]) {
+				n--
+			}
+
+			split = append(split, line[:n])
+			line = line[n:]
+		}
+
+		split = append(split, line)
+	}
+
+	return split
+}
+
+// numberPages will add the page number to the footer of each page, if there is more than one, translated by p
+func numberPages(p *message.Printer, pages []discord.Embed) []discord.Embed {
+	if len(pages) == 1 {
+		return pages
+	}
+
+	numbered := make([]discord.Embed, len(pages))
+	for n, page := range pages {
+		text := p.Sprintf("Page %v/%v", n+1, len(pages))
+		if page.Footer != nil && len(page.Footer.Text) > 0 {
+			text = page.Footer.Text + " • " + text
+		}
+
+		footer := discord.EmbedFooter{Text: text}
+		if page.Footer != nil {
+			footer.Icon = page.Footer.Icon
+		}
+
+		page.Footer = &footer
+		numbered[n] = page
+	}
+
+	return numbered
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/5HT2/taro-bot/util"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestPageCode(t *testing.T) {
+	lines := make([]string, 0)
+	for n := 0; n < 500; n++ {
+		lines = append(lines, fmt.Sprintf("line %v", n))
+	}
+	text := strings.Join(lines, "\n")
+
+	pages := PageCode("", text, 0)
+	if len(pages) < 2 {
+		t.Fatalf("expected more than one page, got %v", len(pages))
+	}
+
+	joined := make([]string, len(pages))
+	for n, page := range pages {
+		if len(page.Description) > maxPageLength {
+			t.Errorf("page %v is %v characters long", n, len(page.Description))
+		}
+		if !strings.HasPrefix(page.Description, "```\n") || !strings.HasSuffix(page.Description, "\n```") {
+			t.Errorf("page %v isn't in a code block: %q", n, page.Description)
+		}
+		joined[n] = strings.TrimSuffix(strings.TrimPrefix(page.Description, "```\n"), "\n```")
+	}
+
+	if got := strings.Join(joined, "\n"); got != text {
+		t.Errorf("expected the pages to contain all of the text")
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	line := strings.Repeat("🐸", 1000)
+
+	split := splitLines([]string{line, "short"}, 2048)
+	if len(split) != 3 || split[2] != "short" {
+		t.Fatalf("expected the long line to be split in two, got %v lines", len(split))
+	}
+
+	for n, s := range split {
+		if len(s) > 2048 || !utf8.ValidString(s) {
+			t.Errorf("line %v was split badly: %v characters, valid: %v", n, len(s), utf8.ValidString(s))
+		}
+	}
+}
+
+func TestPageFields(t *testing.T) {
+	fields := make([]discord.EmbedField, 0)
+	for n := 0; n < 13; n++ {
+		fields = append(fields, discord.EmbedField{Name: fmt.Sprintf("field %v", n), Value: "value"})
+	}
+
+	pages := numberPages(util.Printer(util.DefaultLocale), PageFields("title", fields, 0))
+	if len(pages) != 3 {
+		t.Fatalf("expected 3 pages, got %v", len(pages))
+	}
+
+	if len(pages[2].Fields) != 1 || pages[2].Fields[0].Name != "field 12" {
+		t.Errorf("expected the last page to have the last field, got %v", pages[2].Fields)
+	}
+
+	if pages[1].Footer == nil || pages[1].Footer.Text != "Page 2/3" {
+		t.Errorf("expected the pages to be numbered, got %v", pages[1].Footer)
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"github.com/5HT2/taro-bot/util"
 	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/diamondburned/arikawa/v3/gateway"
-	"log"
+	"log/slog"
 	"strings"
 	"sync"
 	"time"
@@ -65,17 +65,12 @@ func HasPermission(c bot.Command, p Permission) *bot.Error {
 	id := int64(c.E.Author.ID)
 
 	if id == 0 {
-		return bot.GenericError(c.FnName, "checking permission", "id is `0`")
+		return bot.InternalError(c.FnName, "checking permission", "id is `0`", nil)
 	}
 
 	if p == PermOperator {
-		opIDs := make([]int64, 0)
-		bot.C.Run(func(c *bot.Config) {
-			opIDs = c.OperatorIDs
-		})
-
-		if !util.SliceContains(opIDs, int64(c.E.Author.ID)) {
-			return bot.GenericError(c.FnName, "running command", util.GetUserMention(id)+" is not a bot operator")
+		if !IsOperator(c.E.Author.ID) {
+			return bot.PermissionError(c.FnName, "running command", util.GetUserMention(id)+" is not a bot operator")
 		}
 
 		return nil
@@ -86,25 +81,57 @@ func HasPermission(c bot.Command, p Permission) *bot.Error {
 	}
 
 	if !UserHasPermission(c, p, id) {
-		return bot.GenericError(c.FnName, "running command", fmt.Sprintf("%s is missing the \"%s\" permission", util.GetUserMention(id), p))
+		return bot.PermissionError(c.FnName, "running command", fmt.Sprintf("%s is missing the \"%s\" permission", util.GetUserMention(id), p))
 	}
 
 	return nil
 }
 
+// CanUse will return if the author of c has the Permission needed to use a command
+func CanUse(c bot.Command, info bot.CommandInfo) bool {
+	if len(info.Permission) == 0 {
+		return true
+	}
+
+	// Bot operators can use every command
+	if HasPermission(c, PermOperator) == nil {
+		return true
+	}
+
+	p := GetPermission(info.Permission)
+	return p != PermOperator && HasPermission(c, p) == nil
+}
+
 // UserHasPermission will return if the user with id has said permission
 func UserHasPermission(c bot.Command, p Permission, id int64) bool {
 	if HasAdminCached(c.E.GuildID, c.E.Member.RoleIDs, c.E.Author) {
 		return true
 	}
 
-	users := make([]int64, 0)
-	bot.GuildContext(c.E.GuildID, func(g *bot.GuildConfig) (*bot.GuildConfig, string) {
-		users = getPermissionSlice(p, g)
-		return g, "UserHasPermission: " + c.FnName
+	return util.SliceContains(permissionUsers(c.E.GuildID, p, "UserHasPermission: "+c.FnName), id)
+}
+
+// MemberHasPermission will return if m has said permission in the guild with id, for checking permissions outside a command
+func MemberHasPermission(id discord.GuildID, m discord.Member, p Permission) bool {
+	if p == PermOperator {
+		return IsOperator(m.User.ID)
+	}
+
+	if HasAdminCached(id, m.RoleIDs, m.User) {
+		return true
+	}
+
+	return util.SliceContains(permissionUsers(id, p, "MemberHasPermission"), int64(m.User.ID))
+}
+
+// IsOperator will return if the user with id is in bot.C.OperatorIDs
+func IsOperator(id discord.UserID) bool {
+	opIDs := make([]int64, 0)
+	bot.C.Run(func(c *bot.Config) {
+		opIDs = c.OperatorIDs
 	})
 
-	return util.SliceContains(users, id)
+	return util.SliceContains(opIDs, int64(id))
 }
 
 // GivePermission will return nil if the permission was successfully given to the user with a matching id
@@ -159,10 +186,21 @@ func GetPermission(pStr string) Permission {
 
 // UpdateMemberCache will forcibly update the member cache
 func UpdateMemberCache(e *gateway.GuildMemberUpdateEvent) {
-	log.Printf("updating member cache\n")
+	slog.Debug("updating member cache", "guild", e.GuildID, "user", e.User.ID)
 	hasAdmin(e.GuildID, e.RoleIDs, e.User)
 }
 
+// permissionUsers will return the ids of the users that were given p in the guild with id
+func permissionUsers(id discord.GuildID, p Permission, fnName string) []int64 {
+	users := make([]int64, 0)
+	bot.GuildContext(id, func(g *bot.GuildConfig) (*bot.GuildConfig, string) {
+		users = getPermissionSlice(p, g)
+		return g, fnName
+	})
+
+	return users
+}
+
 func getPermissionSlice(p Permission, guild *bot.GuildConfig) []int64 {
 	switch p {
 	case PermChannels:
@@ -185,14 +223,14 @@ func HasAdminCached(id discord.GuildID, memberRoles []discord.RoleID, user disco
 			for _, u := range g.admins {
 				// If ID matches and the last check was more recent than 10 minutes ago
 				if u.id == user.ID && time.Now().Unix()-u.lastCheck < 600 {
-					log.Printf("HasAdminCached: found %v\n", u.id)
+					slog.Debug("found cached admin permission", "guild", id, "user", u.id, "admin", u.admin)
 					return u.admin
 				}
 			}
 		}
 	}
 
-	log.Printf("HasAdminCached: didn't find anyone\n")
+	slog.Debug("didn't find cached admin permission", "guild", id, "user", user.ID)
 	return hasAdmin(id, memberRoles, user)
 }
 
@@ -239,7 +277,7 @@ func hasAdmin(id discord.GuildID, memberRoles []discord.RoleID, user discord.Use
 					u.lastCheck = time.Now().Unix()
 					g.admins[n] = u
 
-					log.Printf("permission cache: found existing cache %v, setting to %v\n", user.ID, admin)
+					slog.Debug("updated cached admin permission", "guild", id, "user", user.ID, "admin", admin)
 					break
 				}
 			}
@@ -248,7 +286,7 @@ func hasAdmin(id discord.GuildID, memberRoles []discord.RoleID, user discord.Use
 			if !foundUser {
 				u := guildUser{lastCheck: time.Now().Unix(), id: user.ID, admin: admin}
 				g.admins = append(g.admins, u)
-				log.Printf("permission cache: didn't find existing cache %v, setting to %v\n", user.ID, admin)
+				slog.Debug("cached admin permission", "guild", id, "user", user.ID, "admin", admin)
 			}
 
 			PermissionCache.guilds[n] = g
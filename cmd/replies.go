@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"github.com/5HT2/taro-bot/bot"
+	"github.com/5HT2/taro-bot/util"
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+//
+// Replies to commands are tracked, so that when a command is edited it can be run again, and edit its previous replies
+// instead of sending new ones, and when a command is deleted its replies are deleted too.
+// Only replies sent with an event, such as SendEmbed and SendMessage, are tracked.
+
+var (
+	replies      = make(map[discord.MessageID]*invocation) // [command message id]invocation
+	repliesOrder = make([]discord.MessageID, 0)            // repliesOrder is the order invocations were added, oldest first
+	repliesMutex sync.Mutex
+
+	DefaultEditWindow = int64(120) // DefaultEditWindow is the seconds after sending a command that editing it runs it again
+	maxInvocations    = 1000       // maxInvocations is the most commands that are tracked at once
+)
+
+type invocation struct {
+	content string              // content of the command message when it was last run
+	replies []discord.MessageID // replies sent by the command, in order
+	editing bool                // editing is set while the command is run again after an edit
+	cursor  int                 // cursor is the next reply that will be edited, while editing
+}
+
+// MessageUpdateHandler will run an edited command again, if it was sent within the edit window
+func MessageUpdateHandler(e *gateway.MessageUpdateEvent) {
+	defer util.LogPanic()
+
+	// Updates without a new edit timestamp are for things like embeds being added to a link, not the content changing
+	if e.Author.Bot || !e.Author.ID.IsValid() || !e.EditedTimestamp.IsValid() {
+		return
+	}
+
+	if e.GuildID.IsValid() && e.ChannelID.IsValid() && bot.C.OperatorChannel == int64(e.ChannelID) {
+		return
+	}
+
+	window := DefaultEditWindow
+	bot.C.Run(func(c *bot.Config) {
+		if c.EditWindow != 0 {
+			window = c.EditWindow
+		}
+	})
+
+	if window < 0 || time.Since(e.ID.Time()) > time.Duration(window)*time.Second {
+		return
+	}
+
+	if !startEdit(e.ID, e.Content) {
+		return
+	}
+
+	ce := &gateway.MessageCreateEvent{Message: e.Message, Member: e.Member}
+	cmdName, cmdArgs := extractCommand(ce.Message)
+	CommandHandlerWithCommand(ce, cmdName, cmdArgs)
+
+	finishEdit(e.ChannelID, e.ID)
+}
+
+// MessageDeleteHandler will delete the replies to a deleted command, unless the guild keeps them
+func MessageDeleteHandler(e *gateway.MessageDeleteEvent) {
+	defer util.LogPanic()
+
+	deleteReplies(e.GuildID, e.ChannelID, []discord.MessageID{e.ID})
+}
+
+// MessageDeleteBulkHandler will delete the replies to deleted commands, unless the guild keeps them
+func MessageDeleteBulkHandler(e *gateway.MessageDeleteBulkEvent) {
+	defer util.LogPanic()
+
+	deleteReplies(e.GuildID, e.ChannelID, e.IDs)
+}
+
+// deleteReplies will stop tracking the commands in ids, and delete their replies
+func deleteReplies(guild discord.GuildID, channel discord.ChannelID, ids []discord.MessageID) {
+	repliesMutex.Lock()
+	deleted := make([]discord.MessageID, 0)
+	for _, id := range ids {
+		if i, ok := replies[id]; ok {
+			deleted = append(deleted, i.replies...)
+			delete(replies, id)
+		}
+	}
+	repliesMutex.Unlock()
+
+	// The deleted invocations are left in repliesOrder, and removed from it once they are the oldest
+	if len(deleted) == 0 {
+		return
+	}
+
+	if guild.IsValid() {
+		keep := false
+		bot.GuildContext(guild, func(g *bot.GuildConfig) (*bot.GuildConfig, string) {
+			keep = g.KeepReplies
+			return g, "deleteReplies"
+		})
+
+		if keep {
+			return
+		}
+	}
+
+	for _, reply := range deleted {
+		if err := bot.Client.DeleteMessage(channel, reply, "command was deleted"); err != nil {
+			slog.Error("error deleting reply", "channel", channel, "message", reply, "err", err)
+		}
+	}
+}
+
+// trackInvocation will start tracking the replies to a command message
+func trackInvocation(e *gateway.MessageCreateEvent) {
+	repliesMutex.Lock()
+	defer repliesMutex.Unlock()
+
+	if _, ok := replies[e.ID]; !ok {
+		addInvocation(e.ID, e.Content)
+	}
+}
+
+// addInvocation will add an invocation, and remove the oldest ones so that the tracker stays bounded.
+// repliesMutex has to be held when calling it.
+func addInvocation(id discord.MessageID, content string) *invocation {
+	i := &invocation{content: content}
+	replies[id] = i
+	repliesOrder = append(repliesOrder, id)
+
+	for len(repliesOrder) > maxInvocations {
+		delete(replies, repliesOrder[0])
+		repliesOrder = repliesOrder[1:]
+	}
+	return i
+}
+
+// startEdit will mark an invocation as being edited, and return false if its content hasn't changed or it is already being edited
+func startEdit(id discord.MessageID, content string) bool {
+	repliesMutex.Lock()
+	defer repliesMutex.Unlock()
+
+	i, ok := replies[id]
+	if !ok {
+		// The message wasn't a command before it was edited, such as a typo in the command name
+		i = addInvocation(id, "")
+	}
+
+	if i.editing || i.content == content {
+		return false
+	}
+
+	i.content = content
+	i.editing = true
+	i.cursor = 0
+	return true
+}
+
+// finishEdit will delete the replies of an edited command that were not replaced by its new replies
+func finishEdit(channel discord.ChannelID, id discord.MessageID) {
+	repliesMutex.Lock()
+	i, ok := replies[id]
+	if !ok {
+		repliesMutex.Unlock()
+		return
+	}
+
+	stale := append([]discord.MessageID{}, i.replies[i.cursor:]...)
+	i.replies = i.replies[:i.cursor]
+	i.editing = false
+	repliesMutex.Unlock()
+
+	for _, reply := range stale {
+		if err := bot.Client.DeleteMessage(channel, reply, "command was edited"); err != nil {
+			slog.Error("error deleting stale reply", "channel", channel, "message", reply, "err", err)
+		}
+	}
+}
+
+// sendReply will send a reply to e, or edit the previous reply if e is a command that is being run again after an edit
+func sendReply(e *gateway.MessageCreateEvent, content string, embeds ...discord.Embed) (*discord.Message, error) {
+	return sendReplyComponents(e, content, embeds, nil)
+}
+
+// sendReplyComponents will send a reply with components to e, like sendReply
+func sendReplyComponents(e *gateway.MessageCreateEvent, content string, embeds []discord.Embed, components discord.ContainerComponents) (*discord.Message, error) {
+	repliesMutex.Lock()
+	i, tracked := replies[e.ID]
+
+	if tracked && i.editing && i.cursor < len(i.replies) {
+		reply := i.replies[i.cursor]
+		i.cursor++
+		repliesMutex.Unlock()
+
+		if embeds == nil {
+			embeds = []discord.Embed{}
+		}
+		if components == nil {
+			components = discord.ContainerComponents{}
+		}
+		return bot.Client.EditMessageComplex(e.ChannelID, reply, api.EditMessageData{
+			Content:    option.NewNullableString(content),
+			Embeds:     &embeds,
+			Components: &components,
+		})
+	}
+	repliesMutex.Unlock()
+
+	msg, err := bot.Client.SendMessageComplex(e.ChannelID, api.SendMessageData{
+		Content:    content,
+		Embeds:     embeds,
+		Components: components,
+	})
+	if err != nil || !tracked {
+		return msg, err
+	}
+
+	repliesMutex.Lock()
+	defer repliesMutex.Unlock()
+
+	// New replies are only sent while editing once every previous reply has been edited, so they go at the end
+	i.replies = append(i.replies, msg.ID)
+	if i.editing {
+		i.cursor++
+	}
+	return msg, err
+}
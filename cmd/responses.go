@@ -5,12 +5,9 @@ import (
 	"github.com/5HT2/taro-bot/bot"
 	"github.com/5HT2/taro-bot/util"
 	"github.com/diamondburned/arikawa/v3/gateway"
-	"log"
-	"regexp"
-	"strings"
 )
 
-// ResponseHandler will find a global response from the config and send it, if found
+// ResponseHandler will find the responses that match a message and send them, in order of priority
 func ResponseHandler(e *gateway.MessageCreateEvent) {
 	defer util.LogPanic()
 
@@ -20,56 +17,61 @@ func ResponseHandler(e *gateway.MessageCreateEvent) {
 	}
 
 	// TODO: Per-guild responses and configuration
-	// TODO: compiling and caching support could be added here to improve speed
-	go func() {
-		for _, response := range bot.Responses {
-			runResponse(e, response)
+	isCommand := isCommandMessage(e)
+
+	for _, response := range matchResponses(e.Message.Content) {
+		if isCommand && !response.info.AllowCommands {
+			continue
 		}
-	}()
-}
 
-func runResponse(e *gateway.MessageCreateEvent, response bot.ResponseInfo) {
-	if findResponse(e, response) {
-		sendResponse(e, response)
+		r := bot.NewResponse(e)
+		if !sendResponse(r, response) {
+			continue
+		}
+
+		if response.info.Exclusive || r.Consumed() {
+			return
+		}
 	}
 }
 
-func sendResponse(e *gateway.MessageCreateEvent, response bot.ResponseInfo) {
+// sendResponse will run response, and return false if it was skipped because of its LockChannels, LockUsers or Cooldowns
+func sendResponse(r bot.Response, c compiledResponse) bool {
+	e, response := r.E, c.info
+
 	// If there is a channel whitelist, and it doesn't contain the original message's channel ID, return
 	if e.ChannelID.IsValid() && len(response.LockChannels) > 0 && !util.SliceContains(response.LockChannels, int64(e.ChannelID)) {
-		return
+		return false
 	}
 
 	// If there is a user whitelist, and it doesn't contain the original author's ID, return
 	if e.ChannelID.IsValid() && len(response.LockUsers) > 0 && !util.SliceContains(response.LockUsers, int64(e.Author.ID)) {
-		return
+		return false
 	}
 
-	if response.Fn != nil {
-		response.Fn(bot.Response{E: e})
+	// Responses on cooldown are skipped silently, because they weren't asked for like commands are
+	if remaining, _ := bot.UseCooldowns(c.key, response.Cooldowns, e); remaining > 0 {
+		return false
 	}
-}
 
-func findResponse(e *gateway.MessageCreateEvent, response bot.ResponseInfo) bool {
-	matched := 0
-	message := []byte(e.Message.Content)
-	for _, regex := range response.Regexes {
-		// Allow using a variable in the regex to represent the current bot user
-		// TODO: Documentation for these
-		regex = strings.ReplaceAll(regex, "DISCORD_BOT_ID", bot.User.ID.String())
+	if !response.NoRateLimit && !bot.WaitRateLimit() {
+		bot.EventLogger(e).Warn("dropped response, rate limited", "response", response)
+		return false
+	}
 
-		found, err := regexp.Match(regex, message)
-		if err != nil {
-			log.Printf("Error matching \"%s\": %v\n", regex, err)
-		}
-		if found {
-			matched += 1
-		}
+	if response.Fn != nil {
+		bot.ResponsesTotal.WithLabelValues(response.Plugin).Inc()
+		response.Fn(r)
+	}
+	return true
+}
 
-		if matched >= response.MatchMin {
-			return true
-		}
+// isCommandMessage will return if e is a message that CommandHandler runs a command for
+func isCommandMessage(e *gateway.MessageCreateEvent) bool {
+	if e.GuildID.IsValid() && e.ChannelID.IsValid() && bot.C.OperatorChannel == int64(e.ChannelID) {
+		return false
 	}
 
-	return false
+	cmdName, _ := extractCommand(e.Message)
+	return len(cmdName) > 0 && getCommandWithName(cmdName) != nil
 }
@@ -5,9 +5,10 @@ import (
 	"github.com/5HT2/taro-bot/util"
 	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/diamondburned/arikawa/v3/gateway"
-	"log"
+	"log/slog"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 var (
@@ -38,20 +39,63 @@ func CommandHandlerWithCommand(e *gateway.MessageCreateEvent, cmdName string, cm
 
 	cmdInfo := getCommandWithName(cmdName)
 	if cmdInfo != nil {
-		command := bot.Command{E: e, FnName: cmdInfo.FnName, Name: cmdName, Args: cmdArgs}
+		command := bot.Command{E: e, FnName: cmdInfo.FnName, Name: cmdName, Args: cmdArgs, Plugin: cmdInfo.Plugin}
 
 		if cmdInfo.GuildOnly && !e.GuildID.IsValid() {
-			_, err := SendEmbed(e, "Error", "The `"+cmdInfo.Name+"` command only works in guilds!", bot.ErrorColor)
+			_, err := SendEmbed(e, command.T("Error"), command.T("The `%s` command only works in guilds!", cmdInfo.Name), bot.ErrorColor)
 			if err != nil {
-				log.Printf("Error with \"%s\" command (Cancelled): %v\n", cmdName, err)
+				command.Log().Error("error sending guild only error", "err", err)
 			}
 			return
 		}
 
-		if err := cmdInfo.Fn(command); err != nil {
-			log.Printf("Error with \"%s\" command: %v\n", cmdName, err)
+		if len(cmdInfo.Permission) > 0 && !CanUse(command, *cmdInfo) {
+			// Use the same error as when a command checks the permission itself
+			err := HasPermission(command, GetPermission(cmdInfo.Permission))
+			if err == nil {
+				err = bot.PermissionError(cmdInfo.FnName, "running command", "missing the \""+cmdInfo.Permission+"\" permission")
+			}
+
 			SendErrorEmbed(command, err)
+			return
+		}
+
+		// Bot operators aren't limited by cooldowns
+		if HasPermission(command, PermOperator) != nil {
+			if remaining, notify := bot.UseCooldowns(cmdInfo.Name, cmdInfo.Cooldowns, e); remaining > 0 {
+				if notify {
+					// Round up, so that "0 seconds" is never shown
+					seconds := int64((remaining + time.Second - 1) / time.Second)
+					remainingText := util.FormattedTimeIn(command.Printer(), seconds)
+					_, err := SendEmbed(e, command.T("Slow down!"), command.T("You can use `%s` again in %s.", cmdInfo.Name, remainingText), bot.WarnColor)
+					if err != nil {
+						command.Log().Error("error sending cooldown warning", "err", err)
+					}
+				}
+				return
+			}
 		}
+
+		if !bot.WaitRateLimit() {
+			command.Log().Warn("dropped command, rate limited")
+			return
+		}
+
+		trackInvocation(e)
+
+		start := time.Now()
+		err := cmdInfo.Fn(command)
+		bot.CommandsTotal.WithLabelValues(cmdInfo.Name, cmdInfo.Plugin).Inc()
+		bot.ObserveSince(bot.CommandDuration.WithLabelValues(cmdInfo.Name, cmdInfo.Plugin), start)
+
+		if err != nil {
+			bot.CommandErrorsTotal.WithLabelValues(cmdInfo.Name, cmdInfo.Plugin).Inc()
+			command.Log().Info("error with command", "err", err)
+			bot.ReportCommandError(command, err)
+			SendErrorEmbed(command, err)
+		}
+	} else {
+		suggestCommands(e, cmdName)
 	}
 }
 
@@ -71,8 +115,8 @@ func extractCommand(message discord.Message) (string, []string) {
 		// If the PrefixCache somehow doesn't have a prefix, set a default one and log it.
 		// This is most likely when the bot has joined a new guild without accessing GuildContext
 		if !ok {
-			log.Printf("expected prefix to be in prefix cache: %s (%s)\n",
-				message.GuildID, CreateMessageLink(int64(message.GuildID), &message, false, false))
+			slog.Warn("expected prefix to be in prefix cache", "guild", message.GuildID,
+				"message", CreateMessageLink(int64(message.GuildID), &message, false, false))
 
 			bot.GuildContext(message.GuildID, func(g *bot.GuildConfig) (*bot.GuildConfig, string) {
 				g.Prefix = bot.DefaultPrefix
@@ -101,10 +145,17 @@ func extractCommand(message discord.Message) (string, []string) {
 
 // getCommandWithName will return the found CommandInfo with a matching name or alias
 func getCommandWithName(name string) *bot.CommandInfo {
-	for _, cmd := range bot.Commands { // TODO: Use hashmap for performance with lots of commands
-		if cmd.Name == name || util.SliceContains(cmd.Aliases, name) {
-			return &cmd
-		}
+	commandIndexMutex.RLock()
+	index := commandIndex
+	commandIndexMutex.RUnlock()
+
+	if index == nil {
+		IndexCommands()
+		return getCommandWithName(name)
+	}
+
+	if cmd, ok := index[name]; ok {
+		return &cmd
 	}
 	return nil
 }
@@ -0,0 +1,473 @@
+// Package dashboard serves a web dashboard on bot.Mux, so that guilds can be configured without chat commands.
+// Bot operators log in with bot.Config.DashboardToken or a login link, and can see every guild, the plugins and the jobs.
+// Anyone else logs in with a link from the dashboard command, and can edit the guilds that they have the moderate
+// permission in, the same as the config command.
+package dashboard
+
+import (
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/5HT2/taro-bot/bot"
+	"github.com/5HT2/taro-bot/cmd"
+	"github.com/5HT2/taro-bot/plugins"
+	"github.com/5HT2/taro-bot/util"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const path = "/dashboard/"
+
+var (
+	//go:embed templates
+	templateFS embed.FS
+	templates  = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+)
+
+// page is the data shared by every template
+type page struct {
+	Title    string
+	LoggedIn bool
+	Operator bool
+	CSRF     string
+	Message  string
+	Error    string
+}
+
+type indexPage struct {
+	page
+	Guilds      []discord.Guild
+	Plugins     []plugins.Status
+	Jobs        []string
+	DurableJobs []bot.DurableJob
+}
+
+type guildPage struct {
+	page
+	Guild     discord.Guild
+	Prefix    string
+	Locale    string
+	Locales   []string
+	Starboard bot.StarboardConfig
+	Configs   []pluginConfig
+	Roles     []discord.Role  // Roles are the roles that can be chosen in forms, highest first
+	Emojis    []discord.Emoji // Emojis are the custom emojis of the guild, which are suggested in forms
+
+	JoinLeave       []joinLeaveForm
+	HasJoinLeave    bool // HasJoinLeave is if leave-join-msg is loaded, the same for the other Has fields
+	MessageRoles    []messageRoleForm
+	HasMessageRoles bool
+	RoleMenus       []roleMenuForm
+	HasRoleMenus    bool
+}
+
+// pluginConfig is the part of a plugin's config that can be edited on a guild page, by its top level keys
+type pluginConfig struct {
+	Name   string
+	Fields []configField
+}
+
+type configField struct {
+	Path  string
+	Value string
+}
+
+// Serve will serve the dashboard on bot.Mux
+func Serve() {
+	bot.Mux.Handle(path, Handler())
+}
+
+// Handler will return the handler for the dashboard, which expects to be served at /dashboard/
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'; frame-ancestors 'none'")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+
+		route := strings.Trim(strings.TrimPrefix(r.URL.Path, path), "/")
+		switch {
+		case route == "login":
+			serveLogin(w, r)
+			return
+		case route == "logout" && r.Method == http.MethodPost:
+			if s, ok := getSession(r); ok && validCSRF(r, s) {
+				endSession(r)
+			}
+			http.Redirect(w, r, path+"login", http.StatusSeeOther)
+			return
+		}
+
+		s, ok := getSession(r)
+		if !ok {
+			http.Redirect(w, r, path+"login", http.StatusSeeOther)
+			return
+		}
+
+		if r.Method == http.MethodPost && !validCSRF(r, s) {
+			http.Error(w, "invalid csrf token, reload the page and try again", http.StatusForbidden)
+			return
+		}
+
+		switch {
+		case route == "" && r.Method == http.MethodGet:
+			serveIndex(w, s)
+		case strings.HasPrefix(route, "guild/"):
+			id, err := discord.ParseSnowflake(strings.TrimPrefix(route, "guild/"))
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+
+			serveGuild(w, r, s, discord.GuildID(id))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func serveLogin(w http.ResponseWriter, r *http.Request) {
+	var id string
+	var s *session
+	ok := false
+
+	switch {
+	case r.Method == http.MethodPost:
+		id, s, ok = useToken(r.PostFormValue("token"))
+	case len(r.URL.Query().Get("code")) > 0:
+		id, s, ok = useLoginLink(r.URL.Query().Get("code"))
+	default:
+		render(w, http.StatusOK, "login.html", page{Title: "Log in"})
+		return
+	}
+
+	if !ok {
+		render(w, http.StatusUnauthorized, "login.html", page{Title: "Log in", Error: "That token or login link is invalid or has expired"})
+		return
+	}
+
+	secure := r.TLS != nil
+	bot.C.Run(func(c *bot.Config) {
+		secure = secure || strings.HasPrefix(c.DashboardUrl, "https://")
+	})
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Value:    id,
+		Path:     path,
+		Expires:  s.expires,
+		Secure:   secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	slog.Info("logged into dashboard", "user", s.user, "token", s.token)
+	http.Redirect(w, r, path, http.StatusSeeOther)
+}
+
+func serveIndex(w http.ResponseWriter, s *session) {
+	p := indexPage{page: newPage("Dashboard", s), Guilds: make([]discord.Guild, 0)}
+
+	guilds, err := bot.Client.Guilds()
+	if err != nil {
+		p.Error = "Failed to get guilds: " + err.Error()
+	}
+	for _, g := range guilds {
+		if canEdit(s, g.ID) {
+			p.Guilds = append(p.Guilds, g)
+		}
+	}
+	sort.SliceStable(p.Guilds, func(i, j int) bool {
+		return strings.ToLower(p.Guilds[i].Name) < strings.ToLower(p.Guilds[j].Name)
+	})
+
+	// Plugins and jobs are only shown to operators, like the plugins and jobs commands
+	if p.Operator {
+		p.Plugins = plugins.Statuses()
+		p.DurableJobs = bot.DurableJobs()
+
+		bot.Mutex.Lock()
+		for _, job := range bot.Jobs {
+			p.Jobs = append(p.Jobs, job.Name)
+		}
+		bot.Mutex.Unlock()
+	}
+
+	render(w, http.StatusOK, "index.html", p)
+}
+
+func serveGuild(w http.ResponseWriter, r *http.Request, s *session, id discord.GuildID) {
+	guild, err := bot.Client.Guild(id)
+	if err != nil || !canEdit(s, id) {
+		http.NotFound(w, r)
+		return
+	}
+
+	p := guildPage{page: newPage(guild.Name, s), Guild: *guild}
+	status := http.StatusOK
+
+	if r.Method == http.MethodPost {
+		action := r.PostFormValue("action")
+		if p.Message, err = updateGuild(r, s, id); err != nil {
+			p.Error = err.Error()
+			status = http.StatusBadRequest
+		} else {
+			slog.Info("changed guild setting from dashboard", "guild", id, "user", s.user, "token", s.token, "action", action)
+		}
+	}
+
+	bot.GuildContext(id, func(g *bot.GuildConfig) (*bot.GuildConfig, string) {
+		p.Prefix = g.Prefix
+		p.Locale = g.Locale
+		p.Starboard = g.Starboard
+		return g, "dashboard.serveGuild"
+	})
+
+	for _, l := range util.Locales() {
+		p.Locales = append(p.Locales, l.String())
+	}
+	sort.Strings(p.Locales)
+
+	roleNames := make(map[discord.RoleID]string)
+	if roles, err := bot.Client.Roles(id); err == nil {
+		for _, role := range roles {
+			roleNames[role.ID] = role.Name
+			if assignable(id, role) {
+				p.Roles = append(p.Roles, role)
+			}
+		}
+	}
+	sort.SliceStable(p.Roles, func(i, j int) bool {
+		return p.Roles[i].Position > p.Roles[j].Position
+	})
+	p.Emojis, _ = bot.Client.Emojis(id)
+
+	access := plugins.ConfigAccess{Guild: id.String(), Operator: s.operator()}
+	p.JoinLeave, p.HasJoinLeave = joinLeaveForms(access)
+	p.MessageRoles, p.HasMessageRoles = messageRoleForms(access, roleNames)
+	p.RoleMenus, p.HasRoleMenus = roleMenuForms(access, roleNames)
+
+	for _, name := range plugins.Configs() {
+		if c, ok := guildConfig(name, access); ok {
+			p.Configs = append(p.Configs, c)
+		}
+	}
+
+	render(w, status, "guild.html", p)
+}
+
+// updateGuild will apply a form from a guild page, and return a message to show if it was successful
+func updateGuild(r *http.Request, s *session, id discord.GuildID) (string, error) {
+	switch r.PostFormValue("action") {
+	case "prefix":
+		prefix, err := bot.SetPrefix("dashboard", id, r.PostFormValue("prefix"))
+		if err != nil {
+			return "", errors.New("prefix is empty")
+		}
+		return "Set prefix to " + prefix, nil
+	case "locale":
+		locale, err := bot.SetGuildLocale("dashboard", id, r.PostFormValue("locale"))
+		if err != nil {
+			return "", errors.New("unknown locale")
+		}
+		return "Set locale to " + locale.String(), nil
+	case "starboard":
+		channel, err := parseChannel(id, r.PostFormValue("channel"))
+		if err != nil {
+			return "", err
+		}
+		nsfwChannel, err := parseChannel(id, r.PostFormValue("nsfw_channel"))
+		if err != nil {
+			return "", err
+		}
+		threshold, err := strconv.ParseInt(strings.TrimSpace(r.PostFormValue("threshold")), 10, 64)
+		if err != nil || threshold < 1 {
+			return "", errors.New("threshold must be a whole number of at least 1")
+		}
+
+		bot.GuildContext(id, func(g *bot.GuildConfig) (*bot.GuildConfig, string) {
+			g.Starboard.Channel = channel
+			g.Starboard.NsfwChannel = nsfwChannel
+			g.Starboard.Threshold = threshold
+			return g, "dashboard.updateGuild: starboard"
+		})
+		return "Saved starboard settings", nil
+	case "join_leave":
+		return updateJoinLeave(r, id, plugins.ConfigAccess{Guild: id.String(), Operator: s.operator()})
+	case "message_role", "message_role_remove":
+		return updateMessageRole(r, id, plugins.ConfigAccess{Guild: id.String(), Operator: s.operator()}, r.PostFormValue("action") == "message_role_remove")
+	case "role_menu_create", "role_menu_add", "role_menu_remove", "role_menu_delete":
+		return updateRoleMenu(r, id, plugins.ConfigAccess{Guild: id.String(), Operator: s.operator()}, r.PostFormValue("action"))
+	case "config", "reset":
+		name := r.PostFormValue("plugin")
+		fieldPath := r.PostFormValue("path")
+		access := plugins.ConfigAccess{Guild: id.String(), Operator: s.operator()}
+
+		if r.PostFormValue("action") == "reset" {
+			if err := plugins.ResetConfig(name, fieldPath, access); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Reset %s %s to the default value", name, fieldPath), nil
+		}
+
+		if _, err := plugins.SetConfig(name, fieldPath, r.PostFormValue("value"), access); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Saved %s %s", name, fieldPath), nil
+	default:
+		return "", errors.New("unknown action")
+	}
+}
+
+// parseChannel will parse a channel id or mention, and check that it is a text channel in the guild with id.
+// An empty value or 0 disables the channel.
+func parseChannel(id discord.GuildID, value string) (int64, error) {
+	value = strings.Trim(strings.TrimSpace(value), "<#>")
+	if len(value) == 0 || value == "0" {
+		return 0, nil
+	}
+
+	channelID, err := discord.ParseSnowflake(value)
+	if err != nil {
+		return 0, fmt.Errorf("`%s` is not a channel id", value)
+	}
+
+	channel, err := bot.Client.Channel(discord.ChannelID(channelID))
+	if err != nil || channel.GuildID != id {
+		return 0, fmt.Errorf("channel `%s` is not in this guild", value)
+	}
+	if channel.Type != discord.GuildText && channel.Type != discord.GuildNews {
+		return 0, fmt.Errorf("channel `%s` is not a text channel", value)
+	}
+
+	return int64(channelID), nil
+}
+
+// parseRole will parse a role id or mention, and check that it is a role in the guild with id that can be given to members
+func parseRole(id discord.GuildID, value string) (int64, error) {
+	value = strings.Trim(strings.TrimSpace(value), "<@&>")
+	roleID, err := discord.ParseSnowflake(value)
+	if err != nil || !roleID.IsValid() {
+		return 0, fmt.Errorf("`%s` is not a role id", value)
+	}
+
+	roles, err := bot.Client.Roles(id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the roles of this guild")
+	}
+
+	for _, role := range roles {
+		if role.ID != discord.RoleID(roleID) {
+			continue
+		}
+
+		if !assignable(id, role) {
+			return 0, fmt.Errorf("role `%s` can't be given to members", role.Name)
+		}
+		return int64(roleID), nil
+	}
+
+	return 0, fmt.Errorf("role `%s` is not in this guild", value)
+}
+
+// assignable will return if role can be given to members, which @everyone and roles managed by integrations can't
+func assignable(id discord.GuildID, role discord.Role) bool {
+	return !role.Managed && discord.Snowflake(role.ID) != discord.Snowflake(id)
+}
+
+// parseEmoji will parse a unicode or custom emoji, and check that a custom emoji is from the guild with id.
+// It returns the emoji in the config format, see bot.EmojiApiAsConfig.
+func parseEmoji(id discord.GuildID, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	emoji, _, argErr := cmd.ParseEmojiArg([]string{value}, 1, false)
+	if argErr != nil {
+		return "", fmt.Errorf("`%s` is not an emoji", value)
+	}
+
+	// Custom emojis are name:id, and unicode emojis don't have a colon
+	name, emojiID, custom := strings.Cut(string(*emoji), ":")
+	if !custom {
+		return bot.EmojiApiAsConfig(emoji, false), nil
+	}
+
+	emojis, err := bot.Client.Emojis(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the emojis of this guild")
+	}
+
+	for _, e := range emojis {
+		if e.ID.String() == emojiID {
+			return bot.EmojiApiAsConfig(emoji, e.Animated), nil
+		}
+	}
+
+	return "", fmt.Errorf("emoji `%s` is not from this guild", name)
+}
+
+// guildConfig will return the top level keys of a plugin's config that access can see, as indented json.
+// The key that the plugin's forms edit, see formKeys, is left out.
+func guildConfig(name string, access plugins.ConfigAccess) (pluginConfig, bool) {
+	res, err := plugins.GetConfig(name, "", access)
+	if err != nil {
+		return pluginConfig{}, false
+	}
+
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal([]byte(res), &fields); err != nil || len(fields) == 0 {
+		return pluginConfig{}, false
+	}
+
+	delete(fields, formKeys[name])
+	if len(fields) == 0 {
+		return pluginConfig{}, false
+	}
+
+	c := pluginConfig{Name: name}
+	for key, value := range fields {
+		indented, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			indented = value
+		}
+		c.Fields = append(c.Fields, configField{Path: key, Value: string(indented)})
+	}
+	sort.Slice(c.Fields, func(i, j int) bool {
+		return c.Fields[i].Path < c.Fields[j].Path
+	})
+
+	return c, true
+}
+
+// canEdit will return if s can see and edit the guild with id
+func canEdit(s *session, id discord.GuildID) bool {
+	if s.operator() {
+		return true
+	}
+
+	m, err := bot.Client.Member(id, s.user)
+	if err != nil {
+		return false
+	}
+	return cmd.MemberHasPermission(id, *m, cmd.PermModerate)
+}
+
+func validCSRF(r *http.Request, s *session) bool {
+	return subtle.ConstantTimeCompare([]byte(r.PostFormValue("csrf")), []byte(s.csrf)) == 1
+}
+
+func newPage(title string, s *session) page {
+	return page{Title: title, LoggedIn: true, Operator: s.operator(), CSRF: s.csrf}
+}
+
+func render(w http.ResponseWriter, status int, name string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+
+	if err := templates.ExecuteTemplate(w, name, data); err != nil {
+		slog.Error("failed to render dashboard page", "page", name, "err", err)
+	}
+}
@@ -0,0 +1,171 @@
+package dashboard
+
+import (
+	"github.com/5HT2/taro-bot/bot"
+	"github.com/5HT2/taro-bot/cmd/cmdtest"
+	"github.com/5HT2/taro-bot/plugins"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newHarness(t *testing.T, ps ...*plugins.Plugin) *cmdtest.Harness {
+	h := cmdtest.New(t, ps...)
+	bot.C.Run(func(c *bot.Config) {
+		c.DashboardUrl = "http://localhost:6017/dashboard/"
+		c.DashboardToken = "secret"
+	})
+	t.Cleanup(func() {
+		bot.C.Run(func(c *bot.Config) {
+			c.DashboardUrl = ""
+			c.DashboardToken = ""
+		})
+	})
+	return h
+}
+
+// request will make a request to the dashboard with the session cookie, and form if it isn't nil
+func request(method, target, cookie string, form url.Values) *httptest.ResponseRecorder {
+	var r *http.Request
+	if form != nil {
+		r = httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		r = httptest.NewRequest(method, target, nil)
+	}
+	if len(cookie) > 0 {
+		r.AddCookie(&http.Cookie{Name: cookieName, Value: cookie})
+	}
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, r)
+	return w
+}
+
+// login will log in as m with a login link, and return the session cookie and csrf token
+func login(t *testing.T, m discord.Member) (string, string) {
+	link, err := LoginLink(m.User.ID)
+	if err != nil {
+		t.Fatalf("creating login link: %v", err)
+	}
+
+	w := request(http.MethodGet, link, "", nil)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("expected login to redirect, got %v", w.Code)
+	}
+
+	for _, c := range w.Result().Cookies() {
+		if c.Name == cookieName {
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+			r.AddCookie(c)
+			s, _ := getSession(r)
+			return c.Value, s.csrf
+		}
+	}
+
+	t.Fatalf("expected login to set a session cookie")
+	return "", ""
+}
+
+func TestLogin(t *testing.T) {
+	h := newHarness(t)
+
+	if w := request(http.MethodGet, path, "", nil); w.Code != http.StatusSeeOther {
+		t.Errorf("expected logged out users to be redirected to login, got %v", w.Code)
+	}
+
+	if w := request(http.MethodPost, path+"login", "", url.Values{"token": {"wrong"}}); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected a wrong token to be rejected, got %v", w.Code)
+	}
+	if w := request(http.MethodPost, path+"login", "", url.Values{"token": {"secret"}}); w.Code != http.StatusSeeOther {
+		t.Errorf("expected the dashboard token to log in, got %v", w.Code)
+	}
+
+	link, err := LoginLink(h.User.User.ID)
+	if err != nil {
+		t.Fatalf("creating login link: %v", err)
+	}
+	if w := request(http.MethodGet, link, "", nil); w.Code != http.StatusSeeOther {
+		t.Errorf("expected the login link to log in, got %v", w.Code)
+	}
+	if w := request(http.MethodGet, link, "", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected the login link to only work once, got %v", w.Code)
+	}
+}
+
+func TestGuildAccess(t *testing.T) {
+	h := newHarness(t)
+	guildPath := path + "guild/" + h.Guild.ID.String()
+
+	cookie, _ := login(t, h.User)
+	if w := request(http.MethodGet, path, cookie, nil); strings.Contains(w.Body.String(), h.Guild.Name) {
+		t.Errorf("expected users without the moderate permission to not see the guild")
+	}
+	if w := request(http.MethodGet, guildPath, cookie, nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected users without the moderate permission to not open the guild, got %v", w.Code)
+	}
+
+	cookie, _ = login(t, h.Owner)
+	w := request(http.MethodGet, path, cookie, nil)
+	if !strings.Contains(w.Body.String(), h.Guild.Name) {
+		t.Errorf("expected the owner to see the guild")
+	}
+	if strings.Contains(w.Body.String(), "Durable Jobs") {
+		t.Errorf("expected jobs to only be shown to bot operators")
+	}
+	if w := request(http.MethodGet, guildPath, cookie, nil); w.Code != http.StatusOK {
+		t.Errorf("expected the owner to open the guild, got %v", w.Code)
+	}
+
+	h.Operator(h.User)
+	cookie, _ = login(t, h.User)
+	if w := request(http.MethodGet, path, cookie, nil); !strings.Contains(w.Body.String(), "Durable Jobs") {
+		t.Errorf("expected jobs to be shown to bot operators")
+	}
+}
+
+func TestEditGuild(t *testing.T) {
+	h := newHarness(t)
+	guildPath := path + "guild/" + h.Guild.ID.String()
+	cookie, csrf := login(t, h.Owner)
+
+	if w := request(http.MethodPost, guildPath, cookie, url.Values{"action": {"prefix"}, "prefix": {"!"}}); w.Code != http.StatusForbidden {
+		t.Errorf("expected a form without a csrf token to be rejected, got %v", w.Code)
+	}
+
+	if w := request(http.MethodPost, guildPath, cookie, url.Values{"csrf": {csrf}, "action": {"prefix"}, "prefix": {"!"}}); w.Code != http.StatusOK {
+		t.Errorf("expected the prefix to be set, got %v: %s", w.Code, w.Body)
+	}
+
+	other := discord.Channel{ID: discord.ChannelID(h.Discord.NewID()), GuildID: discord.GuildID(h.Discord.NewID()), Type: discord.GuildText}
+	h.Discord.PutChannel(other)
+
+	for _, form := range []url.Values{
+		{"channel": {other.ID.String()}, "threshold": {"3"}},
+		{"channel": {h.Channel.ID.String()}, "threshold": {"0"}},
+	} {
+		form.Set("csrf", csrf)
+		form.Set("action", "starboard")
+		if w := request(http.MethodPost, guildPath, cookie, form); w.Code != http.StatusBadRequest {
+			t.Errorf("expected starboard settings %v to be rejected, got %v", form, w.Code)
+		}
+	}
+
+	form := url.Values{"csrf": {csrf}, "action": {"starboard"}, "channel": {"<#" + h.Channel.ID.String() + ">"}, "threshold": {"5"}}
+	if w := request(http.MethodPost, guildPath, cookie, form); w.Code != http.StatusOK {
+		t.Errorf("expected the starboard settings to be saved, got %v: %s", w.Code, w.Body)
+	}
+
+	bot.GuildContext(h.Guild.ID, func(g *bot.GuildConfig) (*bot.GuildConfig, string) {
+		if g.Prefix != "!" {
+			t.Errorf("expected prefix to be !, got %q", g.Prefix)
+		}
+		if g.Starboard.Channel != int64(h.Channel.ID) || g.Starboard.Threshold != 5 {
+			t.Errorf("expected the starboard settings to be saved, got %+v", g.Starboard)
+		}
+		return g, "TestEditGuild"
+	})
+}
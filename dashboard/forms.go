@@ -0,0 +1,504 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/5HT2/taro-bot/bot"
+	"github.com/5HT2/taro-bot/plugins"
+	"github.com/5HT2/taro-bot/util"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+//
+// Forms for the settings of plugins that are painful to edit as json.
+// The dashboard can't import plugins, so these only use the json keys of their configs, through plugins.GetConfig and
+// plugins.UpdateConfig, and are only shown while the plugin is loaded.
+
+const (
+	joinLeavePlugin    = "leave-join-msg"
+	messageRolesPlugin = "message-roles"
+	roleMenuPlugin     = "role-menu"
+)
+
+var (
+	// formKeys are the config keys that each plugin's forms edit, which aren't shown as json
+	formKeys = map[string]string{
+		joinLeavePlugin:    "guilds",
+		messageRolesPlugin: "guild_configs",
+		roleMenuPlugin:     "menus",
+	}
+
+	joinLeaveMessages = []string{"join_message", "leave_message"} // joinLeaveMessages are the config keys of the messages
+)
+
+// joinLeaveForm is the join or leave message of the leave-join-msg plugin
+type joinLeaveForm struct {
+	Key      string         `json:"-"` // Key is the config key of the message, join_message or leave_message
+	Name     string         `json:"-"`
+	Enabled  bool           `json:"enabled"`
+	Channel  int64          `json:"channel"`
+	Content  string         `json:"content"`
+	Collapse bool           `json:"collapse_message"`
+	Embed    *discord.Embed `json:"embed"`
+}
+
+// EmbedColor will return the color of the embed for a color input
+func (f joinLeaveForm) EmbedColor() string {
+	color := bot.DefaultColor
+	if f.Embed != nil {
+		color = f.Embed.Color
+	}
+	return fmt.Sprintf("#%06x", uint32(color))
+}
+
+// messageRoleForm is a role of the message-roles plugin, which is given to members after Threshold messages
+type messageRoleForm struct {
+	ID         int64  `json:"role"`
+	Threshold  int64  `json:"threshold"`
+	LevelUpMsg bool   `json:"level_up_msg"`
+	Name       string `json:"-"` // Name of the role, or its id if it was deleted
+}
+
+// roleMenuForm is a menu of the role-menu plugin, which gives members a role when they react with its emoji
+type roleMenuForm struct {
+	ID      string
+	Channel int64
+	Roles   []roleMenuRole
+}
+
+type roleMenuRole struct {
+	Emoji  string // Emoji in the config format, see bot.EmojiApiAsConfig
+	Name   string // Name is how the emoji is shown, which is its name for custom emojis
+	Role   string // Role is the name of the role, or its id if it was deleted
+	RoleID int64
+}
+
+// joinLeaveForms will return the join and leave message of a guild, and false if leave-join-msg isn't loaded
+func joinLeaveForms(access plugins.ConfigAccess) ([]joinLeaveForm, bool) {
+	messages := make(map[string]joinLeaveForm)
+	if !readConfig(joinLeavePlugin, access, &messages) {
+		return nil, false
+	}
+
+	forms := make([]joinLeaveForm, 0, len(joinLeaveMessages))
+	for _, key := range joinLeaveMessages {
+		f := messages[key]
+		f.Key = key
+		f.Name = "Join"
+		if key == "leave_message" {
+			f.Name = "Leave"
+		}
+		forms = append(forms, f)
+	}
+	return forms, true
+}
+
+// messageRoleForms will return the message roles of a guild, and false if message-roles isn't loaded
+func messageRoleForms(access plugins.ConfigAccess, roles map[discord.RoleID]string) ([]messageRoleForm, bool) {
+	forms := make([]messageRoleForm, 0)
+	if !readConfig(messageRolesPlugin, access, &forms) {
+		return nil, false
+	}
+
+	for n, f := range forms {
+		forms[n].Name = roleName(roles, f.ID)
+	}
+	sort.SliceStable(forms, func(i, j int) bool {
+		return forms[i].Threshold < forms[j].Threshold
+	})
+	return forms, true
+}
+
+// roleMenuForms will return the role menus of a guild, and false if role-menu isn't loaded
+func roleMenuForms(access plugins.ConfigAccess, roles map[discord.RoleID]string) ([]roleMenuForm, bool) {
+	menus := make(map[string]struct {
+		Channel int64 `json:"channel"`
+		Roles   map[string]struct {
+			RoleID int64 `json:"role_id"`
+		} `json:"roles"`
+	})
+	if !readConfig(roleMenuPlugin, access, &menus) {
+		return nil, false
+	}
+
+	forms := make([]roleMenuForm, 0, len(menus))
+	for id, menu := range menus {
+		f := roleMenuForm{ID: id, Channel: menu.Channel}
+		for emoji, role := range menu.Roles {
+			f.Roles = append(f.Roles, roleMenuRole{Emoji: emoji, Name: emojiName(emoji), Role: roleName(roles, role.RoleID), RoleID: role.RoleID})
+		}
+		sort.Slice(f.Roles, func(i, j int) bool {
+			return f.Roles[i].Name < f.Roles[j].Name
+		})
+		forms = append(forms, f)
+	}
+	sort.Slice(forms, func(i, j int) bool {
+		return forms[i].ID < forms[j].ID
+	})
+	return forms, true
+}
+
+// readConfig will read the config key of plugin's forms into v, and return false if the plugin isn't loaded
+func readConfig(plugin string, access plugins.ConfigAccess, v any) bool {
+	res, err := plugins.GetConfig(plugin, formKeys[plugin], access)
+	if err != nil {
+		return false
+	}
+
+	if err := json.Unmarshal([]byte(res), v); err != nil {
+		slog.Warn("failed to read plugin config for dashboard", "plugin", plugin, "err", err)
+		return false
+	}
+	return true
+}
+
+// updateJoinLeave will save the join or leave message form of the leave-join-msg plugin
+func updateJoinLeave(r *http.Request, id discord.GuildID, access plugins.ConfigAccess) (string, error) {
+	key := r.PostFormValue("message")
+	if !util.SliceContains(joinLeaveMessages, key) {
+		return "", errors.New("unknown message")
+	}
+
+	channel, err := parseChannel(id, r.PostFormValue("channel"))
+	if err != nil {
+		return "", err
+	}
+	color, err := parseColor(r.PostFormValue("embed_color"))
+	if err != nil {
+		return "", err
+	}
+
+	enabled := r.PostFormValue("enabled") == "on"
+	collapse := r.PostFormValue("collapse") == "on"
+	content := strings.TrimSpace(r.PostFormValue("content"))
+	title := strings.TrimSpace(r.PostFormValue("embed_title"))
+	description := strings.TrimSpace(r.PostFormValue("embed_description"))
+	hasEmbed := len(title) > 0 || len(description) > 0
+
+	switch {
+	case enabled && channel == 0:
+		return "", errors.New("a channel is required to enable the message")
+	case enabled && len(content) == 0 && !hasEmbed:
+		return "", errors.New("a message or an embed is required to enable the message")
+	case utf8.RuneCountInString(content) > 2000:
+		return "", errors.New("the message can't be longer than 2000 characters")
+	case utf8.RuneCountInString(title) > 256:
+		return "", errors.New("the embed title can't be longer than 256 characters")
+	case utf8.RuneCountInString(description) > 4096:
+		return "", errors.New("the embed description can't be longer than 4096 characters")
+	}
+
+	err = plugins.UpdateConfig(joinLeavePlugin, formKeys[joinLeavePlugin]+"."+key, access, func(value string) (string, error) {
+		var message map[string]any
+		if err := decodeConfig(value, &message); err != nil {
+			return "", err
+		}
+
+		message["enabled"] = enabled
+		message["channel"] = channel
+		message["content"] = content
+		message["collapse_message"] = collapse
+
+		// The other fields of an existing embed, such as its image, are kept
+		if !hasEmbed {
+			message["embed"] = nil
+		} else {
+			embed, ok := message["embed"].(map[string]any)
+			if !ok {
+				embed = make(map[string]any)
+			}
+			embed["title"] = title
+			embed["description"] = description
+			embed["color"] = color
+			message["embed"] = embed
+		}
+
+		return encodeConfig(message)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return "Saved the " + strings.TrimSuffix(key, "_message") + " message", nil
+}
+
+// updateMessageRole will add a message role, or change its threshold if it already exists, or remove it
+func updateMessageRole(r *http.Request, id discord.GuildID, access plugins.ConfigAccess, remove bool) (string, error) {
+	var roleID, threshold int64
+	var err error
+
+	if remove {
+		// Deleted roles can still be removed, so this isn't checked against the guild
+		if roleID, err = strconv.ParseInt(r.PostFormValue("role"), 10, 64); err != nil {
+			return "", errors.New("unknown role")
+		}
+	} else {
+		if roleID, err = parseRole(id, r.PostFormValue("role")); err != nil {
+			return "", err
+		}
+		threshold, err = strconv.ParseInt(strings.TrimSpace(r.PostFormValue("threshold")), 10, 64)
+		if err != nil || threshold < 0 {
+			return "", errors.New("threshold must be a whole number of at least 0")
+		}
+	}
+	levelUpMsg := r.PostFormValue("level_up_msg") == "on"
+
+	err = plugins.UpdateConfig(messageRolesPlugin, formKeys[messageRolesPlugin], access, func(value string) (string, error) {
+		var roles []map[string]any
+		if err := decodeConfig(value, &roles); err != nil {
+			return "", err
+		}
+
+		found := false
+		updated := make([]map[string]any, 0, len(roles)+1)
+		for _, role := range roles {
+			if fmt.Sprint(role["role"]) != strconv.FormatInt(roleID, 10) {
+				updated = append(updated, role)
+				continue
+			}
+
+			found = true
+			if !remove {
+				role["threshold"] = threshold
+				role["level_up_msg"] = levelUpMsg
+				updated = append(updated, role)
+			}
+		}
+
+		switch {
+		case remove && !found:
+			return "", errors.New("that role isn't a message role")
+		case !remove && !found:
+			updated = append(updated, map[string]any{"role": roleID, "threshold": threshold, "level_up_msg": levelUpMsg})
+		}
+
+		return encodeConfig(updated)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if remove {
+		return "Removed the message role", nil
+	}
+	return "Saved the message role", nil
+}
+
+// updateRoleMenu will create a role menu, add a role to it, remove a role from it, or delete it, and edit its message
+func updateRoleMenu(r *http.Request, id discord.GuildID, access plugins.ConfigAccess, action string) (string, error) {
+	var channel, roleID int64
+	var emoji string
+	var err error
+
+	if action == "role_menu_create" || action == "role_menu_add" {
+		if emoji, err = parseEmoji(id, r.PostFormValue("emoji")); err != nil {
+			return "", err
+		}
+		if roleID, err = parseRole(id, r.PostFormValue("role")); err != nil {
+			return "", err
+		}
+	}
+
+	if action == "role_menu_create" {
+		if channel, err = parseChannel(id, r.PostFormValue("channel")); err != nil {
+			return "", err
+		} else if channel == 0 {
+			return "", errors.New("a channel is required to create a role menu")
+		}
+
+		msg, err := bot.Client.SendMessage(discord.ChannelID(channel), roleMenuText(map[string]any{emoji: roleID}))
+		if err != nil {
+			return "", fmt.Errorf("failed to send the role menu: %w", err)
+		}
+
+		err = plugins.UpdateConfig(roleMenuPlugin, formKeys[roleMenuPlugin]+"."+msg.ID.String(), access, func(string) (string, error) {
+			return encodeConfig(map[string]any{"channel": channel, "roles": map[string]any{emoji: map[string]any{"role_id": roleID}}})
+		})
+		if err != nil {
+			_ = bot.Client.DeleteMessage(msg.ChannelID, msg.ID, "failed to create role menu")
+			return "", err
+		}
+
+		reactRoleMenu(discord.ChannelID(channel), msg.ID, emoji, true)
+		return "Created the role menu", nil
+	}
+
+	menuID, err := discord.ParseSnowflake(r.PostFormValue("menu"))
+	if err != nil {
+		return "", errors.New("unknown role menu")
+	}
+	path := formKeys[roleMenuPlugin] + "." + menuID.String()
+
+	if action == "role_menu_delete" {
+		if !readRoleMenu(path, access, &channel) {
+			return "", errors.New("unknown role menu")
+		}
+		if err := plugins.ResetConfig(roleMenuPlugin, path, access); err != nil {
+			return "", err
+		}
+
+		if err := bot.Client.DeleteMessage(discord.ChannelID(channel), discord.MessageID(menuID), "role menu deleted from dashboard"); err != nil {
+			return "Deleted the role menu, but its message couldn't be deleted: " + err.Error(), nil
+		}
+		return "Deleted the role menu", nil
+	}
+
+	if action == "role_menu_remove" {
+		emoji = r.PostFormValue("emoji")
+	}
+
+	var roles map[string]any
+	err = plugins.UpdateConfig(roleMenuPlugin, path, access, func(value string) (string, error) {
+		var menu map[string]any
+		if err := decodeConfig(value, &menu); err != nil {
+			return "", err
+		}
+
+		// Missing menus are walked as an empty menu, which doesn't have a channel
+		n, _ := menu["channel"].(json.Number)
+		if channel, _ = n.Int64(); channel == 0 {
+			return "", errors.New("unknown role menu")
+		}
+
+		roles, _ = menu["roles"].(map[string]any)
+		if roles == nil {
+			roles = make(map[string]any)
+		}
+
+		if action == "role_menu_remove" {
+			if _, ok := roles[emoji]; !ok {
+				return "", errors.New("that emoji isn't in the role menu")
+			}
+			delete(roles, emoji)
+		} else {
+			roles[emoji] = map[string]any{"role_id": roleID}
+		}
+
+		menu["roles"] = roles
+		return encodeConfig(menu)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := bot.Client.EditMessage(discord.ChannelID(channel), discord.MessageID(menuID), roleMenuText(roles)); err != nil {
+		return "Saved the role menu, but its message couldn't be edited: " + err.Error(), nil
+	}
+	reactRoleMenu(discord.ChannelID(channel), discord.MessageID(menuID), emoji, action != "role_menu_remove")
+	return "Saved the role menu", nil
+}
+
+// readRoleMenu will read the channel of the role menu at path, and return false if it doesn't exist
+func readRoleMenu(path string, access plugins.ConfigAccess, channel *int64) bool {
+	res, err := plugins.GetConfig(roleMenuPlugin, path, access)
+	if err != nil {
+		return false
+	}
+
+	var menu struct {
+		Channel int64 `json:"channel"`
+	}
+	if err := json.Unmarshal([]byte(res), &menu); err != nil || menu.Channel == 0 {
+		return false
+	}
+
+	*channel = menu.Channel
+	return true
+}
+
+// roleMenuText will return the message of a role menu, with a line for each role like the role-menu plugin makes it
+func roleMenuText(roles map[string]any) string {
+	lines := make([]string, 0, len(roles))
+	for emoji, role := range roles {
+		formatted, _ := bot.EmojiConfigFormatted(emoji)
+
+		id := role
+		if m, ok := role.(map[string]any); ok {
+			id = m["role_id"]
+		}
+		lines = append(lines, fmt.Sprintf("%s <@&%v>", formatted, id))
+	}
+
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// reactRoleMenu will add or remove the bot's reaction with emoji on a role menu
+func reactRoleMenu(channel discord.ChannelID, menu discord.MessageID, emoji string, add bool) {
+	apiEmoji, err := bot.EmojiConfigAsApi(emoji)
+	if err == nil {
+		if add {
+			err = bot.Client.React(channel, menu, apiEmoji)
+		} else {
+			err = bot.Client.Unreact(channel, menu, apiEmoji)
+		}
+	}
+
+	if err != nil {
+		slog.Error("failed to react to role menu", "channel", channel, "menu", menu, "emoji", emoji, "err", err)
+	}
+}
+
+// decodeConfig will decode json from plugins.UpdateConfig into v, keeping numbers as they are so that ids don't lose
+// precision
+func decodeConfig(value string, v any) error {
+	d := json.NewDecoder(strings.NewReader(value))
+	d.UseNumber()
+	if err := d.Decode(v); err != nil {
+		return err
+	}
+
+	// A missing map key or slice is decoded from null, so start an empty map instead
+	if m, ok := v.(*map[string]any); ok && *m == nil {
+		*m = make(map[string]any)
+	}
+	return nil
+}
+
+func encodeConfig(v any) (string, error) {
+	bytes, err := json.Marshal(v)
+	return string(bytes), err
+}
+
+// roleName will return the name of the role with id, or its id if it isn't in roles
+func roleName(roles map[discord.RoleID]string, id int64) string {
+	if name, ok := roles[discord.RoleID(id)]; ok {
+		return name
+	}
+	return strconv.FormatInt(id, 10)
+}
+
+// emojiName will return how an emoji in the config format is shown on the dashboard, which can't show custom emojis
+func emojiName(emoji string) string {
+	if parts := strings.Split(strings.TrimPrefix(emoji, "a:"), ":"); len(parts) > 1 {
+		for _, part := range parts {
+			if len(part) > 0 {
+				return ":" + part + ":"
+			}
+		}
+	}
+
+	formatted, _ := bot.EmojiConfigFormatted(emoji)
+	return formatted
+}
+
+// parseColor will parse a color from a color input, such as #8d86ef
+func parseColor(value string) (discord.Color, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "#")
+	if len(value) == 0 {
+		return bot.DefaultColor, nil
+	}
+
+	color, err := strconv.ParseUint(value, 16, 32)
+	if err != nil || color > 0xffffff {
+		return 0, fmt.Errorf("`%s` is not a color", value)
+	}
+	return discord.Color(color), nil
+}
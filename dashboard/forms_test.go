@@ -0,0 +1,206 @@
+package dashboard
+
+import (
+	"github.com/5HT2/taro-bot/cmd/cmdtest"
+	"github.com/5HT2/taro-bot/plugins"
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// These configs only have the json keys that the forms use, like the configs of the real plugins
+
+type joinLeaveConfig struct {
+	Guilds plugins.GuildMap[joinLeaveGuild] `json:"guilds,omitempty"`
+}
+
+type joinLeaveGuild struct {
+	JoinMessage  joinLeaveMessage `json:"join_message"`
+	LeaveMessage joinLeaveMessage `json:"leave_message"`
+}
+
+type joinLeaveMessage struct {
+	Enabled     bool           `json:"enabled,omitempty"`
+	Channel     int64          `json:"channel,omitempty"`
+	Content     string         `json:"content,omitempty"`
+	Embed       *discord.Embed `json:"embed,omitempty"`
+	LastMessage int64          `json:"last_message,omitempty" taro:"hidden"`
+}
+
+type messageRolesConfig struct {
+	GuildRoles plugins.GuildMap[[]struct {
+		LevelUpMsg bool  `json:"level_up_msg"`
+		Threshold  int64 `json:"threshold"`
+		ID         int64 `json:"role"`
+	}] `json:"guild_configs,omitempty"`
+}
+
+type roleMenuConfig struct {
+	Menus plugins.GuildMap[map[string]roleMenu] `json:"menus"`
+}
+
+type roleMenu struct {
+	Channel int64 `json:"channel,omitempty"`
+	Roles   map[string]struct {
+		RoleID int64 `json:"role_id"`
+	} `json:"roles"`
+}
+
+// newFormHarness will create a harness with p, and return a function that posts a form to the guild page as the owner
+func newFormHarness(t *testing.T, p *plugins.Plugin) (*cmdtest.Harness, func(action string, form url.Values) int) {
+	h := newHarness(t, p)
+	cookie, csrf := login(t, h.Owner)
+
+	return h, func(action string, form url.Values) int {
+		form.Set("csrf", csrf)
+		form.Set("action", action)
+		return request(http.MethodPost, path+"guild/"+h.Guild.ID.String(), cookie, form).Code
+	}
+}
+
+func TestJoinLeaveForm(t *testing.T) {
+	store := plugins.NewStore[joinLeaveConfig](nil)
+	h, post := newFormHarness(t, &plugins.Plugin{Name: "Leave Join Msg", ConfigDir: joinLeavePlugin, Config: store})
+
+	store.Update(func(c *joinLeaveConfig) {
+		c.Guilds = plugins.GuildMap[joinLeaveGuild]{h.Guild.ID.String(): {JoinMessage: joinLeaveMessage{LastMessage: 5}}}
+	})
+
+	other := discord.Channel{ID: discord.ChannelID(h.Discord.NewID()), GuildID: discord.GuildID(h.Discord.NewID()), Type: discord.GuildText}
+	h.Discord.PutChannel(other)
+
+	for _, form := range []url.Values{
+		{"message": {"join_message"}, "enabled": {"on"}, "channel": {other.ID.String()}, "content": {"hi"}},
+		{"message": {"join_message"}, "enabled": {"on"}, "channel": {h.Channel.ID.String()}},
+		{"message": {"join_message"}, "channel": {h.Channel.ID.String()}, "embed_color": {"blue"}},
+		{"message": {"last_message"}, "channel": {h.Channel.ID.String()}},
+	} {
+		if code := post("join_leave", form); code != http.StatusBadRequest {
+			t.Errorf("expected join message %v to be rejected, got %v", form, code)
+		}
+	}
+
+	form := url.Values{"message": {"join_message"}, "enabled": {"on"}, "channel": {h.Channel.ID.String()}, "content": {"Welcome USER_ID"}, "embed_title": {"Hello"}, "embed_color": {"#0000ff"}}
+	if code := post("join_leave", form); code != http.StatusOK {
+		t.Fatalf("expected the join message to be saved, got %v", code)
+	}
+
+	g := store.Get().Guilds[h.Guild.ID.String()]
+	switch {
+	case !g.JoinMessage.Enabled || g.JoinMessage.Channel != int64(h.Channel.ID) || g.JoinMessage.Content != "Welcome USER_ID":
+		t.Errorf("expected the join message to be saved, got %+v", g.JoinMessage)
+	case g.JoinMessage.Embed == nil || g.JoinMessage.Embed.Title != "Hello" || g.JoinMessage.Embed.Color != 0x0000ff:
+		t.Errorf("expected the join embed to be saved, got %+v", g.JoinMessage.Embed)
+	case g.JoinMessage.LastMessage != 5:
+		t.Errorf("expected the hidden last message to be kept, got %v", g.JoinMessage.LastMessage)
+	}
+}
+
+func TestMessageRoleForm(t *testing.T) {
+	store := plugins.NewStore[messageRolesConfig](nil)
+	h, post := newFormHarness(t, &plugins.Plugin{Name: "Message Roles", ConfigDir: messageRolesPlugin, Config: store})
+
+	role := discord.Role{ID: discord.RoleID(h.Discord.NewID()), Name: "Regular"}
+	managed := discord.Role{ID: discord.RoleID(h.Discord.NewID()), Name: "Bot", Managed: true}
+	for _, r := range []discord.Role{role, managed} {
+		if err := h.Discord.PutRole(h.Guild.ID, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, form := range []url.Values{
+		{"role": {discord.RoleID(h.Discord.NewID()).String()}, "threshold": {"10"}},
+		{"role": {managed.ID.String()}, "threshold": {"10"}},
+		{"role": {h.Guild.ID.String()}, "threshold": {"10"}},
+		{"role": {role.ID.String()}, "threshold": {"-1"}},
+	} {
+		if code := post("message_role", form); code != http.StatusBadRequest {
+			t.Errorf("expected message role %v to be rejected, got %v", form, code)
+		}
+	}
+
+	if code := post("message_role", url.Values{"role": {role.Mention()}, "threshold": {"10"}}); code != http.StatusOK {
+		t.Fatalf("expected the message role to be added, got %v", code)
+	}
+	if code := post("message_role", url.Values{"role": {role.ID.String()}, "threshold": {"20"}, "level_up_msg": {"on"}}); code != http.StatusOK {
+		t.Fatalf("expected the message role to be updated, got %v", code)
+	}
+
+	roles := store.Get().GuildRoles[h.Guild.ID.String()]
+	if len(roles) != 1 || roles[0].ID != int64(role.ID) || roles[0].Threshold != 20 || !roles[0].LevelUpMsg {
+		t.Errorf("expected one message role with a threshold of 20, got %+v", roles)
+	}
+
+	if code := post("message_role_remove", url.Values{"role": {role.ID.String()}}); code != http.StatusOK {
+		t.Fatalf("expected the message role to be removed, got %v", code)
+	}
+	if roles := store.Get().GuildRoles[h.Guild.ID.String()]; len(roles) != 0 {
+		t.Errorf("expected no message roles, got %+v", roles)
+	}
+}
+
+func TestRoleMenuForm(t *testing.T) {
+	store := plugins.NewStore[roleMenuConfig](nil)
+	plugins.NewGuildStore(store, func(c *roleMenuConfig) *plugins.GuildMap[map[string]roleMenu] { return &c.Menus }, func() map[string]roleMenu {
+		return make(map[string]roleMenu)
+	})
+	h, post := newFormHarness(t, &plugins.Plugin{Name: "Role Menu", ConfigDir: roleMenuPlugin, Config: store})
+
+	role := discord.Role{ID: discord.RoleID(h.Discord.NewID()), Name: "Pings"}
+	if err := h.Discord.PutRole(h.Guild.ID, role); err != nil {
+		t.Fatal(err)
+	}
+
+	emoji, err := h.Discord.CreateEmoji(h.Guild.ID, api.CreateEmojiData{Name: "taro"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherGuild := discord.Guild{ID: discord.GuildID(h.Discord.NewID()), Name: "Other Guild"}
+	h.Discord.PutGuild(otherGuild)
+	otherEmoji, err := h.Discord.CreateEmoji(otherGuild.ID, api.CreateEmojiData{Name: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form := url.Values{"channel": {h.Channel.ID.String()}, "emoji": {"<:other:" + otherEmoji.ID.String() + ">"}, "role": {role.ID.String()}}
+	if code := post("role_menu_create", form); code != http.StatusBadRequest {
+		t.Errorf("expected an emoji from another guild to be rejected, got %v", code)
+	}
+
+	form = url.Values{"channel": {h.Channel.ID.String()}, "emoji": {"<:taro:" + emoji.ID.String() + ">"}, "role": {role.ID.String()}}
+	if code := post("role_menu_create", form); code != http.StatusOK {
+		t.Fatalf("expected the role menu to be created, got %v", code)
+	}
+
+	messages := h.Discord.Messages(h.Channel.ID)
+	if len(messages) != 1 || !strings.Contains(messages[0].Content, role.Mention()) {
+		t.Fatalf("expected the role menu to be sent, got %+v", messages)
+	}
+	menu := messages[0].ID.String()
+
+	if code := post("role_menu_add", url.Values{"menu": {menu}, "emoji": {"🍠"}, "role": {role.ID.String()}}); code != http.StatusOK {
+		t.Fatalf("expected the role to be added, got %v", code)
+	}
+
+	menus := store.Get().Menus[h.Guild.ID.String()]
+	if roles := menus[menu].Roles; len(roles) != 2 || roles[url.PathEscape("🍠")].RoleID != int64(role.ID) || roles[":taro:"+emoji.ID.String()].RoleID != int64(role.ID) {
+		t.Errorf("expected the role menu to have both emojis, got %+v", roles)
+	}
+
+	cookie, _ := login(t, h.Owner)
+	w := request(http.MethodGet, path+"guild/"+h.Guild.ID.String(), cookie, nil)
+	if body := w.Body.String(); w.Code != http.StatusOK || !strings.Contains(body, ":taro:") || !strings.Contains(body, "Pings") {
+		t.Errorf("expected the role menu to be shown, got %v: %s", w.Code, body)
+	}
+
+	if code := post("role_menu_delete", url.Values{"menu": {menu}}); code != http.StatusOK {
+		t.Fatalf("expected the role menu to be deleted, got %v", code)
+	}
+	if menus := store.Get().Menus[h.Guild.ID.String()]; len(menus) != 0 {
+		t.Errorf("expected no role menus, got %+v", menus)
+	}
+}
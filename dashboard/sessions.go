@@ -0,0 +1,166 @@
+package dashboard
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"github.com/5HT2/taro-bot/bot"
+	"github.com/5HT2/taro-bot/cmd"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const cookieName = "taro_session"
+
+var (
+	SessionDuration   = 12 * time.Hour   // SessionDuration is how long a login lasts
+	LoginLinkDuration = 10 * time.Minute // LoginLinkDuration is how long a link from LoginLink can be used for
+
+	sessions = struct {
+		sync.Mutex
+		sessions map[string]*session  // [session id]session
+		links    map[string]loginLink // [login code]link
+	}{sessions: make(map[string]*session), links: make(map[string]loginLink)}
+)
+
+// session is a logged in user of the dashboard, kept in memory, so restarting the bot logs everyone out
+type session struct {
+	user    discord.UserID // user is who logged in with a LoginLink, zero when logging in with bot.Config.DashboardToken
+	token   bool           // token is set when logging in with bot.Config.DashboardToken, which is always a bot operator
+	csrf    string         // csrf has to be sent with every form, so that other sites can't submit them
+	expires time.Time
+}
+
+// operator will return if the session can see everything, this is checked on every request so that removing an
+// operator logs them out of the operator pages
+func (s *session) operator() bool {
+	return s.token || cmd.IsOperator(s.user)
+}
+
+type loginLink struct {
+	user    discord.UserID
+	expires time.Time
+}
+
+// LoginLink will return a link that logs user into the dashboard once, within LoginLinkDuration.
+// It returns an error if bot.Config.DashboardUrl isn't set.
+func LoginLink(user discord.UserID) (string, error) {
+	url := ""
+	bot.C.Run(func(c *bot.Config) {
+		url = c.DashboardUrl
+	})
+	if len(url) == 0 {
+		return "", errors.New("the dashboard url is not set")
+	}
+
+	code, err := randomID()
+	if err != nil {
+		return "", err
+	}
+
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	removeExpired()
+	sessions.links[code] = loginLink{user: user, expires: time.Now().Add(LoginLinkDuration)}
+	return strings.TrimSuffix(url, "/") + "/login?code=" + code, nil
+}
+
+// useLoginLink will create a session for the user of the link with code, and remove the link
+func useLoginLink(code string) (string, *session, bool) {
+	sessions.Lock()
+	link, ok := sessions.links[code]
+	delete(sessions.links, code)
+	sessions.Unlock()
+
+	if !ok || time.Now().After(link.expires) {
+		return "", nil, false
+	}
+
+	return newSession(&session{user: link.user})
+}
+
+// useToken will create a session for a bot operator if token is bot.Config.DashboardToken
+func useToken(token string) (string, *session, bool) {
+	expected := ""
+	bot.C.Run(func(c *bot.Config) {
+		expected = c.DashboardToken
+	})
+
+	if len(expected) == 0 || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		return "", nil, false
+	}
+
+	return newSession(&session{token: true})
+}
+
+func newSession(s *session) (string, *session, bool) {
+	id, err := randomID()
+	if err != nil {
+		return "", nil, false
+	}
+	if s.csrf, err = randomID(); err != nil {
+		return "", nil, false
+	}
+	s.expires = time.Now().Add(SessionDuration)
+
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	removeExpired()
+	sessions.sessions[id] = s
+	return id, s, true
+}
+
+// getSession will return the session for the cookie of r, if it has one that hasn't expired
+func getSession(r *http.Request) (*session, bool) {
+	cookie, err := r.Cookie(cookieName)
+	if err != nil {
+		return nil, false
+	}
+
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	s, ok := sessions.sessions[cookie.Value]
+	if !ok || time.Now().After(s.expires) {
+		return nil, false
+	}
+	return s, true
+}
+
+// endSession will log out the session for the cookie of r
+func endSession(r *http.Request) {
+	if cookie, err := r.Cookie(cookieName); err == nil {
+		sessions.Lock()
+		defer sessions.Unlock()
+		delete(sessions.sessions, cookie.Value)
+	}
+}
+
+// removeExpired will remove the expired sessions and login links, sessions must be locked
+func removeExpired() {
+	now := time.Now()
+	for id, s := range sessions.sessions {
+		if now.After(s.expires) {
+			delete(sessions.sessions, id)
+		}
+	}
+	for code, l := range sessions.links {
+		if now.After(l.expires) {
+			delete(sessions.links, code)
+		}
+	}
+}
+
+func randomID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -1,6 +1,6 @@
 module github.com/5HT2/taro-bot
 
-go 1.18
+go 1.21
 
 require (
 	github.com/5HT2C/http-bash-requests v0.0.0-20230107083338-afbcb46f86cb
@@ -8,17 +8,24 @@ require (
 	github.com/forPelevin/gomoji v1.1.8
 	github.com/go-co-op/gocron v1.18.0
 	github.com/mackerelio/go-osstat v0.2.3
+	github.com/prometheus/client_golang v1.19.1
 	golang.org/x/net v0.23.0
 	golang.org/x/text v0.14.0
+	golang.org/x/time v0.3.0
 )
 
 require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
 	github.com/gorilla/schema v1.2.0 // indirect
 	github.com/gorilla/websocket v1.5.0 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
+	github.com/prometheus/client_model v0.5.0 // indirect
+	github.com/prometheus/common v0.48.0 // indirect
+	github.com/prometheus/procfs v0.12.0 // indirect
 	github.com/rivo/uniseg v0.4.4 // indirect
 	github.com/robfig/cron/v3 v3.0.1 // indirect
-	golang.org/x/sync v0.1.0 // indirect
+	golang.org/x/sync v0.3.0 // indirect
 	golang.org/x/sys v0.18.0 // indirect
-	golang.org/x/time v0.3.0 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
 )
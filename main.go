@@ -5,34 +5,47 @@ import (
 	"fmt"
 	"github.com/5HT2/taro-bot/bot"
 	"github.com/5HT2/taro-bot/cmd"
+	"github.com/5HT2/taro-bot/dashboard"
 	"github.com/5HT2/taro-bot/plugins"
 	"github.com/5HT2/taro-bot/util"
 	"github.com/diamondburned/arikawa/v3/gateway"
 	"github.com/diamondburned/arikawa/v3/state"
-	"log"
+	"github.com/diamondburned/arikawa/v3/utils/httputil/httpdriver"
+	"github.com/diamondburned/arikawa/v3/utils/ws"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"runtime"
 	"strings"
+	"sync/atomic"
 	"syscall"
+	"time"
 )
 
 var (
 	pluginDir = flag.String("plugindir", "bin", "Default dir to search for plugins")
-	debugLog  = flag.Bool("debug", false, "Debug messages and faster config saving")
+	debugLog  = flag.Bool("debug", false, "Log debug messages")
+	jsonLog   = flag.Bool("logjson", false, "Log as JSON instead of text")
+	httpAddr  = flag.String("httpaddr", "", "Address to serve metrics, health checks and the dashboard on, such as :6017, disabled if empty")
+
+	connected atomic.Bool // connected is set by the first gateway.ReadyEvent
 )
 
 func main() {
 	flag.Parse()
-	log.Printf("Running on Go version: %s\n", runtime.Version())
+	bot.SetupLogging(*debugLog, *jsonLog)
+	slog.Info("running on go version", "version", runtime.Version())
 
 	// Load configs before anything else, as it will be needed
 	bot.LoadConfig()
 	bot.LoadPluginConfig()
+	bot.LoadDurableJobs()
+	bot.LoadComponentStates()
+	bot.SetLogLevels(bot.C.LogLevels)
 	var token = bot.C.BotToken
 	if token == "" {
-		log.Fatalln("No bot_token given")
+		bot.Fatal("no bot_token given")
 	}
 
 	s := state.NewWithIntents("Bot "+token,
@@ -42,23 +55,56 @@ func main() {
 		gateway.IntentDirectMessages,
 		gateway.IntentGuildMembers,
 	)
-	bot.Client = *s
-
 	if s == nil {
-		log.Fatalln("Session failed: is nil")
+		bot.Fatal("session failed: is nil")
 	}
 
-	// Add handlers
+	// Record the latency and rate limits of requests to Discord
+	s.Client.Client.Client = httpdriver.WrapClient(http.Client{
+		Timeout:   10 * time.Second,
+		Transport: bot.MetricsTransport{RoundTripper: http.DefaultTransport},
+	})
+
+	bot.Client = bot.NewDiscord(s)
+
+	// Add handlers, which are dispatched so that shutting down can wait for them, and stops new events from being handled
 	s.AddHandler(func(e *gateway.MessageCreateEvent) {
-		go cmd.CommandHandler(e)
-		go cmd.ResponseHandler(e)
+		bot.Dispatch(func() { cmd.CommandHandler(e) })
+		bot.Dispatch(func() { cmd.ResponseHandler(e) })
+	})
+	s.AddHandler(func(e *gateway.MessageUpdateEvent) {
+		bot.Dispatch(func() { cmd.MessageUpdateHandler(e) })
+	})
+	s.AddHandler(func(e *gateway.MessageDeleteEvent) {
+		bot.Dispatch(func() { cmd.MessageDeleteHandler(e) })
+	})
+	s.AddHandler(func(e *gateway.MessageDeleteBulkEvent) {
+		bot.Dispatch(func() { cmd.MessageDeleteBulkHandler(e) })
+	})
+	s.AddHandler(func(e *gateway.InteractionCreateEvent) {
+		bot.Dispatch(func() { cmd.InteractionHandler(e) })
 	})
 	s.AddHandler(func(e *gateway.GuildMemberUpdateEvent) {
-		go cmd.UpdateMemberCache(e)
+		bot.Dispatch(func() { cmd.UpdateMemberCache(e) })
+	})
+	s.AddHandler(func(e *gateway.ReadyEvent) {
+		bot.SetGatewayConnected(true)
+
+		// The first ready event is from connecting, and the rest are from reconnecting with a new session
+		if connected.Swap(true) {
+			bot.GatewayReconnectsTotal.WithLabelValues("ready").Inc()
+		}
+	})
+	s.AddHandler(func(e *gateway.ResumedEvent) {
+		bot.SetGatewayConnected(true)
+		bot.GatewayReconnectsTotal.WithLabelValues("resumed").Inc()
+	})
+	s.AddHandler(func(e *ws.CloseEvent) {
+		bot.SetGatewayConnected(false)
 	})
 
 	if err := s.Open(bot.Ctx); err != nil {
-		log.Fatalln("Failed to connect:", err)
+		bot.Fatal("failed to connect", "err", err)
 	}
 
 	// Cancel context when SIGINT / SIGKILL / SIGTERM. SIGTERM is used by `docker stop`
@@ -66,12 +112,12 @@ func main() {
 	defer cancel()
 
 	if err := s.Open(ctx); err != nil {
-		log.Println("cannot open:", err)
+		slog.Error("cannot open", "err", err)
 	}
 
 	u, err := s.Me()
 	if err != nil {
-		log.Fatalln("Failed to get bot user:", err)
+		bot.Fatal("failed to get bot user", "err", err)
 	}
 	bot.User = u
 	http.DefaultClient = &bot.HttpClient
@@ -89,31 +135,58 @@ func main() {
 	// Now we can start the routine-based tasks
 	go bot.SetupConfigSaving()
 	go bot.Scheduler.StartAsync()
+	go bot.RunDurableJobs(ctx)
+	go bot.RunErrorDigests(ctx)
 
-	log.Printf("Started as %v (%s). Debugging is set to `%v`.\n", u.ID, util.FormattedUserTag(*u), *debugLog)
+	bot.AddHealthCheck(bot.HealthCheck{Name: "plugins", Ready: true, Fn: plugins.HealthCheck})
+	bot.ServeHealth()
+	bot.ServeMetrics()
+	dashboard.Serve()
+	go bot.ServeHTTP(ctx, *httpAddr)
+
+	slog.Info("started", "id", u.ID, "user", util.FormattedUserTag(*u), "debug", *debugLog)
 
 	go checkGuildCounts(s)
 
 	<-ctx.Done() // block until Ctrl+C / SIGINT / SIGTERM
 
-	log.Println("received signal, shutting down")
-
-	bot.SaveConfig()
-	bot.SavePluginConfig()
-	plugins.SaveConfig()
-	plugins.Shutdown()
+	slog.Info("received signal, shutting down")
+	shutdown()
 
 	if err := s.Close(); err != nil {
-		log.Println("cannot close:", err)
+		slog.Error("cannot close", "err", err)
 	}
 
-	log.Println("closed connection")
+	slog.Info("closed connection")
+}
+
+// shutdown will stop handling new events, wait up to bot.ShutdownTimeout for the running commands, responses,
+// handlers and jobs, and then run the plugins' ShutdownFn and save everything. Saving is done last, so that nothing
+// changes the configs after they are saved.
+func shutdown() {
+	timeout := bot.ShutdownTimeout()
+	deadline := time.Now().Add(timeout)
+
+	if !bot.Drain(timeout) {
+		slog.Warn("timed out waiting for running work, saving anyway", "running", bot.Running())
+	}
+	if !bot.StopScheduler(time.Until(deadline)) {
+		slog.Warn("timed out waiting for scheduled jobs, saving anyway")
+	}
+
+	plugins.Shutdown()
+
+	bot.SaveConfig()
+	bot.SavePluginConfig()
+	bot.SaveDurableJobs()
+	bot.SaveComponentStates()
+	plugins.SaveConfig()
 }
 
 func checkGuildCounts(s *state.State) {
 	guilds, err := s.Guilds()
 	if err != nil {
-		log.Printf("checkGuildCounts: %v\n", err)
+		slog.Error("failed to get guilds", "err", err)
 	}
 
 	fmtGuilds := make([]string, 0)
@@ -126,10 +199,8 @@ func checkGuildCounts(s *state.State) {
 		}
 	}
 
-	log.Printf(
-		"Currently serving %s on %s\n%s",
-		util.JoinIntAndStr(members, "user"),
-		util.JoinIntAndStr(len(guilds), "guild"),
-		strings.Join(fmtGuilds, "\n"),
+	slog.Info(
+		"currently serving "+util.JoinIntAndStr(members, "user")+" on "+util.JoinIntAndStr(len(guilds), "guild"),
+		"guilds", strings.Join(fmtGuilds, "\n"),
 	)
 }
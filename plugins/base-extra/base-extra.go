@@ -13,13 +13,16 @@ import (
 	"github.com/diamondburned/arikawa/v3/api"
 	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/diamondburned/arikawa/v3/utils/json/option"
-	"log"
 	"mime/multipart"
 	"net/http"
 	"strings"
 	"time"
 )
 
+var (
+	maxShellOutput = 40000 // maxShellOutput is the most characters of shell output that are shown by SudoCommand, about 20 pages
+)
+
 func InitPlugin(_ *plugins.PluginInit) *plugins.Plugin {
 	return &plugins.Plugin{
 		Name:        "Taro Base Extra",
@@ -51,12 +54,16 @@ func InitPlugin(_ *plugins.PluginInit) *plugins.Plugin {
 			Name:        "sudo",
 			Aliases:     []string{"#", "su"},
 			Description: "Operator-only commands",
+			Permission:  "operator",
 		}},
 		Responses: []bot.ResponseInfo{{
-			Fn:           BashResponse,
-			Regexes:      []string{"."},
-			MatchMin:     1,
-			LockChannels: []int64{bot.C.OperatorChannel},
+			Fn:            BashResponse,
+			Regexes:       []string{"."},
+			MatchMin:      1,
+			LockChannels:  []int64{bot.C.OperatorChannel},
+			Priority:      100,
+			Exclusive:     true,
+			AllowCommands: true, // messages in the operator channel are never commands, they are all run as bash
 		}},
 	}
 }
@@ -76,7 +83,7 @@ func ChannelCommand(c bot.Command) error {
 	defaultResponse := func() error {
 		_, err := cmd.SendEmbed(c.E,
 			"Channel",
-			"Available arguments are:\n- `archive`\n- `archive role|category [role id|category id]`\n- `slow [seconds]`",
+			c.T("Available arguments are:")+"\n- `archive`\n- `archive role|category [role id|category id]`\n- `slow [seconds]`",
 			bot.DefaultColor)
 		return err
 	}
@@ -290,7 +297,7 @@ func PermissionCommand(c bot.Command) error {
 	default:
 		_, err := cmd.SendEmbed(c.E,
 			"Permissions",
-			"Available arguments are:\n- `give` <permission> <user>\n- `op`",
+			c.T("Available arguments are:")+"\n- `give` <permission> <user>\n- `op`",
 			bot.DefaultColor)
 		return err
 	}
@@ -510,7 +517,7 @@ func SudoCommand(c bot.Command) error {
 
 						// Get a config from a URL
 						urlMatch := cmd.UrlRegex.FindStringSubmatch(args[0])
-						log.Printf("urlMatch: %s\n", urlMatch)
+						c.Log().Debug("importing aliases from url", "match", urlMatch)
 						if len(urlMatch) != -1 {
 							go func() {
 								msg, _ := cmd.SendEmbed(c.E, c.Name+" `alias --import`", "Found URL as parameter, attempting to load from URL", bot.WarnColor)
@@ -530,7 +537,7 @@ func SudoCommand(c bot.Command) error {
 								urlMatch[0] = cf.FohPrivateUrl + cf.FohPrivateDir + strings.TrimPrefix(urlMatch[0], cf.FohPublicUrl+cf.FohPublicDir)
 							}
 
-							log.Printf("urlMatch: %s\n", urlMatch)
+							c.Log().Debug("importing aliases from url", "match", urlMatch)
 
 							// Request b64 content from URL
 							if content, _, err1 := util.RequestUrlFn(urlMatch[0], http.MethodGet, func(req *http.Request) {
@@ -588,7 +595,7 @@ func SudoCommand(c bot.Command) error {
 	case "-h":
 		_, err := cmd.SendEmbed(c.E,
 			c.Name,
-			"Available arguments are:\n- `alias <name> [command]`\n- `alias -r <name>`\n- `alias -l|-h|--export|--import`",
+			c.T("Available arguments are:")+"\n- `alias <name> [command]`\n- `alias -r <name>`\n- `alias -l|-h|--export|--import`",
 			bot.DefaultColor)
 		return err
 	default: // Default to running a bash shell
@@ -598,7 +605,8 @@ func SudoCommand(c bot.Command) error {
 			if res, err := httpBashRequests.Run(strings.Join(args, " ") + " 2>&1"); err != nil {
 				return err
 			} else {
-				_, err := cmd.SendEmbed(c.E, "", fmt.Sprintf("```\n%s\n```", util.TailLinesLimit(string(res), 2040)), bot.DefaultColor)
+				// Very long output is still cut off, keeping the end of it, so that it doesn't make too many pages
+				_, err := cmd.SendPaginator(c.E, cmd.PageCode("", util.TailLinesLimit(string(res), maxShellOutput), bot.DefaultColor))
 				return err
 			}
 		}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/5HT2/taro-bot/bot"
 	"github.com/5HT2/taro-bot/cmd"
 	"github.com/5HT2/taro-bot/plugins"
@@ -10,10 +11,27 @@ import (
 	"github.com/diamondburned/arikawa/v3/discord"
 	"net/http"
 	"strconv"
+	"time"
 )
 
-func InitPlugin(_ *plugins.PluginInit) *plugins.Plugin {
-	return &plugins.Plugin{
+var (
+	store = plugins.NewStore(defaultConfig)
+)
+
+type config struct {
+	FrogUrl  string `json:"frog_url" taro:"operator"`  // FrogUrl is the frog.pics API, see FrogCommand
+	EmojiUrl string `json:"emoji_url" taro:"operator"` // EmojiUrl is where emoji images are downloaded from, see StealEmojiCommand
+}
+
+func defaultConfig() config {
+	return config{
+		FrogUrl:  "https://frog.pics",
+		EmojiUrl: "https://cdn.discordapp.com",
+	}
+}
+
+func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
+	p := &plugins.Plugin{
 		Name:        "Taro Base Fun",
 		Description: "The fun commands as included as part of the bot",
 		Version:     "1.0.0",
@@ -22,6 +40,7 @@ func InitPlugin(_ *plugins.PluginInit) *plugins.Plugin {
 			FnName:      "FrogCommand",
 			Name:        "frog",
 			Description: "\\*hands you a random frog pic\\*",
+			Cooldowns:   []bot.Cooldown{{Scope: bot.CooldownUser, Duration: 5 * time.Second}},
 		}, {
 			Fn:          StealEmojiCommand,
 			FnName:      "StealEmojiCommand",
@@ -29,13 +48,21 @@ func InitPlugin(_ *plugins.PluginInit) *plugins.Plugin {
 			Aliases:     []string{"se"},
 			Description: "Upload an emoji to the current guild",
 			GuildOnly:   true,
+			Cooldowns: []bot.Cooldown{
+				{Scope: bot.CooldownUser, Duration: 10 * time.Second},
+				{Scope: bot.CooldownGuild, Duration: 3 * time.Second},
+			},
 		}},
 		Responses: []bot.ResponseInfo{},
+		Config:    store,
 	}
+	p.ConfigDir = i.ConfigDir
+	p.LoadConfig()
+	return p
 }
 
 func FrogCommand(c bot.Command) error {
-	frogData, _, err := util.RequestUrl("https://frog.pics/api/random", http.MethodGet)
+	frogData, _, err := util.RequestUrl(store.Get().FrogUrl+"/api/random", http.MethodGet)
 	if err != nil {
 		return err
 	}
@@ -91,7 +118,7 @@ func StealEmojiCommand(c bot.Command) error {
 	//
 	// we now have the emoji ID and name, get the bytes
 
-	url := "https://cdn.discordapp.com/emojis/" + strconv.FormatInt(emojiID, 10)
+	url := store.Get().EmojiUrl + "/emojis/" + strconv.FormatInt(emojiID, 10)
 	bytes, res, err := util.RequestUrl(url+".gif", http.MethodGet)
 	if err != nil {
 		return err
@@ -103,7 +130,7 @@ func StealEmojiCommand(c bot.Command) error {
 		}
 
 		if res.StatusCode != 200 {
-			return bot.GenericError(c.FnName, "getting emoji bytes", "status was "+res.Status)
+			return bot.UpstreamError(c.FnName, "getting emoji bytes", "couldn't download the emoji", errors.New("status was "+res.Status))
 		}
 	}
 
@@ -120,7 +147,7 @@ func StealEmojiCommand(c bot.Command) error {
 
 	if emoji, err := bot.Client.CreateEmoji(c.E.GuildID, createEmojiData); err != nil {
 		// error with uploading
-		return bot.GenericError(c.FnName, "uploading emoji", err.Error())
+		return bot.UpstreamError(c.FnName, "uploading emoji", "couldn't upload the emoji, check that the server has free emoji slots", err)
 	} else {
 		// uploaded successfully, send a nice embed
 		_, err := bot.Client.SendMessage(
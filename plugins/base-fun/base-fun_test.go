@@ -0,0 +1,50 @@
+package main
+
+import (
+	"github.com/5HT2/taro-bot/bot"
+	"github.com/5HT2/taro-bot/cmd/cmdtest"
+	"github.com/5HT2/taro-bot/plugins"
+	"github.com/5HT2/taro-bot/util/fixtures"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"strings"
+	"testing"
+)
+
+func TestFrog(t *testing.T) {
+	h := cmdtest.New(t, InitPlugin(&plugins.PluginInit{ConfigDir: "base-fun"}))
+	s := fixtures.NewServer(t, "testdata/frog", defaultConfig().FrogUrl)
+	store.Update(func(c *config) { c.FrogUrl = s.URL })
+
+	h.Send(bot.DefaultPrefix + "frog")
+	embeds := h.LastReply().Embeds
+	if len(embeds) != 1 || embeds[0].Image == nil {
+		t.Fatalf("expected an embed with the frog, got %v", embeds)
+	}
+
+	if got := embeds[0].Image.URL; got != "https://frog.pics/images/frog.jpg" {
+		t.Errorf("expected the image from the API, got %q", got)
+	}
+	if got := embeds[0].Color; got != discord.Color(0x4c7a3d) {
+		t.Errorf("expected the median color of the image, got %v", got)
+	}
+}
+
+func TestStealEmoji(t *testing.T) {
+	h := cmdtest.New(t, InitPlugin(&plugins.PluginInit{ConfigDir: "base-fun"}))
+	s := fixtures.NewServer(t, "testdata/emoji", defaultConfig().EmojiUrl)
+	store.Update(func(c *config) { c.EmojiUrl = s.URL })
+
+	// The emoji isn't animated, so there is only a png
+	h.Send(bot.DefaultPrefix + "stealemoji 123456789012345678 frog")
+	if got := cmdtest.Text(h.LastReply()); !strings.Contains(got, "Emoji stolen") {
+		t.Fatalf("expected the emoji to be stolen, got %q", got)
+	}
+
+	g, err := h.Discord.Guild(h.Guild.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Emojis) != 1 || g.Emojis[0].Name != "frog" {
+		t.Errorf("expected the emoji to be uploaded to the guild, got %v", g.Emojis)
+	}
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"embed"
 	"fmt"
 	"github.com/5HT2/taro-bot/bot"
 	"github.com/5HT2/taro-bot/cmd"
+	"github.com/5HT2/taro-bot/dashboard"
 	"github.com/5HT2/taro-bot/plugins"
 	"github.com/5HT2/taro-bot/util"
 	"github.com/diamondburned/arikawa/v3/discord"
@@ -11,11 +13,15 @@ import (
 	"strings"
 )
 
+//go:embed locales/*.json
+var locales embed.FS
+
 func InitPlugin(_ *plugins.PluginInit) *plugins.Plugin {
 	return &plugins.Plugin{
 		Name:        "Taro Base",
 		Description: "The base commands and responses included as part of the bot",
 		Version:     "1.0.1",
+		Locales:     locales,
 		Commands: []bot.CommandInfo{{
 			Fn:          InviteCommand,
 			FnName:      "InviteCommand",
@@ -26,13 +32,43 @@ func InitPlugin(_ *plugins.PluginInit) *plugins.Plugin {
 			FnName:      "HelpCommand",
 			Name:        "help",
 			Aliases:     []string{"h"},
-			Description: "Print a list of available commands",
+			Description: "Print a list of available commands, or `help <command|plugin|search>` for more",
+			Usage:       "[command|plugin|search]",
 		}, {
 			Fn:          OperatorConfigCommand,
 			FnName:      "OperatorConfigCommand",
 			Name:        "operatorconfig",
 			Aliases:     []string{"opcfg"},
 			Description: "Allows the bot operator to configure bot-level settings",
+			Usage:       "[setting] [value]",
+			Permission:  "operator",
+		}, {
+			Fn:          PluginsCommand,
+			FnName:      "PluginsCommand",
+			Name:        "plugins",
+			Aliases:     []string{"pl"},
+			Description: "Allows the bot operator to see which plugins loaded and why others didn't, or `enable` a disabled plugin",
+			Usage:       "[enable <plugin>]",
+			Permission:  "operator",
+		}, {
+			Fn:          ConfigCommand,
+			FnName:      "ConfigCommand",
+			Name:        "config",
+			Aliases:     []string{"cfg"},
+			Description: "View and edit plugin configs",
+			Usage:       "[plugin] [get|set|reset] [path] [value]",
+		}, {
+			Fn:          DashboardCommand,
+			FnName:      "DashboardCommand",
+			Name:        "dashboard",
+			Description: "DMs you a link to log into the web dashboard",
+		}, {
+			Fn:          JobsCommand,
+			FnName:      "JobsCommand",
+			Name:        "jobs",
+			Description: "Allows the bot operator to list scheduled jobs, or `cancel` one",
+			Usage:       "[cancel <key>]",
+			Permission:  "operator",
 		}, {
 			Fn:          PingCommand,
 			FnName:      "PingCommand",
@@ -43,7 +79,29 @@ func InitPlugin(_ *plugins.PluginInit) *plugins.Plugin {
 			FnName:      "PrefixCommand",
 			Name:        "prefix",
 			Description: "Set the bot prefix for your guild",
+			Usage:       "<prefix>",
+			GuildOnly:   true,
+		}, {
+			Fn:          LocaleCommand,
+			FnName:      "LocaleCommand",
+			Name:        "locale",
+			Aliases:     []string{"language", "lang"},
+			Description: "View or set the language of the bot's messages for you, or for the guild",
+			Usage:       "[user|guild] [locale|reset]",
+		}, {
+			Fn:          DeleteRepliesCommand,
+			FnName:      "DeleteRepliesCommand",
+			Name:        "deletereplies",
+			Description: "Toggle if the bot deletes its replies to commands that are deleted",
+			GuildOnly:   true,
+			Permission:  "moderate",
+		}, {
+			Fn:          SuggestionsCommand,
+			FnName:      "SuggestionsCommand",
+			Name:        "suggestions",
+			Description: "Toggle if the bot suggests similar commands for unknown ones",
 			GuildOnly:   true,
+			Permission:  "moderate",
 		}},
 		Responses: []bot.ResponseInfo{{
 			Fn:       PrefixResponse,
@@ -106,6 +164,15 @@ func OperatorConfigCommand(c bot.Command) error {
 				_, err = cmd.SendEmbedFooter(c.E, t+"`operator_channel`", fmt.Sprintf("Set `operator_channel` to `%v`", co.OperatorChannel), "This change might take a reload to apply!", bot.WarnColor)
 			}
 		})
+	case "error_digest":
+		bot.C.Run(func(co *bot.Config) {
+			if len(args) == 0 {
+				_, err = cmd.SendEmbed(c.E, t+"`error_digest`", fmt.Sprintf("The current `error_digest` is `%v` seconds, `0` is the default of `%v`", co.ErrorDigest, bot.DefaultErrorDigest), bot.DefaultColor)
+			} else {
+				co.ErrorDigest = argInt
+				_, err = cmd.SendEmbedFooter(c.E, t+"`error_digest`", fmt.Sprintf("Set `error_digest` to `%v` seconds", co.ErrorDigest), "This applies after the next digest, -1 disables digests", bot.SuccessColor)
+			}
+		})
 	case "operator_ids":
 		bot.C.Run(func(co *bot.Config) {
 			if len(args) == 0 {
@@ -133,29 +200,316 @@ func OperatorConfigCommand(c bot.Command) error {
 	default:
 		_, err = cmd.SendEmbed(c.E,
 			"Operator Config",
-			"Available arguments are:\n- `activity_name [activity name]`\n- `activity_url [activity url]`\n- `activity_type [activity type]`\n- `operator_channel [operator channel id]`\n- `operator_ids [operator ids]`\n- `reset_prefix [guild id]`",
+			c.T("Available arguments are:")+"\n- `activity_name [activity name]`\n- `activity_url [activity url]`\n- `activity_type [activity type]`\n- `operator_channel [operator channel id]`\n- `error_digest [seconds]`\n- `operator_ids [operator ids]`\n- `reset_prefix [guild id]`",
 			bot.DefaultColor)
 	}
 
 	return err
 }
 
+func PluginsCommand(c bot.Command) error {
+	if err := cmd.HasPermission(c, cmd.PermOperator); err != nil {
+		return err
+	}
+
+	if arg, _ := cmd.ParseStringArg(c.Args, 1, true); arg == "enable" {
+		name, argErr := cmd.ParseStringArg(c.Args, 2, true)
+		if argErr != nil {
+			return argErr
+		}
+
+		if err := plugins.Enable(name); err != nil {
+			return err
+		}
+
+		_, err := cmd.SendEmbed(c.E, "Plugins", "Enabled `"+name+"`", bot.SuccessColor)
+		return err
+	}
+
+	statuses := plugins.Statuses()
+	if len(statuses) == 0 {
+		_, err := cmd.SendEmbed(c.E, "Plugins", "No plugins have been loaded yet!", bot.WarnColor)
+		return err
+	}
+
+	loaded := 0
+	lines := make([]string, 0)
+	for _, s := range statuses {
+		version := ""
+		if s.Manifest != nil {
+			version = " `" + s.Manifest.Version + "`"
+		}
+
+		switch s.State {
+		case plugins.StateLoaded:
+			loaded++
+			if p := plugins.Find(s.Name); p != nil && p.Disabled() {
+				lines = append(lines, fmt.Sprintf("⛔ **%s**%s (disabled)\nPanicked %s, use `plugins enable %s`", s.Name, version, util.JoinInt64AndStr(p.Panics(), "time"), s.Name))
+			} else if p != nil && p.Panics() > 0 {
+				lines = append(lines, fmt.Sprintf("✅ **%s**%s (%s)", s.Name, version, util.JoinInt64AndStr(p.Panics(), "panic")))
+			} else {
+				lines = append(lines, fmt.Sprintf("✅ **%s**%s", s.Name, version))
+			}
+		default:
+			lines = append(lines, fmt.Sprintf("⛔ **%s**%s (%s)\n%s", s.Name, version, s.State, s.Reason))
+		}
+
+		for _, w := range s.Warnings {
+			lines = append(lines, "⚠️ "+w)
+		}
+	}
+
+	color := bot.SuccessColor
+	if loaded == 0 {
+		color = bot.ErrorColor
+	} else if loaded < len(statuses) {
+		color = bot.WarnColor
+	}
+
+	_, err := cmd.SendEmbedFooter(c.E,
+		"Plugins",
+		util.HeadLinesLimit(strings.Join(lines, "\n"), 4096),
+		fmt.Sprintf("%v/%s loaded, plugin API v%v, bot v%s", loaded, util.JoinIntAndStr(len(statuses), "plugin"), plugins.APIVersion, bot.Version),
+		color)
+	return err
+}
+
+func ConfigCommand(c bot.Command) error {
+	access := plugins.ConfigAccess{Operator: cmd.HasPermission(c, cmd.PermOperator) == nil}
+	if c.E.GuildID.IsValid() {
+		access.Guild = c.E.GuildID.String()
+	}
+
+	if !access.Operator {
+		if err := cmd.HasPermission(c, cmd.PermModerate); err != nil {
+			return err
+		}
+	}
+
+	name, _ := cmd.ParseStringArg(c.Args, 1, true)
+	action, _ := cmd.ParseStringArg(c.Args, 2, true)
+	path, _ := cmd.ParseStringArg(c.Args, 3, false)
+	args, _ := cmd.ParseStringSliceArg(c.Args, 4, -1)
+	value := strings.Join(args, " ")
+
+	title := "Config"
+	if len(name) > 0 {
+		title += " `" + name + "`"
+	}
+	if len(path) > 0 {
+		title += " `" + path + "`"
+	}
+
+	var err error
+	var res string
+
+	switch action {
+	case "", "get":
+		if len(name) == 0 {
+			_, err = cmd.SendEmbed(c.E, "Config",
+				"Available plugins are:\n"+"`"+strings.Join(plugins.Configs(), "`, `")+"`"+
+					"\n\nUsage:\n- `config <plugin> get [path]`\n- `config <plugin> set <path> <value>`\n- `config <plugin> reset <path>`",
+				bot.DefaultColor)
+			return err
+		}
+
+		if res, err = plugins.GetConfig(name, path, access); err != nil {
+			return bot.GenericError(c.FnName, "getting config", err.Error())
+		}
+
+		_, err = cmd.SendEmbed(c.E, title, "```json\n"+util.HeadLinesLimit(res, 4000)+"\n```", bot.DefaultColor)
+	case "set":
+		if len(args) == 0 {
+			return bot.GenericSyntaxError(c.FnName, path, "expected a value to set")
+		}
+
+		if res, err = plugins.SetConfig(name, path, value, access); err != nil {
+			return bot.GenericError(c.FnName, "setting config", err.Error())
+		}
+
+		_, err = cmd.SendEmbed(c.E, title, "Set to\n```json\n"+util.HeadLinesLimit(res, 4000)+"\n```", bot.SuccessColor)
+	case "reset":
+		if err = plugins.ResetConfig(name, path, access); err != nil {
+			return bot.GenericError(c.FnName, "resetting config", err.Error())
+		}
+
+		_, err = cmd.SendEmbed(c.E, title, "Reset to the default value", bot.SuccessColor)
+	default:
+		return bot.GenericSyntaxError(c.FnName, action, "expected `get`, `set` or `reset`")
+	}
+
+	return err
+}
+
+func DashboardCommand(c bot.Command) error {
+	link, err := dashboard.LoginLink(c.E.Author.ID)
+	if err != nil {
+		return bot.GenericError(c.FnName, "creating login link", err.Error())
+	}
+
+	if _, err = cmd.SendDirectMessage(c.E.Author.ID, "Log into the dashboard with "+link+"\nThis link works once, and expires in "+dashboard.LoginLinkDuration.String()); err != nil {
+		return bot.GenericError(c.FnName, "sending login link", "couldn't DM you, check that you allow DMs from this server")
+	}
+
+	_, err = cmd.SendEmbed(c.E, "Dashboard", "Sent you a login link in your DMs", bot.SuccessColor)
+	return err
+}
+
+func JobsCommand(c bot.Command) error {
+	if err := cmd.HasPermission(c, cmd.PermOperator); err != nil {
+		return err
+	}
+
+	if arg, _ := cmd.ParseStringArg(c.Args, 1, true); arg == "cancel" {
+		key, argErr := cmd.ParseStringArg(c.Args, 2, false)
+		if argErr != nil {
+			return argErr
+		}
+
+		if !bot.CancelDurableJob(key) {
+			return bot.NotFoundError(c.FnName, "cancelling job", "no job with key `"+key+"`")
+		}
+
+		_, err := cmd.SendEmbed(c.E, "Jobs", "Cancelled `"+key+"`", bot.SuccessColor)
+		return err
+	}
+
+	jobs := bot.DurableJobs()
+	if len(jobs) == 0 {
+		_, err := cmd.SendEmbed(c.E, "Jobs", "No jobs are scheduled!", bot.WarnColor)
+		return err
+	}
+
+	lines := make([]string, 0)
+	for _, job := range jobs {
+		line := fmt.Sprintf("`%s` (%s) <t:%v:R>", job.Key, job.Kind, job.RunAt.Unix())
+		if job.Interval > 0 {
+			line += ", every " + util.FormattedTime(job.Interval)
+		}
+		if job.Attempts > 0 {
+			line += ", " + util.JoinInt64AndStr(job.Attempts, "failed attempt")
+		}
+
+		lines = append(lines, line)
+	}
+
+	_, err := cmd.SendEmbedFooter(c.E,
+		"Jobs",
+		util.HeadLinesLimit(strings.Join(lines, "\n"), 4096),
+		util.JoinIntAndStr(len(jobs), "job")+" scheduled",
+		bot.DefaultColor)
+	return err
+}
+
 func HelpCommand(c bot.Command) error {
-	fmtCmds := make([]string, 0)
+	commands := make([]bot.CommandInfo, 0)
 	for _, command := range bot.Commands {
-		// Filter GuildOnly commands when not in a guild
-		if !command.GuildOnly || c.E.GuildID.IsValid() {
-			fmtCmds = append(fmtCmds, command.MarkdownString())
+		// Filter GuildOnly commands when not in a guild, and commands the user can't use
+		if (!command.GuildOnly || c.E.GuildID.IsValid()) && cmd.CanUse(c, command) {
+			commands = append(commands, command)
 		}
 	}
 
-	_, err := cmd.SendEmbed(c.E,
-		"Taro Help",
-		strings.Join(fmtCmds, "\n\n"),
-		bot.DefaultColor)
+	arg := strings.ToLower(strings.Join(c.Args, " "))
+	if len(arg) == 0 {
+		_, err := cmd.SendPaginator(c.E, helpPages(commands, plugins.Loaded()))
+		return err
+	}
+
+	// help <command>
+	for _, command := range commands {
+		if command.Name == arg || util.SliceContains(command.Aliases, arg) {
+			_, err := cmd.SendCustomEmbed(c.E.ChannelID, commandHelp(command))
+			return err
+		}
+	}
+
+	// help <plugin>
+	for _, p := range plugins.Loaded() {
+		if p.ConfigDir == arg || strings.ToLower(p.Name) == arg {
+			_, err := cmd.SendPaginator(c.E, helpPages(commands, []*plugins.Plugin{p}))
+			return err
+		}
+	}
+
+	// help <search>
+	found := make([]string, 0)
+	for _, command := range commands {
+		if strings.Contains(command.Name, arg) || strings.Contains(strings.Join(command.Aliases, " "), arg) ||
+			strings.Contains(strings.ToLower(command.Description), arg) {
+			found = append(found, command.MarkdownString())
+		}
+	}
+
+	if len(found) == 0 {
+		return bot.NotFoundError(c.FnName, "searching help", "no commands or plugins found matching `"+arg+"`")
+	}
+
+	_, err := cmd.SendPaginator(c.E, cmd.PageLines("Taro Help: "+arg, found, bot.DefaultColor))
 	return err
 }
 
+// helpPages will return the pages of help for commands, grouped by the plugin in ps that registered them
+func helpPages(commands []bot.CommandInfo, ps []*plugins.Plugin) []discord.Embed {
+	pages := make([]discord.Embed, 0)
+	for _, p := range ps {
+		lines := make([]string, 0)
+		for _, command := range commands {
+			if command.Plugin == p.ConfigDir {
+				lines = append(lines, command.MarkdownString()+"\n")
+			}
+		}
+
+		if len(lines) == 0 {
+			continue
+		}
+
+		if len(p.Description) > 0 {
+			lines = append([]string{"*" + p.Description + "*\n"}, lines...)
+		}
+		pages = append(pages, cmd.PageLines("Taro Help: "+p.Name, lines, bot.DefaultColor)...)
+	}
+
+	if len(pages) == 0 {
+		pages = append(pages, cmd.MakeEmbed("Taro Help", "There are no commands that you can use here!", bot.WarnColor))
+	}
+	return pages
+}
+
+// commandHelp will return an embed describing command
+func commandHelp(command bot.CommandInfo) discord.Embed {
+	embed := cmd.MakeEmbed("Taro Help: "+command.Name, command.Description, bot.DefaultColor)
+	if len(command.Description) == 0 {
+		embed.Description = "No Description"
+	}
+
+	if len(command.Aliases) > 0 {
+		embed.Fields = append(embed.Fields, discord.EmbedField{Name: "Aliases", Value: "`" + strings.Join(command.Aliases, "`, `") + "`"})
+	}
+	if p := plugins.Find(command.Plugin); p != nil {
+		embed.Fields = append(embed.Fields, discord.EmbedField{Name: "Plugin", Value: p.Name, Inline: true})
+	}
+	if len(command.Permission) > 0 {
+		embed.Fields = append(embed.Fields, discord.EmbedField{Name: "Permission", Value: command.Permission, Inline: true})
+	}
+	if command.GuildOnly {
+		embed.Fields = append(embed.Fields, discord.EmbedField{Name: "Guild Only", Value: "Yes", Inline: true})
+	}
+	if len(command.Usage) > 0 {
+		embed.Fields = append(embed.Fields, discord.EmbedField{Name: "Usage", Value: "`" + command.Name + " " + command.Usage + "`"})
+	}
+
+	if len(command.Cooldowns) > 0 {
+		cooldowns := make([]string, 0)
+		for _, cooldown := range command.Cooldowns {
+			cooldowns = append(cooldowns, fmt.Sprintf("%s per %s", util.FormattedTime(int64(cooldown.Duration.Seconds())), cooldown.Scope))
+		}
+		embed.Fields = append(embed.Fields, discord.EmbedField{Name: "Cooldowns", Value: strings.Join(cooldowns, "\n"), Inline: true})
+	}
+
+	return embed
+}
+
 func InviteCommand(c bot.Command) error {
 	_, err := cmd.SendEmbed(c.E,
 		bot.User.Username+" invite", fmt.Sprintf("[Click to add me to your own server!](https://discord.com/oauth2/authorize?client_id=%v&permissions=%v&scope=bot)", bot.User.ID, bot.PermissionsHex),
@@ -166,15 +520,15 @@ func InviteCommand(c bot.Command) error {
 
 func PingCommand(c bot.Command) error {
 	if msg, err := cmd.SendEmbed(c.E,
-		"Ping!",
-		"Waiting for API response...",
+		c.T("Ping!"),
+		c.T("Waiting for API response..."),
 		bot.DefaultColor); err != nil {
 		return err
 	} else {
 		msgTime := c.E.Timestamp.Time().UnixMilli()
 		curTime := msg.Timestamp.Time().UnixMilli()
 
-		embed := cmd.MakeEmbed("Pong!", fmt.Sprintf("Latency is %sms", util.FormattedNum(curTime-msgTime)), bot.SuccessColor)
+		embed := cmd.MakeEmbed(c.T("Pong!"), c.T("Latency is %sms", util.FormattedNumIn(c.Printer(), curTime-msgTime)), bot.SuccessColor)
 		_, err = bot.Client.EditMessage(msg.ChannelID, msg.ID, "", embed)
 		return err
 	}
@@ -189,17 +543,117 @@ func PrefixCommand(c bot.Command) error {
 	arg, err := bot.SetPrefix(c.FnName, c.E.GuildID, arg)
 
 	embed := discord.Embed{
-		Description: "Set prefix to `" + arg + "`",
-		Footer:      &discord.EmbedFooter{Text: "At any time you can ping the bot with the word \"prefix\" to get the current prefix"},
+		Description: c.T("Set prefix to `%s`", arg),
+		Footer:      &discord.EmbedFooter{Text: c.T("At any time you can ping the bot with the word \"prefix\" to get the current prefix")},
 		Color:       bot.SuccessColor,
 	}
 	_, err = cmd.SendCustomEmbed(c.E.ChannelID, embed)
 	return err
 }
 
+// LocaleCommand will show the locale of the user and guild, or set one of them with a locale such as "de"
+func LocaleCommand(c bot.Command) error {
+	scope, _ := cmd.ParseStringArg(c.Args, 1, true)
+	locale, _ := cmd.ParseStringArg(c.Args, 2, true)
+	if locale == "reset" {
+		locale = ""
+	}
+
+	var err error
+	switch scope {
+	case "":
+		guildLocale := bot.Locale(c.E.GuildID, 0)
+		description := c.T("Messages are shown in `%s`", bot.Locale(c.E.GuildID, c.E.Author.ID))
+		if c.E.GuildID.IsValid() {
+			description += "\n" + c.T("This guild uses `%s`", guildLocale)
+		}
+		_, err = cmd.SendEmbedFooter(c.E, c.T("Locale"), description, c.T("Available locales are %s", bot.LocaleNames()), bot.DefaultColor)
+	case "user":
+		if len(c.Args) < 2 {
+			return bot.GenericSyntaxError(c.FnName, scope, "expected a locale or `reset`")
+		}
+
+		tag, setErr := bot.SetUserLocale(c.FnName, c.E.Author.ID, locale)
+		if setErr != nil {
+			return setErr
+		}
+		if len(locale) == 0 {
+			_, err = cmd.SendEmbed(c.E, c.T("Locale"), c.T("Reset your locale, messages will use the locale of the guild"), bot.SuccessColor)
+		} else {
+			_, err = cmd.SendEmbed(c.E, c.T("Locale"), c.T("Set your locale to `%s`", tag), bot.SuccessColor)
+		}
+	case "guild":
+		if !c.E.GuildID.IsValid() {
+			return bot.GenericError(c.FnName, "setting guild locale", "the guild locale can only be set in a guild")
+		}
+		if err := cmd.HasPermission(c, cmd.PermModerate); err != nil {
+			return err
+		}
+		if len(c.Args) < 2 {
+			return bot.GenericSyntaxError(c.FnName, scope, "expected a locale or `reset`")
+		}
+
+		tag, setErr := bot.SetGuildLocale(c.FnName, c.E.GuildID, locale)
+		if setErr != nil {
+			return setErr
+		}
+		if len(locale) == 0 {
+			_, err = cmd.SendEmbed(c.E, c.T("Locale"), c.T("Reset the locale of this guild to `%s`", tag), bot.SuccessColor)
+		} else {
+			_, err = cmd.SendEmbed(c.E, c.T("Locale"), c.T("Set the locale of this guild to `%s`", tag), bot.SuccessColor)
+		}
+	default:
+		return bot.GenericSyntaxError(c.FnName, scope, "expected `user` or `guild`")
+	}
+
+	return err
+}
+
+func DeleteRepliesCommand(c bot.Command) error {
+	if err := cmd.HasPermission(c, cmd.PermModerate); err != nil {
+		return err
+	}
+
+	keep := false
+	bot.GuildContext(c.E.GuildID, func(g *bot.GuildConfig) (*bot.GuildConfig, string) {
+		g.KeepReplies = !g.KeepReplies
+		keep = g.KeepReplies
+		return g, "DeleteRepliesCommand"
+	})
+
+	var err error
+	if keep {
+		_, err = cmd.SendEmbed(c.E, c.T("Delete Replies"), c.T("⛔ Replies will be kept when a command is deleted"), bot.ErrorColor)
+	} else {
+		_, err = cmd.SendEmbed(c.E, c.T("Delete Replies"), c.T("✅ Replies will be deleted when a command is deleted"), bot.SuccessColor)
+	}
+	return err
+}
+
+func SuggestionsCommand(c bot.Command) error {
+	if err := cmd.HasPermission(c, cmd.PermModerate); err != nil {
+		return err
+	}
+
+	disabled := false
+	bot.GuildContext(c.E.GuildID, func(g *bot.GuildConfig) (*bot.GuildConfig, string) {
+		g.NoSuggestions = !g.NoSuggestions
+		disabled = g.NoSuggestions
+		return g, "SuggestionsCommand"
+	})
+
+	var err error
+	if disabled {
+		_, err = cmd.SendEmbed(c.E, c.T("Suggestions"), c.T("⛔ Disabled command suggestions for this guild"), bot.ErrorColor)
+	} else {
+		_, err = cmd.SendEmbed(c.E, c.T("Suggestions"), c.T("✅ Enabled command suggestions for this guild"), bot.SuccessColor)
+	}
+	return err
+}
+
 func PrefixResponse(r bot.Response) {
 	if !r.E.GuildID.IsValid() {
-		_, _ = cmd.SendEmbed(r.E, "", "Commands in DMs don't use a prefix!\nUse `help` for a list of commands.", bot.DefaultColor)
+		_, _ = cmd.SendEmbed(r.E, "", r.T("Commands in DMs don't use a prefix!\nUse `help` for a list of commands."), bot.DefaultColor)
 		return
 	}
 
@@ -209,5 +663,5 @@ func PrefixResponse(r bot.Response) {
 		return g, "PrefixResponse"
 	})
 
-	_, _ = cmd.SendEmbed(r.E, "", fmt.Sprintf("The current prefix is `%s`\nUse `%shelp` for a list of commands.", prefix, prefix), bot.DefaultColor)
+	_, _ = cmd.SendEmbed(r.E, "", r.T("The current prefix is `%s`\nUse `%shelp` for a list of commands.", prefix, prefix), bot.DefaultColor)
 }
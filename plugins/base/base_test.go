@@ -0,0 +1,72 @@
+package main
+
+import (
+	"github.com/5HT2/taro-bot/bot"
+	"github.com/5HT2/taro-bot/cmd/cmdtest"
+	"github.com/5HT2/taro-bot/plugins"
+	"strings"
+	"testing"
+)
+
+func TestHelp(t *testing.T) {
+	h := cmdtest.New(t, InitPlugin(&plugins.PluginInit{ConfigDir: "base"}))
+
+	h.Send(bot.DefaultPrefix + "help")
+	if got := cmdtest.Text(h.LastReply()); !strings.Contains(got, "ping") || strings.Contains(got, "operatorconfig") {
+		t.Errorf("expected help to only show the commands that the user can use, got %q", got)
+	}
+
+	h.Operator(h.User)
+	h.Send(bot.DefaultPrefix + "help")
+	if got := cmdtest.Text(h.LastReply()); !strings.Contains(got, "operatorconfig") {
+		t.Errorf("expected help to show operator commands to operators, got %q", got)
+	}
+
+	h.Send(bot.DefaultPrefix + "help prefix")
+	if got := h.LastReply().Embeds[0].Title; got != "Taro Help: prefix" {
+		t.Errorf("expected help for the prefix command, got %q", got)
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	h := cmdtest.New(t, InitPlugin(&plugins.PluginInit{ConfigDir: "base"}))
+
+	h.SendAs(h.Owner, bot.DefaultPrefix+"prefix !")
+	if got := cmdtest.Text(h.LastReply()); got != "Set prefix to `!`" {
+		t.Fatalf("expected the prefix to be set, got %q", got)
+	}
+
+	h.Send("!help ping")
+	if got := h.LastReply().Embeds[0].Title; got != "Taro Help: ping" {
+		t.Errorf("expected the new prefix to work, got %q", got)
+	}
+}
+
+func TestLocale(t *testing.T) {
+	h := cmdtest.New(t, InitPlugin(&plugins.PluginInit{ConfigDir: "base"}))
+
+	h.Send(bot.DefaultPrefix + "locale guild de")
+	if got := h.LastReply().Embeds[0].Color; got != bot.WarnColor {
+		t.Errorf("expected users without the moderate permission to not set the guild locale, got %v", got)
+	}
+
+	h.SendAs(h.Owner, bot.DefaultPrefix+"locale guild de")
+	if got := h.LastReply().Embeds[0].Description; got != "Die Sprache dieses Servers wurde auf `de` gesetzt" {
+		t.Errorf("expected the guild locale to be set, got %q", got)
+	}
+
+	h.Send(bot.DefaultPrefix + "prefix")
+	if got := cmdtest.Text(h.LastReply()); !strings.Contains(got, "Fehler beim Ausführen von `prefix`") {
+		t.Errorf("expected errors to use the guild locale, got %q", got)
+	}
+
+	h.Send(bot.DefaultPrefix + "locale user en")
+	if got := h.LastReply().Embeds[0].Description; got != "Set your locale to `en`" {
+		t.Errorf("expected the user locale to be used over the guild locale, got %q", got)
+	}
+
+	h.Send(bot.DefaultPrefix + "locale user xx")
+	if got := cmdtest.Text(h.LastReply()); !strings.Contains(got, "unknown locale") {
+		t.Errorf("expected unknown locales to be rejected, got %q", got)
+	}
+}
@@ -5,23 +5,22 @@ import (
 	"github.com/5HT2/taro-bot/bot"
 	"github.com/5HT2/taro-bot/cmd"
 	"github.com/5HT2/taro-bot/plugins"
-	"github.com/5HT2/taro-bot/util"
 	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/diamondburned/arikawa/v3/gateway"
 	"reflect"
-	"sync"
 )
 
 var (
-	p     *plugins.Plugin
-	mutex sync.Mutex
+	p      *plugins.Plugin
+	store  = plugins.NewStore[config](nil)
+	guilds = plugins.NewGuildStore(store, func(c *config) *plugins.GuildMap[bool] { return &c.EnabledGuilds }, func() bool { return true })
 
 	enabledFooter   = discord.EmbedFooter{Text: "Messages will be DMed to you when you react with a 🔖."}
 	escapedBookmark = "%F0%9F%94%96"
 )
 
 type config struct {
-	EnabledGuilds map[string]bool `json:"enabled_guilds,omitempty"` // [guild id]bool
+	EnabledGuilds plugins.GuildMap[bool] `json:"enabled_guilds,omitempty"` // [guild id]bool, enabled by default
 }
 
 func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
@@ -38,7 +37,7 @@ func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
 			Description: "Enable or disable bookmarking messages",
 			GuildOnly:   true,
 		}},
-		ConfigType: reflect.TypeOf(config{}),
+		Config: store,
 		Handlers: []bot.HandlerInfo{{
 			Fn:     BookmarkReactionHandler,
 			FnName: "BookmarkReactionHandler",
@@ -46,24 +45,13 @@ func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
 		}},
 	}
 	p.ConfigDir = i.ConfigDir
-	p.Config = p.LoadConfig()
+	p.LoadConfig()
 	return p
 }
 
 func BookmarkConfigCommand(c bot.Command) error {
-	mutex.Lock()
-	defer mutex.Unlock()
-
-	enabled := true // enabled by default
 	id := c.E.GuildID.String()
-
-	if p.Config != nil {
-		enabledGuild, ok := p.Config.(config).EnabledGuilds[id]
-
-		if ok {
-			enabled = enabledGuild
-		}
-	}
+	enabled := guilds.Get(id)
 
 	var err error = nil
 	arg, _ := cmd.ParseStringArg(c.Args, 1, true)
@@ -88,22 +76,14 @@ func BookmarkConfigCommand(c bot.Command) error {
 		}
 	}
 
-	if p.Config == nil {
-		guilds := make(map[string]bool)
-		guilds[id] = enabled
-		cfg := config{EnabledGuilds: guilds}
-		p.Config = cfg
-	} else {
-		p.Config.(config).EnabledGuilds[id] = enabled
-	}
+	guilds.Update(id, func(g *bool) {
+		*g = enabled
+	})
 
 	return err
 }
 
 func BookmarkReactionHandler(i interface{}) {
-	mutex.Lock()
-	defer mutex.Unlock()
-	defer util.LogPanic()
 	e := i.(*gateway.MessageReactionAddEvent)
 
 	// Bot reacted
@@ -116,21 +96,8 @@ func BookmarkReactionHandler(i interface{}) {
 		return
 	}
 
-	sendBookmark := false
-
-	if p.Config == nil {
-		// Enabled by default
-		sendBookmark = true
-	} else {
-		enabled, ok := p.Config.(config).EnabledGuilds[e.GuildID.String()]
-
-		// If not in the config (enabled by default) or explicitly enabled
-		if !ok || enabled {
-			sendBookmark = true
-		}
-	}
-
-	if sendBookmark {
+	// If not in the config (enabled by default) or explicitly enabled
+	if guilds.Get(e.GuildID.String()) {
 		msg, err := bot.Client.Message(e.ChannelID, e.MessageID)
 		if err != nil {
 			return
@@ -0,0 +1,539 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Plugin config fields can be tagged with `taro:"operator"` to only allow bot operators to see and edit them, or
+// `taro:"hidden"` to not allow anyone to see or edit them with GetConfig, SetConfig or ResetConfig.
+// Fields that aren't inside a GuildMap are global to the bot, so they can only be accessed by bot operators.
+const (
+	tagOperator = "operator"
+	tagHidden   = "hidden"
+)
+
+var (
+	guildMapType  = reflect.TypeOf((*interface{ guildMap() })(nil)).Elem()
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// ConfigAccess is who is accessing a plugin config with GetConfig, SetConfig or ResetConfig
+type ConfigAccess struct {
+	Guild    string // Guild is the guild id that GuildMap fields are read from and written to, empty outside a guild
+	Operator bool   // Operator is if a bot operator is accessing the config
+}
+
+// configWalk is the state of walking a path inside a plugin config
+type configWalk struct {
+	store    ConfigStore
+	access   ConfigAccess
+	write    bool // write is if values read from maps should be written back to them
+	reset    bool // reset is if the last map key in the path should be deleted, instead of calling fn
+	guild    bool // guild is if the path went through a GuildMap
+	operator bool // operator is if the path went through a field tagged with tagOperator
+	hidden   bool // hidden is if the path went through a field tagged with tagHidden
+}
+
+// allowed will return an error if the walked path can't be accessed
+func (w *configWalk) allowed(path string) error {
+	switch {
+	case w.hidden:
+		return fmt.Errorf("`%s` cannot be accessed", path)
+	case (w.operator || !w.guild) && !w.access.Operator:
+		return fmt.Errorf("`%s` can only be accessed by bot operators", path)
+	default:
+		return nil
+	}
+}
+
+// Configs will return the names of the loaded plugins that have a config
+func Configs() []string {
+	names := make([]string, 0)
+	for _, p := range loadedPlugins() {
+		if p.Config != nil {
+			names = append(names, p.ConfigDir)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// GetConfig will return the json of the value at path in a plugin's config.
+// An empty path will return every field of the config that can be accessed.
+func GetConfig(name, path string, a ConfigAccess) (string, error) {
+	store, err := findConfig(name)
+	if err != nil {
+		return "", err
+	}
+
+	w := &configWalk{store: store, access: a}
+	var value any
+	err = store.access(false, func(root reflect.Value) error {
+		return walkConfig(root, splitPath(path), w, func(v reflect.Value) error {
+			if len(path) > 0 {
+				if err := w.allowed(path); err != nil {
+					return err
+				}
+			}
+
+			value = w.render(v, w.guild)
+			return nil
+		})
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return marshalConfig(value)
+}
+
+// SetConfig will parse value as the type of the field at path, and set it in a plugin's config.
+// Strings don't need to be quoted, and numbers can be given as a channel, role or user mention.
+// Fields inside value that a couldn't see, such as hidden ones, keep their value.
+func SetConfig(name, path, value string, a ConfigAccess) (string, error) {
+	store, err := findConfig(name)
+	if err != nil {
+		return "", err
+	}
+
+	if len(path) == 0 {
+		return "", fmt.Errorf("a path to set is required")
+	}
+
+	// Walk the path without writing first, so that we don't create map entries for a value that won't be set
+	var parsed reflect.Value
+	if err := store.access(false, func(root reflect.Value) error {
+		w := &configWalk{store: store, access: a}
+		return walkConfig(root, splitPath(path), w, func(v reflect.Value) error {
+			if err := w.allowed(path); err != nil {
+				return err
+			}
+
+			if containsGuildMap(v.Type()) {
+				return fmt.Errorf("`%s` cannot be set directly, set one of its keys instead", path)
+			}
+
+			parsed, err = parseConfigValue(v.Type(), value)
+			return err
+		})
+	}); err != nil {
+		return "", err
+	}
+
+	w := &configWalk{store: store, access: a, write: true}
+	if err := store.access(true, func(root reflect.Value) error {
+		return walkConfig(root, splitPath(path), w, func(v reflect.Value) error {
+			w.keepHidden(parsed, v, w.guild)
+			v.Set(parsed)
+			return nil
+		})
+	}); err != nil {
+		return "", err
+	}
+
+	return marshalConfig(w.render(parsed, w.guild))
+}
+
+// UpdateConfig will call fn with the json of the value at path in a plugin's config, like GetConfig, and set the value
+// to the json that fn returns. The config is locked while fn runs, so that it can't change in between, and fn shouldn't
+// do anything slow, such as calling Discord. Fields that fn couldn't see, such as hidden ones, keep their value.
+func UpdateConfig(name, path string, a ConfigAccess, fn func(value string) (string, error)) error {
+	store, err := findConfig(name)
+	if err != nil {
+		return err
+	}
+
+	if len(path) == 0 {
+		return fmt.Errorf("a path to update is required")
+	}
+
+	w := &configWalk{store: store, access: a, write: true}
+	return store.access(true, func(root reflect.Value) error {
+		return walkConfig(root, splitPath(path), w, func(v reflect.Value) error {
+			if err := w.allowed(path); err != nil {
+				return err
+			}
+
+			if containsGuildMap(v.Type()) {
+				return fmt.Errorf("`%s` cannot be set directly, set one of its keys instead", path)
+			}
+
+			current, err := marshalConfig(w.render(v, w.guild))
+			if err != nil {
+				return err
+			}
+
+			value, err := fn(current)
+			if err != nil {
+				return err
+			}
+
+			updated := reflect.New(v.Type())
+			if err := json.Unmarshal([]byte(value), updated.Interface()); err != nil {
+				return fmt.Errorf("`%s` is not a valid %s", path, typeName(v.Type()))
+			}
+
+			w.keepHidden(updated.Elem(), v, w.guild)
+			v.Set(updated.Elem())
+			return nil
+		})
+	})
+}
+
+// ResetConfig will set the value at path in a plugin's config back to its default.
+// Map keys, including the current guild in a GuildMap, are removed instead.
+func ResetConfig(name, path string, a ConfigAccess) error {
+	store, err := findConfig(name)
+	if err != nil {
+		return err
+	}
+
+	if len(path) == 0 {
+		return fmt.Errorf("a path to reset is required")
+	}
+
+	// Find the default value first, this is the zero value if the default config doesn't have the path.
+	var def reflect.Value
+	if err := walkConfig(store.defaultValue(), splitPath(path), &configWalk{store: store, access: a}, func(v reflect.Value) error {
+		def = v
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	check := &configWalk{store: store, access: a}
+	if err := store.access(false, func(root reflect.Value) error {
+		return walkConfig(root, splitPath(path), check, func(v reflect.Value) error {
+			return check.allowed(path)
+		})
+	}); err != nil {
+		return err
+	}
+
+	return store.access(true, func(root reflect.Value) error {
+		return walkConfig(root, splitPath(path), &configWalk{store: store, access: a, write: true, reset: true}, func(v reflect.Value) error {
+			v.Set(def)
+			return nil
+		})
+	})
+}
+
+func findConfig(name string) (ConfigStore, error) {
+	p := Find(name)
+	if p == nil {
+		return nil, fmt.Errorf("no loaded plugin named `%s`", name)
+	}
+
+	if p.Config == nil {
+		return nil, fmt.Errorf("`%s` does not have a config", name)
+	}
+
+	return p.Config, nil
+}
+
+func splitPath(path string) []string {
+	if len(path) == 0 {
+		return []string{}
+	}
+
+	return strings.Split(path, ".")
+}
+
+// walkConfig will call fn with the addressable value at path inside v.
+// GuildMaps are indexed with the ConfigAccess.Guild automatically, so the guild id is not part of the path.
+// Missing map keys are walked as their default value, and are only written back to the map if w.write is set.
+func walkConfig(v reflect.Value, path []string, w *configWalk, fn func(v reflect.Value) error) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			if !w.write {
+				return walkConfig(reflect.New(v.Type().Elem()).Elem(), path, w, fn)
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return walkConfig(v.Elem(), path, w, fn)
+	}
+
+	if v.Type().Implements(guildMapType) {
+		if len(w.access.Guild) == 0 {
+			return fmt.Errorf("this can only be accessed in a guild")
+		}
+
+		w.guild = true
+		return walkMapKey(v, w.access.Guild, path, w, fn)
+	}
+
+	if len(path) == 0 {
+		return fn(v)
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		f, ok := jsonField(v.Type(), path[0])
+		if !ok {
+			return fmt.Errorf("unknown key `%s`", path[0])
+		}
+
+		switch f.Tag.Get("taro") {
+		case tagOperator:
+			w.operator = true
+		case tagHidden:
+			w.hidden = true
+		}
+
+		return walkConfig(v.FieldByIndex(f.Index), path[1:], w, fn)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("`%s` cannot be accessed", path[0])
+		}
+
+		return walkMapKey(v, path[0], path[1:], w, fn)
+	default:
+		return fmt.Errorf("unknown key `%s`", path[0])
+	}
+}
+
+func walkMapKey(m reflect.Value, key string, path []string, w *configWalk, fn func(v reflect.Value) error) error {
+	k := reflect.ValueOf(key).Convert(m.Type().Key())
+
+	if w.reset && len(path) == 0 {
+		if !m.IsNil() {
+			m.SetMapIndex(k, reflect.Value{})
+		}
+		return nil
+	}
+
+	// Map elements aren't addressable, so they are copied, changed and set again
+	elem := w.mapIndex(m, k)
+
+	if err := walkConfig(elem, path, w, fn); err != nil {
+		return err
+	}
+
+	if w.write {
+		if m.IsNil() {
+			m.Set(reflect.MakeMap(m.Type()))
+		}
+		m.SetMapIndex(k, elem)
+	}
+	return nil
+}
+
+// mapIndex will return an addressable copy of m[k], or the default value if m doesn't have k
+func (w *configWalk) mapIndex(m, k reflect.Value) reflect.Value {
+	elem := reflect.New(m.Type().Elem()).Elem()
+	if e := m.MapIndex(k); e.IsValid() {
+		elem.Set(e)
+	} else if def, ok := w.store.guildDefault(m.Type()); ok {
+		elem.Set(def)
+	}
+	return elem
+}
+
+// jsonField will return the struct field with a json name of key
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if name, ok := jsonName(f); ok && name == key {
+			return f, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// jsonName will return the name that encoding/json uses for f, and false if it is not marshalled
+func jsonName(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+
+	if name, _, _ := strings.Cut(tag, ","); len(name) > 0 {
+		return name, true
+	}
+	return f.Name, true
+}
+
+// containsGuildMap will return if t has a GuildMap in it, which is used to hide global fields from non-operators
+func containsGuildMap(t reflect.Type) bool {
+	if t.Implements(guildMapType) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return containsGuildMap(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if _, ok := jsonName(t.Field(i)); ok && containsGuildMap(t.Field(i).Type) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// render will convert v into something that can be marshalled, without the fields that w.access cannot access
+func (w *configWalk) render(v reflect.Value, guild bool) any {
+	a := w.access
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		return w.render(v.Elem(), guild)
+	}
+
+	if v.Type().Implements(guildMapType) {
+		return w.render(w.mapIndex(v, reflect.ValueOf(a.Guild).Convert(v.Type().Key())), true)
+	}
+
+	if v.Type().Implements(marshalerType) || reflect.PtrTo(v.Type()).Implements(marshalerType) {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		fields := make(map[string]any)
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			name, ok := jsonName(f)
+			if !ok {
+				continue
+			}
+
+			if !w.visible(f, guild) {
+				continue
+			}
+
+			fields[name] = w.render(v.Field(i), guild)
+		}
+		return fields
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return v.Interface()
+		}
+
+		elems := make(map[string]any)
+		iter := v.MapRange()
+		for iter.Next() {
+			elems[iter.Key().String()] = w.render(iter.Value(), guild)
+		}
+		return elems
+	default:
+		return v.Interface()
+	}
+}
+
+// visible will return if render includes the struct field f, which is inside a GuildMap if guild is set
+func (w *configWalk) visible(f reflect.StructField, guild bool) bool {
+	tag := f.Tag.Get("taro")
+	if tag == tagHidden {
+		return false
+	}
+
+	return w.access.Operator || (tag != tagOperator && (guild || containsGuildMap(f.Type)))
+}
+
+// keepHidden will copy the struct fields that render leaves out from old into v, so that setting v doesn't change them
+func (w *configWalk) keepHidden(v, old reflect.Value, guild bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() && !old.IsNil() {
+			w.keepHidden(v.Elem(), old.Elem(), guild)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !f.IsExported() {
+				continue
+			}
+
+			if _, ok := jsonName(f); !ok || !w.visible(f, guild) {
+				v.Field(i).Set(old.Field(i))
+			} else {
+				w.keepHidden(v.Field(i), old.Field(i), guild)
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() || old.IsNil() {
+			return
+		}
+
+		// Map elements aren't addressable, so they are copied, changed and set again
+		iter := v.MapRange()
+		for iter.Next() {
+			if o := old.MapIndex(iter.Key()); o.IsValid() {
+				elem := reflect.New(v.Type().Elem()).Elem()
+				elem.Set(iter.Value())
+				w.keepHidden(elem, o, guild)
+				v.SetMapIndex(iter.Key(), elem)
+			}
+		}
+	}
+}
+
+func marshalConfig(value any) (string, error) {
+	bytes, err := json.MarshalIndent(value, "", "  ")
+	return string(bytes), err
+}
+
+// parseConfigValue will parse value as json into a new t.
+// Strings can be unquoted, and numbers can be mentions, such as <#123>, <@&123> or <@!123>.
+func parseConfigValue(t reflect.Type, value string) (reflect.Value, error) {
+	v := reflect.New(t)
+	if err := json.Unmarshal([]byte(value), v.Interface()); err == nil {
+		return v.Elem(), nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		v.Elem().SetString(value)
+		return v.Elem(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, err := strconv.ParseInt(strings.Trim(value, "<#@&!>"), 10, 64); err == nil && !v.Elem().OverflowInt(i) {
+			v.Elem().SetInt(i)
+			return v.Elem(), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if i, err := strconv.ParseUint(strings.Trim(value, "<#@&!>"), 10, 64); err == nil && !v.Elem().OverflowUint(i) {
+			v.Elem().SetUint(i)
+			return v.Elem(), nil
+		}
+	}
+
+	return reflect.Value{}, fmt.Errorf("`%s` is not a valid %s", value, typeName(t))
+}
+
+// typeName will return a user-friendly name for t
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeName(t.Elem())
+	case reflect.Bool:
+		return "bool"
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "whole number"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "json array of " + typeName(t.Elem())
+	default:
+		return "json " + t.String()
+	}
+}
@@ -0,0 +1,162 @@
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testConfig struct {
+	Token  string              `json:"token"` // Token is global, so only operators can access it
+	Guilds GuildMap[testGuild] `json:"guilds"`
+}
+
+type testGuild struct {
+	Enabled  bool             `json:"enabled"`
+	Channels []int64          `json:"channels"`
+	Limit    int64            `json:"limit" taro:"operator"`
+	Secret   string           `json:"secret" taro:"hidden"`
+	Roles    map[string]int64 `json:"roles,omitempty"`
+}
+
+// newTestConfig will register a plugin with a testConfig, which has a guild "1" and a guild "2"
+func newTestConfig(t *testing.T) *Store[testConfig] {
+	store := NewStore[testConfig](nil)
+	NewGuildStore(store, func(c *testConfig) *GuildMap[testGuild] { return &c.Guilds }, func() testGuild {
+		return testGuild{Enabled: true, Channels: []int64{1}}
+	})
+	store.Update(func(c *testConfig) {
+		c.Token = "token"
+		c.Guilds = GuildMap[testGuild]{
+			"1": {Enabled: true, Channels: []int64{10}, Limit: 5, Secret: "one"},
+			"2": {Enabled: false, Channels: []int64{20}, Secret: "two"},
+		}
+	})
+	store.dirty = false
+
+	RegisterPlugins(&Plugin{Name: "Test", ConfigDir: "test", Config: store})
+	t.Cleanup(func() { RegisterPlugins() })
+	return store
+}
+
+func TestConfigAccess(t *testing.T) {
+	newTestConfig(t)
+	member := ConfigAccess{Guild: "1"}
+	operator := ConfigAccess{Guild: "1", Operator: true}
+
+	for _, path := range []string{"token", "guilds.secret", "guilds.limit", "guilds.2.enabled", "guilds.missing"} {
+		if res, err := GetConfig("test", path, member); err == nil {
+			t.Errorf("expected %s to not be readable, got %s", path, res)
+		}
+		if _, err := SetConfig("test", path, "1", member); err == nil {
+			t.Errorf("expected %s to not be writable", path)
+		}
+		if err := ResetConfig("test", path, member); err == nil {
+			t.Errorf("expected %s to not be resettable", path)
+		}
+	}
+
+	res, err := GetConfig("test", "", member)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hidden := range []string{"token", "secret", "limit", "one", "two", "20"} {
+		if strings.Contains(res, hidden) {
+			t.Errorf("expected %s to not be shown, got %s", hidden, res)
+		}
+	}
+	if !strings.Contains(res, "10") {
+		t.Errorf("expected the guild's channels to be shown, got %s", res)
+	}
+
+	if res, err := GetConfig("test", "guilds.limit", operator); err != nil || res != "5" {
+		t.Errorf("expected an operator to read the limit, got %s, %v", res, err)
+	}
+	if res, err := GetConfig("test", "token", operator); err != nil || res != `"token"` {
+		t.Errorf("expected an operator to read the token, got %s, %v", res, err)
+	}
+	if _, err := GetConfig("test", "guilds.secret", operator); err == nil {
+		t.Errorf("expected hidden fields to not be readable by operators")
+	}
+	if _, err := GetConfig("test", "guilds", ConfigAccess{Operator: true}); err == nil {
+		t.Errorf("expected guild fields to not be readable outside a guild")
+	}
+}
+
+func TestSetConfigKeepsHidden(t *testing.T) {
+	store := newTestConfig(t)
+	member := ConfigAccess{Guild: "1"}
+
+	if _, err := SetConfig("test", "guilds", `{"enabled": false, "channels": [11], "limit": 100, "secret": "changed"}`, member); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateConfig("test", "guilds", member, func(value string) (string, error) {
+		return strings.Replace(value, "11", "12", 1), nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	g := store.Get().Guilds["1"]
+	if g.Enabled || len(g.Channels) != 1 || g.Channels[0] != 12 {
+		t.Errorf("expected the visible fields to be set, got %+v", g)
+	}
+	if g.Secret != "one" || g.Limit != 5 {
+		t.Errorf("expected the hidden and operator fields to be kept, got %+v", g)
+	}
+	if other := store.Get().Guilds["2"]; other.Secret != "two" || len(other.Channels) != 1 || other.Channels[0] != 20 {
+		t.Errorf("expected the other guild to be unchanged, got %+v", other)
+	}
+}
+
+func TestResetConfig(t *testing.T) {
+	store := newTestConfig(t)
+	member := ConfigAccess{Guild: "1"}
+
+	if err := ResetConfig("test", "guilds.channels", member); err != nil {
+		t.Fatal(err)
+	}
+	if g := store.Get().Guilds["1"]; len(g.Channels) != 1 || g.Channels[0] != 1 || g.Secret != "one" {
+		t.Errorf("expected the channels to be reset to the guild default, got %+v", g)
+	}
+
+	if err := ResetConfig("test", "guilds", member); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Get().Guilds["1"]; ok {
+		t.Errorf("expected the guild to be removed")
+	}
+	if res, err := GetConfig("test", "guilds.enabled", member); err != nil || res != "true" {
+		t.Errorf("expected the removed guild to read as the guild default, got %s, %v", res, err)
+	}
+	if _, ok := store.Get().Guilds["2"]; !ok {
+		t.Errorf("expected the other guild to be kept")
+	}
+}
+
+func TestFailedEditsDontChangeConfig(t *testing.T) {
+	store := newTestConfig(t)
+	member := ConfigAccess{Guild: "3"}
+	before, _ := json.Marshal(store.Get())
+
+	if _, err := SetConfig("test", "guilds.channels", "not a list", member); err == nil {
+		t.Errorf("expected an invalid value to be rejected")
+	}
+	if err := UpdateConfig("test", "guilds", member, func(value string) (string, error) {
+		return `{"channels": "not a list"}`, nil
+	}); err == nil {
+		t.Errorf("expected an invalid update to be rejected")
+	}
+	if err := UpdateConfig("test", "guilds.roles", member, func(value string) (string, error) {
+		return "", errors.New("failed")
+	}); err == nil {
+		t.Errorf("expected the error from the update to be returned")
+	}
+
+	if after, _ := json.Marshal(store.Get()); string(after) != string(before) {
+		t.Errorf("expected the config to be unchanged, got %s, was %s", after, before)
+	}
+	if store.dirty {
+		t.Errorf("expected the config to not be marked as changed")
+	}
+}
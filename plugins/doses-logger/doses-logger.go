@@ -8,16 +8,21 @@ import (
 	"github.com/5HT2/taro-bot/util"
 	"github.com/5HT2C/http-bash-requests/httpBashRequests"
 	"net/http"
-	"reflect"
 	"strings"
 )
 
 var (
-	p *plugins.Plugin
+	p     *plugins.Plugin
+	store = plugins.NewStore(defaultConfig)
 )
 
 type config struct {
-	FohToken string `json:"foh_token"`
+	FohToken string `json:"foh_token" taro:"hidden"`
+	FohUrl   string `json:"foh_url" taro:"operator"` // FohUrl is the fs-over-http server that the doses are stored in
+}
+
+func defaultConfig() config {
+	return config{FohUrl: "http://localhost:6010"}
 }
 
 func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
@@ -31,20 +36,21 @@ func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
 			Name:        "dose",
 			Description: "Manage medication and substance doses",
 		}},
-		ConfigType: reflect.TypeOf(config{}),
+		Config: store,
 	}
 	p.ConfigDir = i.ConfigDir
-	p.Config = p.LoadConfig()
+	p.LoadConfig()
 	return p
 }
 
 func DoseCommand(c bot.Command) error {
-	if p.Config == nil || p.Config.(config).FohToken == "" {
-		return bot.GenericError(c.FnName, "running command", "`foh_token` not set")
+	token, fohUrl := store.Get().FohToken, store.Get().FohUrl
+	if token == "" {
+		return bot.InternalError(c.FnName, "running command", "`foh_token` not set", nil)
 	}
 
 	// Make URL of public file
-	file := fmt.Sprintf("http://localhost:6010/media/doses-%v.json", c.E.Author.ID)
+	file := fmt.Sprintf("%s/media/doses-%v.json", fohUrl, c.E.Author.ID)
 
 	// Get args to pass to command
 	argsTmp, _ := cmd.ParseStringSliceArg(c.Args, 1, -1)
@@ -69,7 +75,7 @@ func DoseCommand(c bot.Command) error {
 			continue
 		case "-frog":
 			frog = true
-			file = "http://localhost:6010/media/doses.json"
+			file = fohUrl + "/media/doses.json"
 			continue
 		default:
 			args = append(args, arg)
@@ -87,7 +93,7 @@ func DoseCommand(c bot.Command) error {
 		return bot.GenericError(c.FnName, "parsing args", "`-frog` cannot be used with `-add` or `-rm`!")
 	}
 
-	parsedArgs := fmt.Sprintf(`%s%s-token=%s -url=%s`, pArgs, sep, p.Config.(config).FohToken, file)
+	parsedArgs := fmt.Sprintf(`%s%s-token=%s -url=%s`, pArgs, sep, token, file)
 	// end arg parsing
 
 	// get dose db for user
@@ -99,10 +105,10 @@ func DoseCommand(c bot.Command) error {
 
 	// if not found, do we need to make a json file for the user?
 	if res.StatusCode == 404 {
-		file = fmt.Sprintf("http://localhost:6010/public/media/doses-%v.json", c.E.Author.ID)
+		file = fmt.Sprintf("%s/public/media/doses-%v.json", fohUrl, c.E.Author.ID)
 
 		// TODO: Use http stdlib
-		if res, err := httpBashRequests.Run(fmt.Sprintf("curl -X POST -H \"Auth: %s\" %s -F \"content=[]\"", p.Config.(config).FohToken, file)); err != nil {
+		if res, err := httpBashRequests.Run(fmt.Sprintf("curl -X POST -H \"Auth: %s\" %s -F \"content=[]\"", token, file)); err != nil {
 			return err
 		} else if _, err := cmd.SendEmbed(c.E, "", fmt.Sprintf("```\n%s\n```", util.TailLinesLimit(string(res), 2040)), bot.DefaultColor); err != nil {
 			return err
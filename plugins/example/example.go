@@ -5,15 +5,20 @@ import (
 	"github.com/5HT2/taro-bot/bot"
 	"github.com/5HT2/taro-bot/cmd"
 	"github.com/5HT2/taro-bot/plugins"
-	"github.com/5HT2/taro-bot/util"
 	"github.com/diamondburned/arikawa/v3/gateway"
 	"github.com/go-co-op/gocron"
-	"log"
 	"math/rand"
 	"reflect"
 )
 
-var p *plugins.Plugin
+var (
+	p *plugins.Plugin
+	// store holds the plugin's config. The function given to NewStore returns the default config, which is used until
+	// a config has been saved, and to fill in any fields that are missing from it.
+	store = plugins.NewStore(func() config {
+		return config{Fn: "example"}
+	})
+)
 
 type config struct {
 	Fn string `json:"fn"`
@@ -39,9 +44,9 @@ func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
 			Aliases:     []string{"err", "e"},
 			Description: "This command will only return errors",
 		}},
-		// This is used to ensure type safety when loading the Config
-		// If you forget to declare this and use p.LoadConfig(), you will get a safe panic when loading
-		ConfigType: reflect.TypeOf(config{}),
+		// Config is loaded by p.LoadConfig(), and saved automatically after it has been changed with store.Update.
+		// Use store.Get() to read it, there is no need to check for nil.
+		Config: store,
 		// Responses are called based on regex matching the message.
 		// DISCORD_BOT_ID is replaced in the regex matching, and this response will be called by pinging the bot with the word test or help.
 		// MatchMin means that a minimum of two of the Regexes need to match.
@@ -74,20 +79,19 @@ func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
 	}
 	// This is required to set the config directory initially.
 	p.ConfigDir = i.ConfigDir
-	// When loading a config, you should cast not cast it, as it will be nil by default.
-	// Instead, check if it is nil before doing .(config) in order to use it.
-	p.Config = p.LoadConfig()
+	// This loads the saved config into the store, if there is one.
+	p.LoadConfig()
 	return p
 }
 
 // Startup will run after all plugins have been loaded and before schedulers are started
 func Startup() {
-	log.Println("hello from the example plugin!")
+	p.Log().Info("hello from the example plugin!")
 }
 
 // Shutdown will run when the bot is killed / stopped, and says goodbye to the console.
 func Shutdown() {
-	log.Println("goodbye from the example plugin!")
+	p.Log().Info("goodbye from the example plugin!")
 }
 
 // ExampleCommand (.example) is a basic example of returning just a message with a command.
@@ -129,12 +133,12 @@ func TestResponse(r bot.Response) {
 
 // EveryMinuteJob will print something to the console every minute.
 func EveryMinuteJob() {
-	log.Printf("This was called from the example plugin, and is called every minute\n")
+	p.Log().Debug("this was called from the example plugin, and is called every minute")
 }
 
 // ReactionHandler will send a message whenever someone adds a reaction to a message, as well as info about the reaction.
 func ReactionHandler(i interface{}) {
-	defer util.LogPanic()                     // handle panics and log them. panics are safe even without this, but aren't logged.
+	// Panics are recovered by the plugin loader, logged, and sent to the operator channel. A plugin that panics too often is disabled.
 	e := i.(*gateway.MessageReactionAddEvent) // this is necessary to access the event. FnType ensures that this is safe.
 
 	_, _ = cmd.SendCustomMessage(e.ChannelID, fmt.Sprintf("This is in response to a reaction added by <@%v>, the emoji name is `%s`", e.UserID, e.Emoji.Name))
@@ -9,19 +9,18 @@ import (
 	"github.com/5HT2/taro-bot/util"
 	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/diamondburned/arikawa/v3/gateway"
-	"log"
 	"reflect"
 	"strings"
-	"sync"
 )
 
 var (
-	p     *plugins.Plugin
-	mutex sync.Mutex
+	p      *plugins.Plugin
+	store  = plugins.NewStore[config](nil)
+	guilds = plugins.NewGuildStore(store, func(c *config) *plugins.GuildMap[MsgConfig] { return &c.Guilds }, nil)
 )
 
 type config struct {
-	Guilds map[string]MsgConfig `json:"guilds,omitempty"` // [guild id]MsgConfig
+	Guilds plugins.GuildMap[MsgConfig] `json:"guilds,omitempty"` // [guild id]MsgConfig
 }
 
 type MsgConfig struct {
@@ -35,7 +34,7 @@ type Message struct {
 	Content         string         `json:"content,omitempty"`
 	Embed           *discord.Embed `json:"embed,omitempty"`
 	CollapseMessage bool           `json:"collapse_message,omitempty"`
-	LastMessage     int64          `json:"last_message,omitempty"`
+	LastMessage     int64          `json:"last_message,omitempty" taro:"hidden"`
 }
 
 func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
@@ -50,8 +49,9 @@ func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
 			Aliases:     []string{"ljcfg"},
 			Description: "Edit leave & join msg config",
 			GuildOnly:   true,
+			Permission:  "moderate",
 		}},
-		ConfigType: reflect.TypeOf(config{}),
+		Config: store,
 		Handlers: []bot.HandlerInfo{{
 			Fn:     LeaveJoinAddHandler,
 			FnName: "LeaveJoinAddHandler",
@@ -63,60 +63,48 @@ func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
 		}},
 	}
 	p.ConfigDir = i.ConfigDir
-	p.Config = p.LoadConfig()
+	p.LoadConfig()
 	return p
 }
 
 func LeaveJoinAddHandler(i interface{}) {
-	mutex.Lock()
-	defer mutex.Unlock()
-	defer util.LogPanic()
 	e := i.(*gateway.GuildMemberAddEvent)
 
-	if p.Config == nil {
-		return
-	}
-
-	if cfg, ok := p.Config.(config).Guilds[e.GuildID.String()]; ok && cfg.JoinMessage.Enabled {
+	if cfg := guilds.Get(e.GuildID.String()); cfg.JoinMessage.Enabled {
 		message := strings.ReplaceAll(cfg.JoinMessage.Content, "USER_ID", e.User.ID.String())
 		message = strings.ReplaceAll(message, "USER_TAG", util.FormattedUserTag(e.User))
 
 		if msg, err := cmd.SendMessageEmbedSafe(discord.ChannelID(cfg.JoinMessage.Channel), message, cfg.JoinMessage.Embed); err != nil {
-			log.Printf("error sending join message: %v\n", err)
+			p.Log().Error("error sending join message", "guild", e.GuildID, "err", err)
 		} else {
 			if cfg.JoinMessage.CollapseMessage && cfg.JoinMessage.LastMessage != 0 {
 				_ = bot.Client.DeleteMessage(discord.ChannelID(cfg.JoinMessage.Channel), discord.MessageID(cfg.JoinMessage.LastMessage), "join message collapsed")
 			}
 
-			cfg.JoinMessage.LastMessage = int64(msg.ID)
-			p.Config.(config).Guilds[e.GuildID.String()] = cfg
+			guilds.Update(e.GuildID.String(), func(g *MsgConfig) {
+				g.JoinMessage.LastMessage = int64(msg.ID)
+			})
 		}
 	}
 }
 
 func LeaveJoinRemoveHandler(i interface{}) {
-	mutex.Lock()
-	defer mutex.Unlock()
-	defer util.LogPanic()
 	e := i.(*gateway.GuildMemberRemoveEvent)
 
-	if p.Config == nil {
-		return
-	}
-
-	if cfg, ok := p.Config.(config).Guilds[e.GuildID.String()]; ok && cfg.LeaveMessage.Enabled {
+	if cfg := guilds.Get(e.GuildID.String()); cfg.LeaveMessage.Enabled {
 		message := strings.ReplaceAll(cfg.LeaveMessage.Content, "USER_ID", e.User.ID.String())
 		message = strings.ReplaceAll(message, "USER_TAG", util.FormattedUserTag(e.User))
 
 		if msg, err := cmd.SendMessageEmbedSafe(discord.ChannelID(cfg.LeaveMessage.Channel), message, cfg.LeaveMessage.Embed); err != nil {
-			log.Printf("error sending leave message: %v\n", err)
+			p.Log().Error("error sending leave message", "guild", e.GuildID, "err", err)
 		} else {
 			if cfg.LeaveMessage.CollapseMessage && cfg.LeaveMessage.LastMessage != 0 {
 				_ = bot.Client.DeleteMessage(discord.ChannelID(cfg.LeaveMessage.Channel), discord.MessageID(cfg.LeaveMessage.LastMessage), "leave message collapsed")
 			}
 
-			cfg.LeaveMessage.LastMessage = int64(msg.ID)
-			p.Config.(config).Guilds[e.GuildID.String()] = cfg
+			guilds.Update(e.GuildID.String(), func(g *MsgConfig) {
+				g.LeaveMessage.LastMessage = int64(msg.ID)
+			})
 		}
 	}
 }
@@ -126,19 +114,6 @@ func LeaveJoinMsgCfgCommand(c bot.Command) error {
 		return err
 	}
 
-	mutex.Lock()
-	defer mutex.Unlock()
-
-	msgConfig := MsgConfig{JoinMessage: Message{}, LeaveMessage: Message{}}
-
-	if p.Config != nil {
-		if guildMsgConfig, ok := p.Config.(config).Guilds[c.E.GuildID.String()]; ok {
-			msgConfig = guildMsgConfig
-		}
-	}
-
-	var err error = nil
-
 	arg, _ := cmd.ParseStringArg(c.Args, 1, true)
 	arg2, _ := cmd.ParseStringArg(c.Args, 2, true)
 	arg3, argErr := cmd.ParseStringSliceArg(c.Args, 3, -1)
@@ -147,107 +122,124 @@ func LeaveJoinMsgCfgCommand(c bot.Command) error {
 	argCollapse, argCollapseErr := cmd.ParseBoolArg(c.Args, 3)
 
 	defaultResponse := func() error {
-		_, err := cmd.SendEmbed(c.E, "Leave & Join Message", "Available arguments are:\n- `join|leave channel|message|embed|enabled|collapse <channel|message|embed json|enabled bool|collapse bool>`", bot.DefaultColor)
+		_, err := cmd.SendEmbed(c.E, "Leave & Join Message", c.T("Available arguments are:")+"\n- `join|leave channel|message|embed|enabled|collapse <channel|message|embed json|enabled bool|collapse bool>`", bot.DefaultColor)
 		return err
 	}
 
-	subArgs := func(s string, msg Message) Message {
+	// The replies are sent after updating the config, so that it isn't locked while sending them
+	reply := func(title, description string, color discord.Color) func() error {
+		return func() error {
+			_, err := cmd.SendEmbed(c.E, title, description, color)
+			return err
+		}
+	}
+	replyEmbeds := func(embeds ...discord.Embed) func() error {
+		return func() error {
+			_, err := bot.Client.SendMessage(c.E.ChannelID, "", embeds...)
+			return err
+		}
+	}
+
+	// subArgs will return a func that sets the new value of msg, or nil if nothing should be changed, and a func that
+	// sends the reply
+	subArgs := func(s string, msg Message) (func(m *Message), func() error) {
 		switch arg2 {
 		case "channel":
 			if argChannelErr != nil {
 				if msg.Channel == 0 {
-					_, err = cmd.SendEmbed(c.E, s+" Message Channel", s+" Message channel is not set!", bot.WarnColor)
-
-				} else {
-					_, err = cmd.SendEmbed(c.E, s+" Message Channel", fmt.Sprintf("%s Message channel is set to <#%v>!", s, msg.Channel), bot.DefaultColor)
+					return nil, reply(s+" Message Channel", s+" Message channel is not set!", bot.WarnColor)
 				}
-			} else {
-				msg.Channel = argChannel
-				_, err = cmd.SendEmbed(c.E, s+" Message Channel", fmt.Sprintf("Set %s Message channel to <#%v>!", s, argChannel), bot.SuccessColor)
+				return nil, reply(s+" Message Channel", fmt.Sprintf("%s Message channel is set to <#%v>!", s, msg.Channel), bot.DefaultColor)
 			}
+
+			return func(m *Message) { m.Channel = argChannel },
+				reply(s+" Message Channel", fmt.Sprintf("Set %s Message channel to <#%v>!", s, argChannel), bot.SuccessColor)
 		case "message":
 			if argErr != nil {
-				_, err = cmd.SendEmbed(c.E, s+" Message Content", fmt.Sprintf("%s Message content is set to \n```\n%s\n```", s, msg.Content), bot.DefaultColor)
-			} else {
-				msg.Content = strings.Join(arg3, " ")
-				_, err = cmd.SendEmbed(c.E, s+" Message Content", fmt.Sprintf("Set %s Message content to \n```\n%s\n```", s, msg.Content), bot.SuccessColor)
+				return nil, reply(s+" Message Content", fmt.Sprintf("%s Message content is set to \n```\n%s\n```", s, msg.Content), bot.DefaultColor)
 			}
+
+			content := strings.Join(arg3, " ")
+			return func(m *Message) { m.Content = content },
+				reply(s+" Message Content", fmt.Sprintf("Set %s Message content to \n```\n%s\n```", s, content), bot.SuccessColor)
 		case "embed":
 			if argErr != nil || len(arg3) == 0 {
 				embed := cmd.MakeEmbed(s+" Message Embed", fmt.Sprintf("%s Message embed is set to:", s), bot.DefaultColor)
 
 				if msg.Embed != nil {
-					log.Println("here1")
-					_, err = bot.Client.SendMessage(c.E.ChannelID, "", embed, *msg.Embed)
-				} else {
-					log.Println("here2")
-					_, err = bot.Client.SendMessage(c.E.ChannelID, "", embed)
-				}
-			} else {
-				if err == nil {
-					var embed discord.Embed
-					err = json.Unmarshal([]byte(strings.Join(arg3, " ")), &embed)
-
-					if err == nil {
-						msg.Embed = &embed
-						_, err = bot.Client.SendMessage(c.E.ChannelID, "", cmd.MakeEmbed(s+" Message Embed", fmt.Sprintf("Set %s Message embed to:", s), bot.SuccessColor), embed)
-					}
+					return nil, replyEmbeds(embed, *msg.Embed)
 				}
+				return nil, replyEmbeds(embed)
+			}
+
+			var embed discord.Embed
+			if err := json.Unmarshal([]byte(strings.Join(arg3, " ")), &embed); err != nil {
+				return nil, func() error { return err }
 			}
+
+			return func(m *Message) { m.Embed = &embed },
+				replyEmbeds(cmd.MakeEmbed(s+" Message Embed", fmt.Sprintf("Set %s Message embed to:", s), bot.SuccessColor), embed)
 		case "enabled":
 			if argEnabledErr != nil {
 				if msg.Enabled {
-					_, err = cmd.SendEmbed(c.E, s+" Message", s+" Message is enabled!", bot.SuccessColor)
-				} else {
-					_, err = cmd.SendEmbed(c.E, s+" Message", s+" Message is not enabled!", bot.WarnColor)
-				}
-			} else {
-				msg.Enabled = argEnabled
-				if argEnabled {
-					_, err = cmd.SendEmbed(c.E, s+" Message", "✅ Enabled "+s+" Message!", bot.SuccessColor)
-				} else {
-					_, err = cmd.SendEmbed(c.E, s+" Message", "⛔ Disabled "+s+" Message!", bot.ErrorColor)
+					return nil, reply(s+" Message", s+" Message is enabled!", bot.SuccessColor)
 				}
+				return nil, reply(s+" Message", s+" Message is not enabled!", bot.WarnColor)
+			}
+
+			set := func(m *Message) { m.Enabled = argEnabled }
+			if argEnabled {
+				return set, reply(s+" Message", "✅ Enabled "+s+" Message!", bot.SuccessColor)
 			}
+			return set, reply(s+" Message", "⛔ Disabled "+s+" Message!", bot.ErrorColor)
 		case "collapse":
 			if argCollapseErr != nil {
 				if msg.CollapseMessage {
-					_, err = cmd.SendEmbed(c.E, s+" Message Collapsing", s+" Message Collapsing is enabled!", bot.SuccessColor)
-				} else {
-					_, err = cmd.SendEmbed(c.E, s+" Message Collapsing", s+" Message Collapsing is not enabled!", bot.WarnColor)
-				}
-			} else {
-				msg.CollapseMessage = argCollapse
-				if argCollapse {
-					_, err = cmd.SendEmbed(c.E, s+" Message Collapsing", "✅ Enabled collapsing for "+s+" Message!", bot.SuccessColor)
-				} else {
-					_, err = cmd.SendEmbed(c.E, s+" Message Collapsing", "⛔ Disabled collapsing for "+s+" Message!", bot.ErrorColor)
+					return nil, reply(s+" Message Collapsing", s+" Message Collapsing is enabled!", bot.SuccessColor)
 				}
+				return nil, reply(s+" Message Collapsing", s+" Message Collapsing is not enabled!", bot.WarnColor)
+			}
+
+			set := func(m *Message) { m.CollapseMessage = argCollapse }
+			if argCollapse {
+				return set, reply(s+" Message Collapsing", "✅ Enabled collapsing for "+s+" Message!", bot.SuccessColor)
 			}
+			return set, reply(s+" Message Collapsing", "⛔ Disabled collapsing for "+s+" Message!", bot.ErrorColor)
 		default:
-			err = defaultResponse()
+			return nil, defaultResponse
 		}
+	}
 
-		return msg
+	if arg != "join" && arg != "leave" {
+		return defaultResponse()
 	}
 
-	switch arg {
-	case "join":
-		msgConfig.JoinMessage = subArgs("Join", msgConfig.JoinMessage)
-	case "leave":
-		msgConfig.LeaveMessage = subArgs("Leave", msgConfig.LeaveMessage)
-	default:
-		err = defaultResponse()
+	// message will return the join or leave message of g
+	message := func(g *MsgConfig) *Message {
+		if arg == "join" {
+			return &g.JoinMessage
+		}
+		return &g.LeaveMessage
+	}
+
+	id := c.E.GuildID.String()
+	var msg Message
+	guilds.View(id, func(g MsgConfig) {
+		msg = *message(&g)
+	})
+
+	s := "Join"
+	if arg == "leave" {
+		s = "Leave"
 	}
 
-	if p.Config == nil {
-		guilds := make(map[string]MsgConfig, 0)
-		guilds[c.E.GuildID.String()] = msgConfig
-		cfg := config{Guilds: guilds}
-		p.Config = cfg
-	} else {
-		p.Config.(config).Guilds[c.E.GuildID.String()] = msgConfig
+	// Only the changed field is set, in one update, so that the LastMessage set by the handlers isn't overwritten
+	set, send := subArgs(s, msg)
+	if set != nil {
+		guilds.Update(id, func(g *MsgConfig) {
+			set(message(g))
+		})
 	}
 
-	return err
+	return send()
 }
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/5HT2/taro-bot/bot"
+	"github.com/5HT2/taro-bot/cmd/cmdtest"
+	"github.com/5HT2/taro-bot/plugins"
+	"strings"
+	"testing"
+)
+
+func TestLeaveJoinMsgCfg(t *testing.T) {
+	h := cmdtest.New(t, InitPlugin(&plugins.PluginInit{ConfigDir: "leave-join-msg"}))
+	prefix := bot.DefaultPrefix
+	id := h.Guild.ID.String()
+
+	// Reading a setting, or failing to set one, doesn't add a config for the guild
+	h.SendAs(h.Owner, prefix+"ljcfg join channel")
+	if got := cmdtest.Text(h.LastReply()); !strings.Contains(got, "channel is not set") {
+		t.Errorf("expected the channel to not be set, got %q", got)
+	}
+	h.SendAs(h.Owner, prefix+"ljcfg join embed {not json")
+	if _, ok := guilds.Lookup(id); ok {
+		t.Fatalf("expected reading the config to not change it")
+	}
+
+	guilds.Update(id, func(g *MsgConfig) {
+		g.JoinMessage.LastMessage = 5
+	})
+
+	h.SendAs(h.Owner, prefix+"ljcfg join message Welcome USER_ID")
+	h.SendAs(h.Owner, prefix+"ljcfg join enabled true")
+	if got := cmdtest.Text(h.LastReply()); !strings.Contains(got, "Enabled Join Message") {
+		t.Errorf("expected the join message to be enabled, got %q", got)
+	}
+
+	g := guilds.Get(id)
+	if !g.JoinMessage.Enabled || g.JoinMessage.Content != "Welcome USER_ID" || g.JoinMessage.LastMessage != 5 {
+		t.Errorf("expected only the changed fields to be set, got %+v", g.JoinMessage)
+	}
+}
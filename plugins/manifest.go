@@ -0,0 +1,234 @@
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/5HT2/taro-bot/bot"
+	"github.com/5HT2/taro-bot/util"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// APIVersion is the version of the plugin API that the bot provides. It has to be bumped whenever the bot, cmd or plugins
+// packages change in a way that makes previously compiled plugins incompatible, so that stale plugins are refused
+// before calling plugin.Open on them.
+const APIVersion = 4
+
+var (
+	statuses     = make([]*Status, 0)
+	statusMutex  sync.Mutex
+	schemaTypes  = []string{"string", "number", "bool", "object", "array"}
+	manifestName = "%s.manifest.json"
+)
+
+// Manifest is shipped next to each compiled plugin as `name.manifest.json`, and is checked before the plugin is opened.
+type Manifest struct {
+	Name         string            `json:"name"`                    // Name of the plugin, must match the name of the .so file
+	Version      string            `json:"version"`                 // Version in semver, must match Plugin.Version
+	APIVersion   int               `json:"api_version"`             // APIVersion the plugin was built against
+	HostVersion  string            `json:"host_version"`            // HostVersion is the minimum bot.Version the plugin requires
+	ConfigSchema map[string]string `json:"config_schema,omitempty"` // ConfigSchema is the [json key]type of the plugin config
+}
+
+// State is the outcome of loading a plugin, shown in its Status
+type State string
+
+const (
+	StateLoaded  State = "loaded"
+	StateFailed  State = "failed"
+	StateMissing State = "missing"
+)
+
+// Status is the result of trying to load a single plugin, used to report why a plugin did or did not load.
+type Status struct {
+	Name     string
+	Manifest *Manifest
+	State    State
+	Reason   string
+	Warnings []string
+}
+
+func (s *Status) String() string {
+	return fmt.Sprintf("[%s, %s, %s, %v]", s.Name, s.State, s.Reason, s.Warnings)
+}
+
+func (s *Status) fail(format string, a ...any) {
+	s.State = StateFailed
+	s.Reason = fmt.Sprintf(format, a...)
+}
+
+func (s *Status) warn(format string, a ...any) {
+	s.Warnings = append(s.Warnings, fmt.Sprintf(format, a...))
+}
+
+// Statuses will return a copy of the statuses from the last time plugins were loaded
+func Statuses() []Status {
+	statusMutex.Lock()
+	defer statusMutex.Unlock()
+
+	s := make([]Status, 0, len(statuses))
+	for _, status := range statuses {
+		s = append(s, *status)
+	}
+	return s
+}
+
+// HealthCheck will report how many of the plugins in bot.P.LoadedPlugins have loaded, and fail if any of them didn't.
+// It is added with bot.AddHealthCheck.
+func HealthCheck() (string, error) {
+	if !loaded.Load() {
+		return "", errors.New("plugins are still loading")
+	}
+
+	failed := make([]string, 0)
+	n := 0
+	for _, s := range Statuses() {
+		if s.State == StateLoaded {
+			n++
+		} else {
+			failed = append(failed, s.Name)
+		}
+	}
+
+	detail := fmt.Sprintf("%v/%v plugins loaded", n, len(parsePluginsList()))
+	if len(failed) > 0 {
+		return "", fmt.Errorf("%s, not loaded: %s", detail, strings.Join(failed, ", "))
+	}
+
+	disabled := make([]string, 0)
+	for _, p := range loadedPlugins() {
+		if p.Disabled() {
+			disabled = append(disabled, p.ConfigDir)
+		}
+	}
+	if len(disabled) > 0 {
+		detail += ", disabled after panicking: " + strings.Join(disabled, ", ")
+	}
+	return detail, nil
+}
+
+func addStatus(s *Status) {
+	statusMutex.Lock()
+	defer statusMutex.Unlock()
+
+	statuses = append(statuses, s)
+}
+
+func clearStatuses() {
+	statusMutex.Lock()
+	defer statusMutex.Unlock()
+
+	statuses = make([]*Status, 0)
+}
+
+// LoadManifest will read the manifest for the plugin with name from dir
+func LoadManifest(dir, name string) (*Manifest, error) {
+	bytes, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf(manifestName, name)))
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(bytes, &m); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// Validate will return an error if the manifest is incompatible with the running bot
+func (m *Manifest) Validate(name string) error {
+	if m.Name != name {
+		return fmt.Errorf("manifest name `%s` does not match plugin file `%s`", m.Name, name)
+	}
+
+	if m.APIVersion != APIVersion {
+		return fmt.Errorf("built for plugin API v%v, but the bot provides v%v (rebuild the plugin)", m.APIVersion, APIVersion)
+	}
+
+	if _, err := util.ParseVersion(m.Version); err != nil {
+		return fmt.Errorf("invalid version: %v", err)
+	}
+
+	if len(m.HostVersion) > 0 {
+		if c, err := util.CompareVersions(bot.Version, m.HostVersion); err != nil {
+			return fmt.Errorf("invalid host_version: %v", err)
+		} else if c < 0 {
+			return fmt.Errorf("requires bot version %s or newer, but this is %s", m.HostVersion, bot.Version)
+		}
+	}
+
+	for key, typ := range m.ConfigSchema {
+		if !util.SliceContains(schemaTypes, typ) {
+			return fmt.Errorf("config_schema key `%s` has unknown type `%s`", key, typ)
+		}
+	}
+
+	return nil
+}
+
+// validatePlugin will return an error if the plugin returned by InitPlugin doesn't match the manifest
+func (m *Manifest) validatePlugin(p *Plugin) error {
+	if p.Version != m.Version {
+		return fmt.Errorf("manifest version %s does not match plugin version %s", m.Version, p.Version)
+	}
+
+	return nil
+}
+
+// validateConfig will compare the plugin's existing config file with the ConfigSchema, if there is one.
+// This doesn't stop the plugin from loading, but it will explain why a config might have failed to load.
+func (m *Manifest) validateConfig(configDir string, s *Status) {
+	if len(m.ConfigSchema) == 0 {
+		return
+	}
+
+	bytes, err := os.ReadFile(configPath(configDir, m.Version))
+	if err != nil {
+		return // no config has been saved yet
+	}
+
+	var cfg map[string]json.RawMessage
+	if err := json.Unmarshal(bytes, &cfg); err != nil {
+		s.warn("config is not a json object: %v", err)
+		return
+	}
+
+	for key, value := range cfg {
+		typ, ok := m.ConfigSchema[key]
+		if !ok {
+			s.warn("config key `%s` is not in config_schema", key)
+			continue
+		}
+
+		if found := jsonType(value); found != "null" && found != typ {
+			s.warn("config key `%s` is a %s, expected %s", key, found, typ)
+		}
+	}
+}
+
+// jsonType will return the config_schema type of a raw json value
+func jsonType(r json.RawMessage) string {
+	v := strings.TrimSpace(string(r))
+	if len(v) == 0 {
+		return "null"
+	}
+
+	switch v[0] {
+	case '"':
+		return "string"
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case 't', 'f':
+		return "bool"
+	case 'n':
+		return "null"
+	default:
+		return "number"
+	}
+}
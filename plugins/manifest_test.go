@@ -0,0 +1,54 @@
+package plugins
+
+import (
+	"github.com/5HT2/taro-bot/bot"
+	"strings"
+	"testing"
+)
+
+func TestManifestValidate(t *testing.T) {
+	valid := Manifest{Name: "test", Version: "1.0.0", APIVersion: APIVersion, HostVersion: bot.Version}
+
+	tests := []struct {
+		name    string
+		edit    func(m *Manifest)
+		wantErr string // wantErr is part of the error, or empty if the manifest is valid
+	}{
+		{name: "valid", edit: func(m *Manifest) {}},
+		{name: "prerelease version", edit: func(m *Manifest) { m.Version = "1.0.0-beta.1" }},
+		{name: "no host version", edit: func(m *Manifest) { m.HostVersion = "" }},
+		{name: "other name", edit: func(m *Manifest) { m.Name = "other" }, wantErr: "does not match plugin file"},
+		{name: "old api version", edit: func(m *Manifest) { m.APIVersion = APIVersion - 1 }, wantErr: "rebuild the plugin"},
+		{name: "malformed version", edit: func(m *Manifest) { m.Version = "1.0" }, wantErr: "invalid version"},
+		{name: "empty version", edit: func(m *Manifest) { m.Version = "" }, wantErr: "invalid version"},
+		{name: "malformed host version", edit: func(m *Manifest) { m.HostVersion = "one" }, wantErr: "invalid host_version"},
+		{name: "newer host version", edit: func(m *Manifest) { m.HostVersion = "999.0.0" }, wantErr: "requires bot version"},
+		{name: "unknown schema type", edit: func(m *Manifest) { m.ConfigSchema = map[string]string{"guilds": "map"} }, wantErr: "unknown type"},
+	}
+
+	for _, tt := range tests {
+		m := valid
+		tt.edit(&m)
+
+		err := m.Validate("test")
+		switch {
+		case len(tt.wantErr) == 0 && err != nil:
+			t.Errorf("%s: expected the manifest to be valid, got %v", tt.name, err)
+		case len(tt.wantErr) > 0 && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestManifestValidatePlugin(t *testing.T) {
+	m := Manifest{Name: "test", Version: "1.0.0"}
+
+	if err := m.validatePlugin(&Plugin{Version: "1.0.0"}); err != nil {
+		t.Errorf("expected the plugin version to match, got %v", err)
+	}
+	for _, version := range []string{"1.0.1", "1.0.0-beta", "v1.0.0", ""} {
+		if err := m.validatePlugin(&Plugin{Version: version}); err == nil {
+			t.Errorf("expected plugin version %q to not match manifest version %s", version, m.Version)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"embed"
 	"fmt"
 	"github.com/5HT2/taro-bot/bot"
 	"github.com/5HT2/taro-bot/cmd"
@@ -8,24 +9,30 @@ import (
 	"github.com/5HT2/taro-bot/util"
 	"github.com/diamondburned/arikawa/v3/api"
 	"github.com/diamondburned/arikawa/v3/discord"
-	"log"
-	"reflect"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
 var (
-	p     *plugins.Plugin
-	mutex sync.Mutex
+	p          *plugins.Plugin
+	store      = plugins.NewStore[config](nil)
+	guildUsers = plugins.NewGuildStore(store, func(c *config) *plugins.GuildMap[map[string]User] { return &c.GuildUsers }, func() map[string]User {
+		return make(map[string]User)
+	})
+	guildRoles = plugins.NewGuildStore(store, func(c *config) *plugins.GuildMap[[]Role] { return &c.GuildRoles }, nil)
+
+	//go:embed locales/*.json
+	locales embed.FS
+
+	topLinesPerField = 10 // topLinesPerField is how many users are in each field of MessageTopCommand, after the top 3
 )
 
 type config struct {
-	StartDate  time.Time                  `json:"start_date"`              // Date bot started keeping track of User.TotalMsgs
-	GuildUsers map[string]map[string]User `json:"guild_users,omitempty"`   // [guild id][user id]User
-	GuildRoles map[string][]Role          `json:"guild_configs,omitempty"` // [guild id][]Role
+	StartDate  time.Time                         `json:"start_date"`                          // Date bot started keeping track of User.TotalMsgs
+	GuildUsers plugins.GuildMap[map[string]User] `json:"guild_users,omitempty" taro:"hidden"` // [guild id][user id]User
+	GuildRoles plugins.GuildMap[[]Role]          `json:"guild_configs,omitempty"`             // [guild id][]Role
 	// this could also be a [guild id][role id]Role for performance reasons, but it's only loop-searched in commands,
 	// so it can stay like this for now.
 }
@@ -37,6 +44,17 @@ type User struct {
 	GivenRoles    map[string]bool  `json:"given_roles"`     // [role id]given role
 }
 
+// newUser will return u, with its maps created if they are nil
+func newUser(u User) User {
+	if u.Msgs == nil {
+		u.Msgs = make(map[string]int64)
+	}
+	if u.GivenRoles == nil {
+		u.GivenRoles = make(map[string]bool)
+	}
+	return u
+}
+
 type Role struct {
 	LevelUpMsg bool    `json:"level_up_msg"`
 	Threshold  int64   `json:"threshold"`
@@ -50,6 +68,7 @@ func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
 		Name:        "Message Roles",
 		Description: "Assign a role once a message threshold has been reached",
 		Version:     "1.0.2",
+		Locales:     locales,
 		Commands: []bot.CommandInfo{{
 			Fn:          MessageRolesConfigCommand,
 			FnName:      "MessageRolesConfigCommand",
@@ -57,6 +76,7 @@ func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
 			Aliases:     []string{"mrcfg"},
 			Description: "Edit message roles config",
 			GuildOnly:   true,
+			Permission:  "moderate",
 		}, {
 			Fn:          MessageTopCommand,
 			FnName:      "MessageTopCommand",
@@ -66,36 +86,28 @@ func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
 			GuildOnly:   true,
 		}},
 		Responses: []bot.ResponseInfo{{
-			Fn:       MsgThresholdMsgResponse,
-			Regexes:  []string{"."},
-			MatchMin: 1,
+			Fn:            MsgThresholdMsgResponse,
+			Regexes:       []string{"."},
+			MatchMin:      1,
+			AllowCommands: true, // commands count towards message roles, like any other message
+			NoRateLimit:   true, // every message is counted, so ordinary chat shouldn't use up the rate limit of commands
 		}},
-		ConfigType: reflect.TypeOf(config{}),
+		Config: store,
 		StartupFn: func() {
-			if cfg, ok := p.Config.(config); ok {
-				if cfg.StartDate.IsZero() {
-					cfg.StartDate = time.Now()
-				}
-
-				p.Config = cfg
-			} else {
-				p.Config = config{StartDate: time.Now()}
+			if store.Get().StartDate.IsZero() {
+				store.Update(func(c *config) {
+					c.StartDate = time.Now()
+				})
 			}
 		},
 	}
 	p.ConfigDir = i.ConfigDir
-	p.Config = p.LoadConfig()
+	p.LoadConfig()
 	return p
 }
 
 func MsgThresholdMsgResponse(r bot.Response) {
-	mutex.Lock()
-	defer mutex.Unlock()
-
-	roles := make([]Role, 0)
-	if guildRoles, ok := p.Config.(config).GuildRoles[r.E.GuildID.String()]; ok {
-		roles = guildRoles
-	}
+	roles := guildRoles.Get(r.E.GuildID.String())
 
 	// this will go and validate if the message channel is in the whitelist or blacklist, or neither, and bump the message count for said role
 	bumpMessages := func(roles []Role, user User, channel discord.ChannelID) User {
@@ -129,78 +141,60 @@ func MsgThresholdMsgResponse(r bot.Response) {
 		return user
 	}
 
-	// this will check each role if the threshold is met or not, and assign it if so
-	checkThreshold := func(roles []Role, user User) User {
+	// this will check each role if the threshold is met or not, and mark it as given if so.
+	// the roles are assigned after the config has been updated, so that we don't hold the config while calling discord.
+	checkThreshold := func(roles []Role, user User) (User, []Role) {
+		pending := make([]Role, 0)
 		for _, role := range roles {
 			roleID := strconv.FormatInt(role.ID, 10)
 			givenRole, _ := user.GivenRoles[roleID]
 
 			if !givenRole && user.Msgs[roleID] >= role.Threshold && role.ID != 0 && role.Threshold != 0 {
-				// Assign role
-				reason := fmt.Sprintf("user messages met threshold of %v for role <@&%v>", role.Threshold, role.ID)
-				data := api.AddRoleData{AuditLogReason: api.AuditLogReason(reason)}
-				log.Printf("attempting to add threshold role: %v (%s)\n", role.ID, data)
-
-				if err := bot.Client.AddRole(r.E.GuildID, r.E.Author.ID, discord.RoleID(role.ID), data); err != nil {
-					log.Printf("failed to add threshold role: %v\n", err)
-				} else {
-					user.GivenRoles[roleID] = true
-
-					author := cmd.CreateEmbedAuthor(*r.E.Member)
-					_, _ = cmd.SendMessageEmbedSafe(r.E.ChannelID, r.E.Author.Mention(), &discord.Embed{
-						Title:       "Role Level Up!",
-						Description: fmt.Sprintf("Congrats! 🎉 You've earned the role <@&%v>!", role.ID),
-						Author:      author,
-						Footer:      &discord.EmbedFooter{Text: "Messages sent since"},
-						Timestamp:   discord.Timestamp(p.Config.(config).StartDate),
-						Color:       bot.DefaultColor,
-					})
-				}
+				user.GivenRoles[roleID] = true
+				pending = append(pending, role)
 			}
 		}
 
-		return user
+		return user, pending
 	}
 
-	// Check if the guild has an existing config
-	if cfg, ok := p.Config.(config).GuildUsers[r.E.GuildID.String()]; ok {
-		// Make a new user, populate it
-		user := User{Msgs: make(map[string]int64), GivenRoles: make(map[string]bool)}
-
-		// If the guild has an existing config, does this user exist in it yet?
-		if guildUser, ok := cfg[r.E.Author.ID.String()]; ok {
-			user = guildUser
-		}
+	pending := make([]Role, 0)
+	guildUsers.Update(r.E.GuildID.String(), func(users *map[string]User) {
+		// If the user doesn't exist in this guild's config yet, this will make a new user
+		user := newUser((*users)[r.E.Author.ID.String()])
 
-		// User not in this guild's config, add them to it.
 		user = bumpMessages(roles, user, r.E.ChannelID)
-		user = checkThreshold(roles, user)
+		user, pending = checkThreshold(roles, user)
 
 		// Update the config
-		p.Config.(config).GuildUsers[r.E.GuildID.String()][r.E.Author.ID.String()] = user
-	} else {
-		// Make a new user, populate it
-		user := User{Msgs: make(map[string]int64), GivenRoles: make(map[string]bool)}
-		user = bumpMessages(roles, user, r.E.ChannelID)
-		user = checkThreshold(roles, user)
-
-		// Users map not found, create it
-		users := make(map[string]User)
-		users[r.E.Author.ID.String()] = user
+		(*users)[r.E.Author.ID.String()] = user
+	})
 
-		// If there are no guilds with users, create a new guild and replace it with the `users` map
-		if len(p.Config.(config).GuildUsers) == 0 {
-			guilds := make(map[string]map[string]User, 0)
-			guilds[r.E.GuildID.String()] = users
+	for _, role := range pending {
+		// Assign role
+		reason := fmt.Sprintf("user messages met threshold of %v for role <@&%v>", role.Threshold, role.ID)
+		data := api.AddRoleData{AuditLogReason: api.AuditLogReason(reason)}
+		r.Log().Debug("attempting to add threshold role", "role", role.ID, "reason", reason)
 
-			cfg := p.Config.(config)
-			cfg.GuildUsers = guilds
+		if err := bot.Client.AddRole(r.E.GuildID, r.E.Author.ID, discord.RoleID(role.ID), data); err != nil {
+			r.Log().Error("failed to add threshold role", "role", role.ID, "err", err)
 
-			p.Config = cfg
+			// Try again on the next message
+			roleID := strconv.FormatInt(role.ID, 10)
+			guildUsers.Update(r.E.GuildID.String(), func(users *map[string]User) {
+				delete((*users)[r.E.Author.ID.String()].GivenRoles, roleID)
+			})
+		} else {
+			author := cmd.CreateEmbedAuthor(*r.E.Member)
+			_, _ = cmd.SendMessageEmbedSafe(r.E.ChannelID, r.E.Author.Mention(), &discord.Embed{
+				Title:       r.T("Role Level Up!"),
+				Description: r.T("Congrats! 🎉 You've earned the role <@&%v>!", role.ID),
+				Author:      author,
+				Footer:      &discord.EmbedFooter{Text: r.T("Messages sent since")},
+				Timestamp:   discord.Timestamp(store.Get().StartDate),
+				Color:       bot.DefaultColor,
+			})
 		}
-
-		// Save `users` map in the config
-		p.Config.(config).GuildUsers[r.E.GuildID.String()] = users
 	}
 }
 
@@ -209,9 +203,6 @@ func MessageRolesConfigCommand(c bot.Command) error {
 		return err
 	}
 
-	mutex.Lock()
-	defer mutex.Unlock()
-
 	getPrintEmbed := func(title string, roles ...Role) discord.Embed {
 		lines := make([]string, 0)
 		for _, role := range roles {
@@ -236,10 +227,8 @@ func MessageRolesConfigCommand(c bot.Command) error {
 		return embed
 	}
 
-	roles := make([]Role, 0)
-	if guildRoles, ok := p.Config.(config).GuildRoles[c.E.GuildID.String()]; ok {
-		roles = guildRoles
-	}
+	// Copy the roles, so they aren't changed in the config until they're saved with guildRoles.Update
+	roles := append(make([]Role, 0), guildRoles.Get(c.E.GuildID.String())...)
 
 	arg, _ := cmd.ParseStringArg(c.Args, 1, true)
 	var err error = nil
@@ -405,43 +394,14 @@ func MessageRolesConfigCommand(c bot.Command) error {
 			} else {
 				roleStr := fmt.Sprintf("%v", role)
 
-				// Check if the guild has an existing config
-				if cfg, ok := p.Config.(config).GuildUsers[c.E.GuildID.String()]; ok {
-					user := User{Msgs: make(map[string]int64), GivenRoles: make(map[string]bool)}
-
-					// If the guild has an existing config, does this user exist in it yet?
-					if guildUser, ok := cfg[discordUser.ID.String()]; ok {
-						user = guildUser
-					}
-
-					// User not in this guild's config, add them to it.
+				guildUsers.Update(c.E.GuildID.String(), func(users *map[string]User) {
+					// If the user doesn't exist in this guild's config yet, this will make a new user
+					user := newUser((*users)[discordUser.ID.String()])
 					user.GivenRoles[roleStr] = true
 
 					// Update the config
-					p.Config.(config).GuildUsers[c.E.GuildID.String()][discordUser.ID.String()] = user
-				} else {
-					// Make a new user, populate it
-					user := User{Msgs: make(map[string]int64), GivenRoles: make(map[string]bool)}
-					user.GivenRoles[roleStr] = true
-
-					// Users map not found, create it
-					users := make(map[string]User)
-					users[discordUser.ID.String()] = user
-
-					// If there are no guilds with users, create a new guild and replace it with the `users` map
-					if len(p.Config.(config).GuildUsers) == 0 {
-						guilds := make(map[string]map[string]User, 0)
-						guilds[c.E.GuildID.String()] = users
-
-						cfg := p.Config.(config)
-						cfg.GuildUsers = guilds
-
-						p.Config = cfg
-					}
-
-					// Save `users` map in the config
-					p.Config.(config).GuildUsers[c.E.GuildID.String()] = users
-				}
+					(*users)[discordUser.ID.String()] = user
+				})
 
 				_, err = cmd.SendEmbed(c.E, p.Name, fmt.Sprintf("Succesfully blacklisted <@%v> from getting <@&%v>!", user, role), bot.SuccessColor)
 				return err
@@ -477,7 +437,7 @@ func MessageRolesConfigCommand(c bot.Command) error {
 			return argErr3
 		}
 
-		cfg, ok := p.Config.(config).GuildRoles[c.E.GuildID.String()]
+		cfg, ok := guildRoles.Lookup(c.E.GuildID.String())
 		if !ok {
 			_, err = cmd.SendEmbed(c.E, p.Name, "You don't have any roles setup for Message Roles! Add one using the `role` argument.", bot.ErrorColor)
 			return err
@@ -503,7 +463,9 @@ func MessageRolesConfigCommand(c bot.Command) error {
 		}
 
 		// Update the config
-		p.Config.(config).GuildRoles[c.E.GuildID.String()] = cfg
+		guildRoles.Update(c.E.GuildID.String(), func(r *[]Role) {
+			*r = cfg
+		})
 
 		_, err = cmd.SendCustomEmbed(c.E.ChannelID,
 			cmd.MakeEmbed(p.Name, "Updated level up messages:", bot.SuccessColor),
@@ -520,7 +482,7 @@ func MessageRolesConfigCommand(c bot.Command) error {
 	default:
 		_, err = cmd.SendEmbed(c.E,
 			"Configure Message Roles",
-			"Available arguments are:\n"+
+			c.T("Available arguments are:")+"\n"+
 				"- `role [role id] [threshold]`\n"+
 				"- `remove [role id]`\n"+
 				"- `whitelist [role id] [channel]`\n"+
@@ -532,23 +494,26 @@ func MessageRolesConfigCommand(c bot.Command) error {
 			bot.DefaultColor)
 	}
 
-	if p.Config == nil {
-		guilds := make(map[string][]Role, 0)
-		guilds[c.E.GuildID.String()] = roles
-		cfg := config{GuildRoles: guilds}
-		p.Config = cfg
-	} else {
-		p.Config.(config).GuildRoles[c.E.GuildID.String()] = roles
-	}
+	guildRoles.Update(c.E.GuildID.String(), func(r *[]Role) {
+		*r = roles
+	})
 
 	return err
 }
 
 func MessageTopCommand(c bot.Command) error {
-	mutex.Lock()
-	defer mutex.Unlock()
+	cfg := make(map[string]int64) // [user id]User.TotalMsgs
+	ok := false
+	store.View(func(c1 config) {
+		var users map[string]User
+		if users, ok = c1.GuildUsers[c.E.GuildID.String()]; ok {
+			for k, u := range users {
+				cfg[k] = u.TotalMsgs
+			}
+		}
+	})
 
-	if cfg, ok := p.Config.(config).GuildUsers[c.E.GuildID.String()]; ok {
+	if ok {
 		topUsers := make([]string, 0)
 
 		for k := range cfg {
@@ -560,7 +525,7 @@ func MessageTopCommand(c bot.Command) error {
 		}
 
 		sort.SliceStable(topUsers, func(i, j int) bool {
-			return cfg[topUsers[i]].TotalMsgs > cfg[topUsers[j]].TotalMsgs
+			return cfg[topUsers[i]] > cfg[topUsers[j]]
 		})
 
 		lines := make([]string, 0)
@@ -573,7 +538,7 @@ func MessageTopCommand(c bot.Command) error {
 		for n, u := range topUsers {
 			if u == id {
 				selfPos = n + 1
-				selfNum = util.FormattedNum(cfg[u].TotalMsgs)
+				selfNum = util.FormattedNum(cfg[u])
 			}
 
 			if n < 3 {
@@ -589,17 +554,24 @@ func MessageTopCommand(c bot.Command) error {
 
 				fields = append(fields, discord.EmbedField{
 					Name:  emoji,
-					Value: fmt.Sprintf("<@%s>: %s", u, util.FormattedNum(cfg[u].TotalMsgs)),
+					Value: fmt.Sprintf("<@%s>: %s", u, util.FormattedNum(cfg[u])),
 				})
 			} else {
-				lines = append(lines, fmt.Sprintf("#%v <@%s>: %s", n+1, u, util.FormattedNum(cfg[u].TotalMsgs)))
+				lines = append(lines, fmt.Sprintf("#%v <@%s>: %s", n+1, u, util.FormattedNum(cfg[u])))
 			}
 		}
 
-		if len(lines) > 0 {
+		// The rest of the leaderboard is split into fields, which are split into pages by cmd.PageFields
+		for len(lines) > 0 {
+			n := len(lines)
+			if n > topLinesPerField {
+				n = topLinesPerField
+			}
+
 			fields = append(fields, discord.EmbedField{
-				Name: "​", Value: util.HeadLinesLimit(strings.Join(lines, "\n"), 1024),
+				Name: "​", Value: util.HeadLinesLimit(strings.Join(lines[:n], "\n"), 1024),
 			})
+			lines = lines[n:]
 		}
 
 		author := cmd.CreateEmbedAuthor(*c.E.Member)
@@ -607,15 +579,14 @@ func MessageTopCommand(c bot.Command) error {
 			author.Name += fmt.Sprintf(" (#%v: %s)", selfPos, selfNum)
 		}
 
-		_, err := cmd.SendCustomEmbed(c.E.ChannelID, discord.Embed{
-			Title:     "Message Leaderboard",
-			Author:    author,
-			Fields:    fields,
-			Footer:    &discord.EmbedFooter{Text: "Messages sent since"},
-			Timestamp: discord.Timestamp(p.Config.(config).StartDate),
-			Color:     bot.DefaultColor,
-		})
+		pages := cmd.PageFields("Message Leaderboard", fields, bot.DefaultColor)
+		for n := range pages {
+			pages[n].Author = author
+			pages[n].Footer = &discord.EmbedFooter{Text: "Messages sent since"}
+			pages[n].Timestamp = discord.Timestamp(store.Get().StartDate)
+		}
 
+		_, err := cmd.SendPaginator(c.E, pages)
 		return err
 	}
 
@@ -1,24 +1,31 @@
 package plugins
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/5HT2/taro-bot/bot"
+	"github.com/5HT2/taro-bot/cmd"
 	"github.com/5HT2/taro-bot/util"
 	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/go-co-op/gocron"
+	"io/fs"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"plugin"
 	"reflect"
+	"runtime/debug"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var (
-	fileMode = os.FileMode(0755)
-	plugins  = make([]*Plugin, 0)
+	fileMode     = os.FileMode(0755)
+	plugins      = make([]*Plugin, 0)
+	pluginsMutex sync.Mutex
+	loaded       atomic.Bool // loaded is set once RegisterAll has loaded the plugins, see HealthCheck
 )
 
 type PluginInit struct {
@@ -26,80 +33,121 @@ type PluginInit struct {
 }
 
 type Plugin struct {
-	Name        string             // Name of the plugin to display to users
-	Description string             // Description of what the plugin does
-	Version     string             // Version in semver, e.g.., 1.1.0
-	Config      interface{}        // Config is the Plugin's config, can be nil
-	ConfigDir   string             // ConfigDir is the name of the config directory
-	ConfigType  reflect.Type       // ConfigType is the type to validate parse the config with
-	Commands    []bot.CommandInfo  // Commands to register, could be none
-	Responses   []bot.ResponseInfo // Responses to register, could be none
-	Handlers    []bot.HandlerInfo  // Handlers to register, could be none
-	Jobs        []bot.JobInfo      // Jobs to register, could be none
-	StartupFn   func()             // ShutdownFn is a function to be called when the bot starts up
-	ShutdownFn  func()             // ShutdownFn is a function to be called when the bot shuts down
+	Name        string                      // Name of the plugin to display to users
+	Description string                      // Description of what the plugin does
+	Version     string                      // Version in semver, e.g.., 1.1.0
+	Config      ConfigStore                 // Config is the Plugin's config, usually a *Store, can be nil
+	ConfigDir   string                      // ConfigDir is the name of the config directory
+	Commands    []bot.CommandInfo           // Commands to register, could be none
+	Responses   []bot.ResponseInfo          // Responses to register, could be none
+	Components  []bot.ComponentInfo         // Components are the handlers for buttons, select menus and modals, could be none
+	Handlers    []bot.HandlerInfo           // Handlers to register, could be none
+	Jobs        []bot.JobInfo               // Jobs to register, could be none
+	DurableJobs []bot.DurableJobHandlerInfo // DurableJobs are the handlers for durable jobs, see bot.ScheduleDurableJob
+	Locales     fs.FS                       // Locales has the plugin's translations as locales/<locale>.json files, see util.LoadTranslations
+	StartupFn   func()                      // ShutdownFn is a function to be called when the bot starts up
+	ShutdownFn  func()                      // ShutdownFn is a function to be called when the bot shuts down
+
+	pkgPath  string   // pkgPath is the package path of the plugin's functions, used to attribute panics in jobs
+	failures failures // failures keeps track of panics, see recordPanic
 }
 
 func (p *Plugin) String() string {
-	return fmt.Sprintf("[%s, %s, %s, %s, %s, %s, %s, %s, %s]", p.Name, p.Description, p.Version, p.ConfigDir, p.ConfigType, p.Commands, p.Responses, p.Handlers, p.Jobs)
+	var configType reflect.Type
+	if p.Config != nil {
+		configType = p.Config.Type()
+	}
+
+	return fmt.Sprintf("[%s, %s, %s, %s, %s, %s, %s, %s, %s]", p.Name, p.Description, p.Version, p.ConfigDir, configType, p.Commands, p.Responses, p.Handlers, p.Jobs)
+}
+
+// Log will return a logger for the plugin, which uses its log level from bot.Config.LogLevels
+func (p *Plugin) Log() *slog.Logger {
+	return bot.PluginLogger(p.ConfigDir)
 }
 
-// Register will register a plugin's commands, responses and jobs to the bot
+// Register will register a plugin's commands, responses and jobs to the bot.
+// Each of them is wrapped to recover from panics, which are attributed to the plugin, see recordPanic.
 func (p *Plugin) Register() {
+	p.wrap()
+
+	pluginsMutex.Lock()
 	plugins = append(plugins, p)
+	pluginsMutex.Unlock()
 
 	bot.Commands = append(bot.Commands, p.Commands...)
 	bot.Responses = append(bot.Responses, p.Responses...)
+	bot.Components = append(bot.Components, p.Components...)
 	bot.Handlers = append(bot.Handlers, p.Handlers...) // these need to have RegisterHandlers called in order to function
 	bot.Jobs = append(bot.Jobs, p.Jobs...)             // these need to have RegisterJobs called in order to function
-}
 
-func (p *Plugin) LoadConfig() (i interface{}) {
-	defer util.LogPanic() // This code is unsafe, we should log if it panics
+	for _, h := range p.DurableJobs {
+		bot.RegisterDurableJobHandler(h.Kind, h.Fn)
+	}
 
+	// Translations can't be removed, so reloading a plugin only replaces the ones it still has
+	if p.Locales != nil {
+		locales, err := fs.Sub(p.Locales, "locales")
+		if err == nil {
+			err = util.LoadTranslations(locales)
+		}
+		if err != nil {
+			p.Log().Error("failed to load translations", "err", err)
+		}
+	}
+}
+
+// LoadConfig will load the plugin's saved config into p.Config. If there is no saved config, it keeps its default value.
+func (p *Plugin) LoadConfig() {
 	if p.ConfigDir == "" {
-		log.Fatalln("plugin config load failed: p.ConfigDir is unset!")
+		bot.Fatal("plugin config load failed: p.ConfigDir is unset!", "plugin", p.Name)
+	}
+
+	if p.Config == nil {
+		return
 	}
 
 	bytes, err := os.ReadFile(getConfigPath(p))
 	if err != nil {
-		log.Printf("plugin config reading failed (%s): %s\n", p.Name, err)
-		return i
+		p.Log().Info("plugin config reading failed", "err", err)
+		return
 	}
 
-	obj, err := util.NewInterface(p.ConfigType, bytes) // unsafe
-	if err != nil {
-		log.Printf("plugin config unmarshalling failed (%s): %s\n", p.Name, err)
-		return i
+	if err := p.Config.load(bytes); err != nil {
+		p.Log().Error("plugin config unmarshalling failed", "err", err)
+		return
 	}
 
-	log.Printf("plugin config loaded for %s\n", p.Name)
-	return obj
+	p.Log().Info("plugin config loaded")
 }
 
+// SaveConfig will save the plugin's config, if it has changed since it was last saved
 func (p *Plugin) SaveConfig() {
-	if p.Config == nil || p.ConfigType == nil || p.ConfigDir == "" {
-		log.Printf("skipping saving %s\n", p.Name)
+	if p.Config == nil || p.ConfigDir == "" {
+		p.Log().Debug("skipping saving plugin config")
 		return
 	}
+	defer bot.ObserveSince(bot.ConfigSaveDuration.WithLabelValues(p.ConfigDir), time.Now())
 
-	// This is faster than checking if it exists
-	_ = os.Mkdir("config/"+p.ConfigDir, fileMode)
+	saved := false
+	err := p.Config.save(func(bytes []byte) error {
+		// This is faster than checking if it exists
+		_ = os.Mkdir("config/"+p.ConfigDir, fileMode)
 
-	if bytes, err := json.MarshalIndent(p.Config, "", "    "); err != nil {
-		log.Printf("plugin config marshalling failed (%s): %s\n", p.Name, err)
-	} else {
-		if err = os.WriteFile(getConfigPath(p), bytes, fileMode); err != nil {
-			log.Printf("plugin config writing failed (%s): %s\n", p.Name, err)
-		} else {
-			log.Printf("saved config for %s\n", p.Name)
-		}
+		saved = true
+		return os.WriteFile(getConfigPath(p), bytes, fileMode)
+	})
+
+	if err != nil {
+		p.Log().Error("plugin config saving failed", "err", err)
+	} else if saved {
+		p.Log().Debug("saved plugin config")
 	}
 }
 
 // Startup will run the startup function for all plugins
 func Startup() {
-	for _, p := range plugins {
+	for _, p := range loadedPlugins() {
 		if p.StartupFn != nil {
 			p.StartupFn()
 		}
@@ -108,7 +156,7 @@ func Startup() {
 
 // Shutdown will run the shutdown function for all plugins
 func Shutdown() {
-	for _, p := range plugins {
+	for _, p := range loadedPlugins() {
 		if p.ShutdownFn != nil {
 			p.ShutdownFn()
 		}
@@ -117,7 +165,7 @@ func Shutdown() {
 
 // SaveConfig will save all plugin configs
 func SaveConfig() {
-	for _, p := range plugins {
+	for _, p := range loadedPlugins() {
 		p.SaveConfig()
 	}
 }
@@ -139,56 +187,106 @@ func SetupConfigSaving() {
 func Load(dir string) {
 	d, err := ioutil.ReadDir(dir)
 	if err != nil {
-		log.Printf("plugin loading failed: couldn't load dir: %s\n", err)
+		slog.Error("plugin loading failed: couldn't load dir", "dir", dir, "err", err)
 		return
 	}
 
 	plugins := parsePluginsList()
 
-	log.Printf("plugin list: [%s]\n", strings.Join(plugins, ", "))
+	slog.Info("plugin list", "plugins", plugins)
 
+	found := make([]string, 0)
 	for _, entry := range d {
-		func() {
-			defer util.LogPanic() // plugins can panic when returning their PluginInit
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".so") || !util.SliceContains(plugins, entry.Name()) {
+			continue
+		}
 
-			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".so") && util.SliceContains(plugins, entry.Name()) {
-				pluginPath := filepath.Join(dir, entry.Name())
-				log.Printf("plugin found: %s\n", entry.Name())
+		found = append(found, entry.Name())
+		status := &Status{Name: strings.TrimSuffix(entry.Name(), ".so")}
+		addStatus(status)
 
-				p, err := plugin.Open(pluginPath)
-				if err != nil {
-					log.Printf("plugin load failed: couldn't open plugin: %s (%s)\n", entry.Name(), err)
-					return
+		func() {
+			// plugins can panic when returning their PluginInit
+			defer func() {
+				if x := recover(); x != nil {
+					slog.Error("panic while initializing plugin", "plugin", status.Name, "panic", x, "stack", string(debug.Stack()))
+					status.fail("panicked while initializing: %v", x)
 				}
+			}()
+
+			pluginPath := filepath.Join(dir, entry.Name())
+			slog.Debug("plugin found", "file", entry.Name())
+
+			// Check the manifest before opening the plugin, as plugin.Open can't be undone and gives an unhelpful error
+			// when the plugin was built against a different version of the bot.
+			manifest, err := LoadManifest(dir, status.Name)
+			if err != nil {
+				status.fail("couldn't read manifest: %s", err)
+				slog.Warn("plugin load failed", "plugin", status.Name, "state", status.State, "reason", status.Reason)
+				return
+			}
+			status.Manifest = manifest
 
-				fn, err := p.Lookup("InitPlugin")
-				if err != nil {
-					log.Printf("plugin load failed: couldn't lookup symbols: %s (%s)\n", entry.Name(), err)
-					return
-				}
+			if err := manifest.Validate(status.Name); err != nil {
+				status.fail("incompatible manifest: %s", err)
+				slog.Warn("plugin load failed", "plugin", status.Name, "state", status.State, "reason", status.Reason)
+				return
+			}
+			manifest.validateConfig(status.Name, status)
+
+			p, err := plugin.Open(pluginPath)
+			if err != nil {
+				status.fail("couldn't open plugin: %s", err)
+				slog.Warn("plugin load failed", "plugin", status.Name, "state", status.State, "reason", status.Reason)
+				return
+			}
+
+			fn, err := p.Lookup("InitPlugin")
+			if err != nil {
+				status.fail("couldn't lookup symbols: %s", err)
+				slog.Warn("plugin load failed", "plugin", status.Name, "state", status.State, "reason", status.Reason)
+				return
+			}
+
+			// Create the init function to execute, to attempt plugin registration.
+			initFn, ok := fn.(func(manager *PluginInit) *Plugin)
+			if !ok {
+				status.fail("InitPlugin has the wrong signature: %T", fn)
+				slog.Warn("plugin load failed", "plugin", status.Name, "state", status.State, "reason", status.Reason)
+				return
+			}
+
+			// Pass the ConfigDir to the PluginInit, so plugins can access it while loading their initial config.
+			// This requires an extra step on the user's part when writing a plugin, but the plugin loading will fail
+			// and let the user know if they forgot to do so. This isn't ideal, but it allows the renaming of plugin
+			// names, without breaking the config or relying on parsing to be consistent.
+			pluginInit := &PluginInit{ConfigDir: status.Name}
 
-				if fn == nil {
-					log.Printf("plugin load failed: fn nil\n")
+			if p := initFn(pluginInit); p != nil {
+				if err := manifest.validatePlugin(p); err != nil {
+					status.fail("incompatible manifest: %s", err)
+					slog.Warn("plugin load failed", "plugin", status.Name, "state", status.State, "reason", status.Reason)
 					return
 				}
 
-				// Pass the ConfigDir to the PluginInit, so plugins can access it while loading their initial config.
-				// This requires an extra step on the user's part when writing a plugin, but the plugin loading will fail
-				// and let the user know if they forgot to do so. This isn't ideal, but it allows the renaming of plugin
-				// names, without breaking the config or relying on parsing to be consistent.
-				pluginInit := &PluginInit{ConfigDir: strings.TrimSuffix(entry.Name(), ".so")}
-				// Create the init function to execute, to attempt plugin registration.
-				initFn := fn.(func(manager *PluginInit) *Plugin)
-
-				if p := initFn(pluginInit); p != nil {
-					p.Register()
-					log.Printf("plugin registered: %s\n", p)
-				} else {
-					log.Printf("plugin load failed: %s (nil)\n", entry.Name())
-				}
+				p.pkgPath = funcPkgPath(initFn)
+				p.Register()
+				status.State = StateLoaded
+				p.Log().Info("plugin registered", "name", p.Name, "version", p.Version)
+			} else {
+				status.fail("InitPlugin returned nil")
+				slog.Warn("plugin load failed", "plugin", status.Name, "state", status.State, "reason", status.Reason)
 			}
 		}()
 	}
+
+	for _, p := range plugins {
+		if !util.SliceContains(found, p) {
+			status := &Status{Name: strings.TrimSuffix(p, ".so"), State: StateMissing, Reason: "not found in " + dir}
+			addStatus(status)
+			slog.Warn("plugin load failed", "plugin", status.Name, "state", status.State, "reason", status.Reason)
+		}
+	}
 }
 
 // ClearJobs will clear all registered jobs
@@ -207,9 +305,10 @@ func RegisterJobs() {
 // RegisterJob registers a job for use with gocron. Ensure you add the job to bot.Jobs for de-registration with ClearJobs.
 func RegisterJob(job bot.JobInfo) {
 	if rJob, err := job.Fn(); err != nil {
-		log.Printf("failed to register job (%s): %v\n", job.Name, err)
+		slog.Error("failed to register job", "job", job.Name, "err", err)
 	} else {
-		log.Printf("registered job (%s): %v\n", job.Name, rJob)
+		rJob.SetEventListeners(func() { bot.JobRunsTotal.WithLabelValues(job.Name).Inc() }, nil)
+		slog.Debug("registered job", "job", job.Name, "next", rJob.NextRun())
 	}
 }
 
@@ -249,32 +348,33 @@ func RegisterHandlers() {
 		// Believe me, I tried doing so with reflection and got nothing to show for it after 5 hours.
 		// If this behavior changes as Go finally figures out their situation with generics, that would be
 		// nice to implement here, as a consideration for the future.
+		// Handlers are tracked, so that shutting down waits for them and stops new events from being handled
 		switch handler.FnType {
 		case reflect.TypeOf(func(e *gateway.MessageReactionAddEvent) {}):
 			fn = func(e *gateway.MessageReactionAddEvent) {
-				handler.Fn(e)
+				bot.Track(func() { handler.Fn(e) })
 			}
 		case reflect.TypeOf(func(e *gateway.MessageReactionRemoveEvent) {}):
 			fn = func(e *gateway.MessageReactionRemoveEvent) {
-				handler.Fn(e)
+				bot.Track(func() { handler.Fn(e) })
 			}
 		case reflect.TypeOf(func(e *gateway.GuildMemberAddEvent) {}):
 			fn = func(e *gateway.GuildMemberAddEvent) {
-				handler.Fn(e)
+				bot.Track(func() { handler.Fn(e) })
 			}
 		case reflect.TypeOf(func(e *gateway.GuildMemberRemoveEvent) {}):
 			fn = func(e *gateway.GuildMemberRemoveEvent) {
-				handler.Fn(e)
+				bot.Track(func() { handler.Fn(e) })
 			}
 		default:
-			log.Printf("failed to register handler (%s): type %v not recognized\n", handler.FnName, handler.FnType)
+			slog.Error("failed to register handler: type not recognized", "handler", handler.FnName, "type", handler.FnType)
 			continue
 		}
 
 		if fn != nil {
 			rm := bot.Client.AddHandler(fn)
 			bot.Handlers[n].FnRm = rm
-			log.Printf("registered handler: %v\n", bot.Handlers[n])
+			slog.Debug("registered handler", "handler", handler.FnName)
 		}
 	}
 }
@@ -284,29 +384,88 @@ func RegisterAll(dir string) {
 	bot.Mutex.Lock()
 	defer bot.Mutex.Unlock()
 
+	// This registers the plugins we have downloaded
+	// This does not build new plugins for us, which instead has to be done separately
+	register(func() {
+		Load(dir)
+	})
+
+	// This enables config saving for all loaded plugins
+	SetupConfigSaving()
+
+	// This runs the startup sequence for all loaded plugins that have it
+	Startup()
+
+	loaded.Store(true)
+}
+
+// RegisterPlugins will register all bot features like RegisterAll, with ps instead of the plugins in a dir.
+// Their configs are never saved, so this is used to test plugins.
+func RegisterPlugins(ps ...*Plugin) {
+	bot.Mutex.Lock()
+	defer bot.Mutex.Unlock()
+
+	register(func() {
+		for _, p := range ps {
+			p.Register()
+			p.Log().Info("plugin registered", "name", p.Name, "version", p.Version)
+		}
+	})
+
+	Startup()
+}
+
+// register will clear the registered plugins, call load to register new ones, and then register their features
+func register(load func()) {
 	// This is done to clear the existing plugins that have already been registered, if this is called after the bot
 	// has already been initialized. This allows reloading plugins at runtime.
+	pluginsMutex.Lock()
 	plugins = make([]*Plugin, 0)
+	pluginsMutex.Unlock()
+	clearStatuses()
 	bot.Commands = make([]bot.CommandInfo, 0)
 	bot.Responses = make([]bot.ResponseInfo, 0)
+	bot.Components = make([]bot.ComponentInfo, 0)
 
 	// We want to do this before registering plugins
 	ClearHandlers()
 	ClearJobs()
+	bot.ClearDurableJobHandlers()
 
-	// This registers the plugins we have downloaded
-	// This does not build new plugins for us, which instead has to be done separately
-	Load(dir)
+	load()
 
-	// This registers the new jobs that plugins have scheduled, and the handlers that they return
+	// This registers the new jobs that plugins have scheduled, and the handlers that they return.
+	// Panics in jobs happen inside gocron, so they are attributed to their plugin by jobPanicHandler instead of wrap.
+	gocron.SetPanicHandler(jobPanicHandler)
 	RegisterHandlers()
 	RegisterJobs()
 
-	// This enables config saving for all loaded plugins
-	SetupConfigSaving()
+	// Commands are indexed and responses are compiled once here, instead of for every message
+	cmd.IndexCommands()
+	cmd.CompileResponses()
+}
 
-	// This runs the startup sequence for all loaded plugins that have it
-	Startup()
+// loadedPlugins will return a copy of the currently registered plugins
+func loadedPlugins() []*Plugin {
+	pluginsMutex.Lock()
+	defer pluginsMutex.Unlock()
+
+	return append(make([]*Plugin, 0, len(plugins)), plugins...)
+}
+
+// Loaded will return the loaded plugins, in the order they were loaded
+func Loaded() []*Plugin {
+	return loadedPlugins()
+}
+
+// Find will return the loaded plugin with a matching ConfigDir, or nil
+func Find(name string) *Plugin {
+	for _, p := range loadedPlugins() {
+		if p.ConfigDir == name {
+			return p
+		}
+	}
+	return nil
 }
 
 func parsePluginsList() []string {
@@ -335,5 +494,9 @@ func parsePluginsList() []string {
 }
 
 func getConfigPath(p *Plugin) string {
-	return fmt.Sprintf("config/%s/%s.json", p.ConfigDir, p.Version)
+	return configPath(p.ConfigDir, p.Version)
+}
+
+func configPath(configDir, version string) string {
+	return fmt.Sprintf("config/%s/%s.json", configDir, version)
 }
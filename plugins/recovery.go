@@ -0,0 +1,315 @@
+package plugins
+
+import (
+	"fmt"
+	"github.com/5HT2/taro-bot/bot"
+	"github.com/5HT2/taro-bot/cmd"
+	"github.com/5HT2/taro-bot/util"
+	"github.com/go-co-op/gocron"
+	"log/slog"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	DefaultPanicLimit  = 5
+	DefaultPanicWindow = int64(600)
+)
+
+// failures keeps track of the recent panics of a Plugin, and if it has been disabled because of them
+type failures struct {
+	mutex    sync.Mutex
+	panics   []time.Time
+	total    int64
+	disabled bool
+}
+
+// Disabled will return if the plugin has been disabled for panicking too often
+func (p *Plugin) Disabled() bool {
+	p.failures.mutex.Lock()
+	defer p.failures.mutex.Unlock()
+	return p.failures.disabled
+}
+
+// Panics will return the total number of times the plugin has panicked since it was loaded
+func (p *Plugin) Panics() int64 {
+	p.failures.mutex.Lock()
+	defer p.failures.mutex.Unlock()
+	return p.failures.total
+}
+
+// Enable will re-enable a plugin that was disabled for panicking, and re-register its jobs
+func Enable(name string) error {
+	p := Find(name)
+	if p == nil {
+		return bot.NotFoundError("Enable", "enabling plugin", "no loaded plugin named `"+name+"`")
+	}
+
+	p.failures.mutex.Lock()
+	wasDisabled := p.failures.disabled
+	p.failures.disabled = false
+	p.failures.panics = make([]time.Time, 0)
+	p.failures.mutex.Unlock()
+
+	if !wasDisabled {
+		return bot.GenericError("Enable", "enabling plugin", "`"+name+"` is not disabled")
+	}
+
+	for _, job := range p.Jobs {
+		RegisterJobConcurrent(job, false)
+	}
+
+	p.Log().Info("re-enabled plugin")
+	return nil
+}
+
+// recordPanic will log a panic, attribute it to the plugin, notify the operator channel and disable the plugin if it
+// has panicked more than bot.P.PanicLimit times in the last bot.P.PanicWindow seconds.
+func (p *Plugin) recordPanic(fnName string, x any, stack []byte) {
+	p.Log().ErrorContext(bot.Reported, "panic in plugin", "fn", fnName, "panic", x, "stack", string(stack))
+	bot.ReportError(bot.ErrorReport{
+		Source:  "panic",
+		Plugin:  p.ConfigDir,
+		Title:   fmt.Sprintf("Panic in %s (`%s`)", p.Name, fnName),
+		Message: fmt.Sprint(x),
+		Detail:  string(stack),
+	})
+
+	limit, window := panicLimits()
+	now := time.Now()
+
+	p.failures.mutex.Lock()
+	recent := make([]time.Time, 0)
+	for _, t := range p.failures.panics {
+		if now.Sub(t) < time.Duration(window)*time.Second {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, now)
+	p.failures.panics = recent
+	p.failures.total++
+
+	disable := !p.failures.disabled && limit > 0 && len(recent) >= limit
+	if disable {
+		p.failures.disabled = true
+	}
+	p.failures.mutex.Unlock()
+
+	if disable {
+		if err := bot.Scheduler.RemoveByTag(p.jobTag()); err != nil && err != gocron.ErrJobNotFoundWithTag {
+			p.Log().Error("failed to remove jobs", "err", err)
+		}
+
+		p.Log().Warn("disabled plugin", "panics", len(recent), "window", util.FormattedTime(window))
+		bot.NotifyOperators(cmd.MakeEmbed(
+			"Disabled "+p.Name,
+			fmt.Sprintf("`%s` panicked %s in %s, and has been disabled.\nUse `plugins enable %s` to enable it again.",
+				p.ConfigDir, util.JoinIntAndStr(len(recent), "time"), util.FormattedTime(window), p.ConfigDir),
+			bot.WarnColor,
+		))
+	}
+}
+
+// wrap will make each Fn of the plugin recover from panics and stop running while the plugin is disabled
+func (p *Plugin) wrap() {
+	for n, i := range p.Commands {
+		fn, fnName := i.Fn, i.FnName
+		if fn == nil {
+			continue
+		}
+
+		p.Commands[n].Plugin = p.ConfigDir
+		p.Commands[n].Fn = func(c bot.Command) (err error) {
+			if p.Disabled() {
+				return bot.GenericError(fnName, "running command", "the `"+p.ConfigDir+"` plugin has been disabled")
+			}
+
+			defer func() {
+				if x := recover(); x != nil {
+					p.recordPanic(fnName, x, debug.Stack())
+					err = bot.InternalError(fnName, "running command", "command panicked, the bot operators have been notified", bot.ErrPanic)
+				}
+			}()
+
+			return fn(c)
+		}
+	}
+
+	for n, i := range p.Responses {
+		fn := i.Fn
+		if fn == nil {
+			continue
+		}
+
+		fnName := fmt.Sprintf("response %s", i.Regexes)
+		p.Responses[n].Plugin = p.ConfigDir
+		p.Responses[n].Fn = func(r bot.Response) {
+			if p.Disabled() {
+				return
+			}
+
+			defer func() {
+				if x := recover(); x != nil {
+					p.recordPanic(fnName, x, debug.Stack())
+				}
+			}()
+
+			r.Plugin = p.ConfigDir
+			fn(r)
+		}
+	}
+
+	for n, i := range p.Components {
+		fn, fnName := i.Fn, i.FnName
+		if fn == nil {
+			continue
+		}
+
+		p.Components[n].Fn = func(c bot.Component) (err error) {
+			if p.Disabled() {
+				return bot.GenericError(fnName, "running component", "the `"+p.ConfigDir+"` plugin has been disabled")
+			}
+
+			defer func() {
+				if x := recover(); x != nil {
+					p.recordPanic(fnName, x, debug.Stack())
+					err = bot.InternalError(fnName, "running component", "component panicked, the bot operators have been notified", bot.ErrPanic)
+				}
+			}()
+
+			return fn(c)
+		}
+	}
+
+	for n, i := range p.Handlers {
+		fn, fnName := i.Fn, i.FnName
+		if fn == nil {
+			continue
+		}
+
+		p.Handlers[n].Fn = func(e interface{}) {
+			if p.Disabled() {
+				return
+			}
+
+			bot.HandlersTotal.WithLabelValues(fnName, p.ConfigDir).Inc()
+
+			defer func() {
+				if x := recover(); x != nil {
+					p.recordPanic(fnName, x, debug.Stack())
+				}
+			}()
+
+			fn(e)
+		}
+	}
+
+	for n, i := range p.DurableJobs {
+		fn, kind := i.Fn, i.Kind
+		if fn == nil {
+			continue
+		}
+
+		p.DurableJobs[n].Fn = func(job bot.DurableJob) (err error) {
+			if p.Disabled() {
+				return bot.ErrJobNotReady
+			}
+
+			defer func() {
+				if x := recover(); x != nil {
+					p.recordPanic("durable job "+kind, x, debug.Stack())
+					err = bot.InternalError(kind, "running durable job", "job panicked", bot.ErrPanic)
+				}
+			}()
+
+			return fn(job)
+		}
+	}
+
+	for n, i := range p.Jobs {
+		fn, name := i.Fn, i.Name
+		if fn == nil {
+			continue
+		}
+
+		// The job's function itself is run by gocron, see jobPanicHandler
+		p.Jobs[n].Fn = func() (job *gocron.Job, err error) {
+			if p.Disabled() {
+				return nil, bot.GenericError(name, "registering job", "the `"+p.ConfigDir+"` plugin has been disabled")
+			}
+
+			defer func() {
+				if x := recover(); x != nil {
+					p.recordPanic(name, x, debug.Stack())
+					err = bot.InternalError(name, "registering job", "job panicked", bot.ErrPanic)
+				}
+			}()
+
+			if job, err = fn(); job != nil {
+				job.Tag(p.jobTag())
+			}
+			return job, err
+		}
+	}
+}
+
+// jobPanicHandler is given to gocron, and attributes panics in scheduled jobs to the plugin that defined the job function
+func jobPanicHandler(jobName string, x interface{}) {
+	stack := debug.Stack()
+
+	for _, p := range loadedPlugins() {
+		if len(p.pkgPath) > 0 && strings.HasPrefix(jobName, p.pkgPath+".") {
+			p.recordPanic("job "+strings.TrimPrefix(jobName, p.pkgPath+"."), x, stack)
+			return
+		}
+	}
+
+	slog.ErrorContext(bot.Reported, "panic in job", "job", jobName, "panic", x, "stack", string(stack))
+	bot.ReportError(bot.ErrorReport{
+		Source:  "panic",
+		Title:   fmt.Sprintf("Panic in job `%s`", jobName),
+		Message: fmt.Sprint(x),
+		Detail:  string(stack),
+	})
+}
+
+// jobTag is used to tag the plugin's jobs, so they can be removed when the plugin is disabled
+func (p *Plugin) jobTag() string {
+	return "plugin-" + p.ConfigDir
+}
+
+// funcPkgPath will return the package path that fn was declared in.
+// Plugins are all built as `package main`, so this is used to tell their functions apart.
+func funcPkgPath(fn any) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return ""
+	}
+
+	name := f.Name()
+	// The package path may contain dots in the last element, so we have to look for the first one after the last slash
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot != -1 {
+		return name[:slash+1+dot]
+	}
+	return name
+}
+
+func panicLimits() (int, int64) {
+	limit, window := DefaultPanicLimit, DefaultPanicWindow
+
+	bot.P.Mutex.Lock()
+	defer bot.P.Mutex.Unlock()
+
+	if bot.P.PanicLimit != 0 {
+		limit = bot.P.PanicLimit
+	}
+	if bot.P.PanicWindow > 0 {
+		window = bot.P.PanicWindow
+	}
+	return limit, window
+}
@@ -1,27 +1,29 @@
 package main
 
 import (
+	"embed"
 	"fmt"
 	"github.com/5HT2/taro-bot/bot"
 	"github.com/5HT2/taro-bot/cmd"
 	"github.com/5HT2/taro-bot/plugins"
 	"github.com/diamondburned/arikawa/v3/discord"
-	"github.com/go-co-op/gocron"
-	"log"
-	"reflect"
-	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
 var (
 	p     *plugins.Plugin
-	mutex sync.Mutex
+	store = plugins.NewStore[config](nil)
+
+	//go:embed locales/*.json
+	locales embed.FS
 )
 
+const reminderKind = "remindme"
+
 type config struct {
-	Reminders map[string]Reminder `json:"reminders"` // [msg id]Reminder
+	// Reminders used to be saved in the config, they are now durable jobs and are only read here to migrate them
+	Reminders map[string]Reminder `json:"reminders,omitempty" taro:"hidden"` // [msg id]Reminder
 }
 
 type Reminder struct {
@@ -44,18 +46,24 @@ func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
 		Name:        "Remind Me",
 		Description: "Set a reminder for yourself at a later date!",
 		Version:     "1.0.0",
+		Locales:     locales,
 		Commands: []bot.CommandInfo{{
 			Fn:          RemindMeCommand,
 			FnName:      "RemindMeCommand",
 			Name:        "remindme",
 			Aliases:     []string{"remind", "r"},
 			Description: "Set a reminder for yourself!",
+			Usage:       "<duration> [reminder]",
+		}},
+		Config: store,
+		DurableJobs: []bot.DurableJobHandlerInfo{{
+			Fn:   SendReminder,
+			Kind: reminderKind,
 		}},
-		ConfigType: reflect.TypeOf(config{}),
 	}
 	p.ConfigDir = i.ConfigDir
-	p.Config = p.LoadConfig()
-	p.Jobs = generateJobs() // even if the plugin is reloaded, InitPlugin should re-add the reminders with this
+	p.LoadConfig()
+	migrateReminders()
 	return p
 }
 
@@ -72,7 +80,7 @@ func RemindMeCommand(c bot.Command) error {
 	args, _ := cmd.ParseStringSliceArg(c.Args, 2, -1)
 	content := strings.Join(args, " ")
 	if len(args) == 0 {
-		content = "No reminder message set!"
+		content = c.T("No reminder message set!")
 	}
 
 	t := time.Now().Add(duration)
@@ -87,89 +95,80 @@ func RemindMeCommand(c bot.Command) error {
 		Contents:  content,
 	}
 
-	createAndRegisterReminder(reminder)
+	if err := scheduleReminder(reminder); err != nil {
+		return err
+	}
 
 	_, err1 := cmd.SendEmbed(
 		c.E,
 		p.Name,
-		fmt.Sprintf("Successfully created reminder for <t:%v:R>, you will be reminded on <t:%v:F>!", t.Unix(), t.Unix()),
+		c.T("Successfully created reminder for <t:%v:R>, you will be reminded on <t:%v:F>!", t.Unix(), t.Unix()),
 		bot.SuccessColor,
 	)
 	return err1
 }
 
-// createAndRegisterReminder will create a job from a Reminder and register it right away
-func createAndRegisterReminder(r Reminder) {
-	job := createJob(r)
-	id := strconv.FormatInt(r.ID, 10)
-
-	if p.Config == nil || p.Config.(config).Reminders == nil {
-		reminders := make(map[string]Reminder, 0)
-		reminders[id] = r
-		cfg := config{Reminders: reminders}
-		p.Config = cfg
-	} else {
-		p.Config.(config).Reminders[id] = r
+// scheduleReminder will schedule a Reminder as a durable job, so it is kept when the bot restarts
+func scheduleReminder(r Reminder) error {
+	job, err := bot.NewDurableJob(fmt.Sprintf("remindme-%v", r.ID), reminderKind, r.Time, r)
+	if err != nil {
+		return err
 	}
 
-	plugins.RegisterJobConcurrent(job, true)
+	return bot.ScheduleDurableJob(job)
 }
 
-// generateJobs will generate the initial jobs needed from a plugin load
-func generateJobs() []bot.JobInfo {
-	if p.Config == nil || p.Config.(config).Reminders == nil {
-		return []bot.JobInfo{}
+// migrateReminders will schedule the reminders that were saved in the config, before reminders were durable jobs
+func migrateReminders() {
+	reminders := store.Get().Reminders
+	if len(reminders) == 0 {
+		return
 	}
 
-	jobs := make([]bot.JobInfo, 0)
-
-	for _, reminder := range p.Config.(config).Reminders {
-		jobs = append(jobs, createJob(reminder))
-	}
+	store.Update(func(c *config) {
+		for id, r := range c.Reminders {
+			if err := scheduleReminder(r); err != nil {
+				p.Log().Error("failed to migrate reminder", "reminder", r, "err", err)
+				continue
+			}
 
-	return jobs
-}
-
-func createJob(r Reminder) bot.JobInfo {
-	fn := func() {
-		mutex.Lock()
-		defer mutex.Unlock()
-
-		field := discord.EmbedField{Name: "Source", Value: cmd.CreateMessageLinkInt64(r.Guild, r.ID, r.Channel, true, r.DM)}
-		footer := discord.EmbedFooter{Text: r.User.ID.String()}
-		embed := &discord.Embed{
-			Description: r.Contents,
-			Author:      cmd.CreateEmbedAuthorUser(r.User),
-			Fields:      []discord.EmbedField{field},
-			Footer:      &footer,
-			Timestamp:   r.Timestamp,
-			Color:       bot.BlueColor,
+			delete(c.Reminders, id)
 		}
+	})
+}
 
-		var err error
+// SendReminder is the durable job handler that delivers a Reminder
+func SendReminder(job bot.DurableJob) error {
+	var r Reminder
+	if err := job.Unmarshal(&r); err != nil {
+		p.Log().Error("failed to unmarshal reminder", "job", job.Key, "err", err)
+		return nil // this won't work if it is retried
+	}
 
-		if r.DM {
-			_, err = cmd.SendDirectMessageEmbedSafe(r.User.ID, fmt.Sprintf("📝 from <#%v>", r.Channel), embed)
-		} else {
-			_, err = cmd.SendMessageEmbedSafe(discord.ChannelID(r.Channel), fmt.Sprintf("📝 from <#%v> <@%v>", r.Channel, r.User.ID), embed)
-		}
+	// Reminders are delivered later, so they use the locale of the user at that time
+	printer := bot.Printer(discord.GuildID(r.Guild), r.User.ID)
+	field := discord.EmbedField{Name: printer.Sprintf("Source"), Value: cmd.CreateMessageLinkInt64(r.Guild, r.ID, r.Channel, true, r.DM)}
+	footer := discord.EmbedFooter{Text: r.User.ID.String()}
+	embed := &discord.Embed{
+		Description: r.Contents,
+		Author:      cmd.CreateEmbedAuthorUser(r.User),
+		Fields:      []discord.EmbedField{field},
+		Footer:      &footer,
+		Timestamp:   r.Timestamp,
+		Color:       bot.BlueColor,
+	}
 
-		if err != nil {
-			log.Printf("failed to deliver reminder: %v\n%s\n", err, r)
-		}
+	var err error
 
-		// Remove after attempting to send reminder
-		if p.Config != nil && p.Config.(config).Reminders != nil {
-			delete(p.Config.(config).Reminders, strconv.FormatInt(r.ID, 10))
-		}
+	if r.DM {
+		_, err = cmd.SendDirectMessageEmbedSafe(r.User.ID, printer.Sprintf("📝 from <#%v>", r.Channel), embed)
+	} else {
+		_, err = cmd.SendMessageEmbedSafe(discord.ChannelID(r.Channel), printer.Sprintf("📝 from <#%v> <@%v>", r.Channel, r.User.ID), embed)
 	}
 
-	job := bot.JobInfo{
-		Fn: func() (*gocron.Job, error) {
-			return bot.Scheduler.Every(1).LimitRunsTo(1).StartAt(r.Time).Do(fn)
-		},
-		Name: fmt.Sprintf("remindme-plugin-schedule-%v", r.ID),
+	if err != nil {
+		p.Log().Error("failed to deliver reminder", "reminder", r, "err", err)
 	}
 
-	return job
+	return err
 }
@@ -10,17 +10,22 @@ import (
 	"github.com/diamondburned/arikawa/v3/api"
 	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/diamondburned/arikawa/v3/gateway"
-	"log"
 	"reflect"
 	"strconv"
 	"strings"
 	"time"
 )
 
-var p *plugins.Plugin
+var (
+	p     *plugins.Plugin
+	store = plugins.NewStore[config](nil)
+	menus = plugins.NewGuildStore(store, func(c *config) *plugins.GuildMap[map[string]Menu] { return &c.Menus }, func() map[string]Menu {
+		return make(map[string]Menu)
+	})
+)
 
 type config struct {
-	Menus map[string]map[string]Menu `json:"menus"` // [guild id][message id]Menu
+	Menus plugins.GuildMap[map[string]Menu] `json:"menus"` // [guild id][message id]Menu
 }
 
 // Menu stores the information needed to operate a role menu
@@ -60,8 +65,9 @@ func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
 			Aliases:     []string{"rmcfg"},
 			Description: "Create a role menu",
 			GuildOnly:   true,
+			Permission:  "moderate",
 		}},
-		ConfigType: reflect.TypeOf(config{}),
+		Config: store,
 		Handlers: []bot.HandlerInfo{{
 			Fn:     RoleMenuReactionAddHandler,
 			FnName: "RoleMenuReactionAddHandler",
@@ -69,7 +75,7 @@ func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
 		}},
 	}
 	p.ConfigDir = i.ConfigDir
-	p.Config = p.LoadConfig()
+	p.LoadConfig()
 	return p
 }
 
@@ -85,7 +91,7 @@ func RoleMenuCommand(c bot.Command) error {
 
 	defaultHelp := func() error {
 		_, err := cmd.SendEmbed(c.E, p.Name,
-			"Available arguments are:\n- `create|add|remove [role json]`\n\n`create` a new role menu\n`add` roles\n`remove` existing roles",
+			c.T("Available arguments are:")+"\n- `create|add|remove [role json]`\n\n`create` a new role menu\n`add` roles\n`remove` existing roles",
 			bot.DefaultColor)
 		return err
 	}
@@ -152,30 +158,22 @@ func RoleMenuCommand(c bot.Command) error {
 
 	getMenu := func(c bot.Command, rc RoleConfig) (*Menu, error) {
 		var menu *Menu
-		if p.Config != nil {
-			if guild, ok := p.Config.(config).Menus[c.E.GuildID.String()]; ok {
-				if m, ok := guild[rc.ID]; ok {
-					menu = &m
-				}
+		menus.View(c.E.GuildID.String(), func(guild map[string]Menu) {
+			if m, ok := guild[rc.ID]; ok {
+				menu = &m
 			}
-		}
+		})
 
 		if menu == nil {
-			return nil, bot.GenericError(c.FnName, "getting existing role menu", "none found")
+			return nil, bot.NotFoundError(c.FnName, "getting existing role menu", "none found")
 		}
 		return menu, nil
 	}
 
 	setMenu := func(c bot.Command, rc RoleConfig, m Menu) {
-		if p.Config != nil {
-			p.Config.(config).Menus[c.E.GuildID.String()][rc.ID] = m
-		} else {
-			menus := make(map[string]map[string]Menu, 0)
-			msgMenu := make(map[string]Menu)
-			msgMenu[rc.ID] = m
-			menus[c.E.GuildID.String()] = msgMenu
-			p.Config = config{Menus: menus}
-		}
+		menus.Update(c.E.GuildID.String(), func(guild *map[string]Menu) {
+			(*guild)[rc.ID] = m
+		})
 	}
 
 	switch firstArg {
@@ -227,7 +225,7 @@ func RoleMenuCommand(c bot.Command) error {
 		for n, parsedEmoji := range roleConfig.Roles {
 			apiEmoji, _ := bot.EmojiConfigAsApi(parsedEmoji.Emoji)
 			if err := bot.Client.React(discord.ChannelID(roleConfig.ChannelID), discord.MessageID(roleConfig.MessageID), apiEmoji); err != nil {
-				log.Printf("failed to react when creating role menu: %v\n", err)
+				p.Log().Error("failed to react when creating role menu", "err", err)
 			}
 
 			if n < len(roleConfig.Roles)-1 {
@@ -286,7 +284,7 @@ func RoleMenuCommand(c bot.Command) error {
 		for n, parsedEmoji := range roleConfig.Roles {
 			apiEmoji, _ := bot.EmojiConfigAsApi(parsedEmoji.Emoji)
 			if err := bot.Client.Unreact(discord.ChannelID(roleConfig.ChannelID), discord.MessageID(roleConfig.MessageID), apiEmoji); err != nil {
-				log.Printf("failed to unreact when creating role menu: %v\n", err)
+				p.Log().Error("failed to unreact when creating role menu", "err", err)
 			}
 
 			if n < len(roleConfig.Roles)-1 {
@@ -315,28 +313,17 @@ func RoleMenuCommand(c bot.Command) error {
 				//
 				// Save final menu in config
 
-				menus := make(map[string]map[string]Menu, 0)
-				if p.Config != nil {
-					menus = p.Config.(config).Menus // copy over the menus for other builds and our current guild
-				}
-
 				createdMenu := Menu{Channel: int64(c.E.ChannelID), Roles: roles}
 
-				if _, ok := menus[c.E.GuildID.String()]; ok {
-					menus[c.E.GuildID.String()][msg.ID.String()] = createdMenu
-				} else {
-					messageMenu := make(map[string]Menu)
-					messageMenu[msg.ID.String()] = createdMenu
-					menus[c.E.GuildID.String()] = messageMenu
-				}
-
-				p.Config = config{Menus: menus}
+				menus.Update(c.E.GuildID.String(), func(guild *map[string]Menu) {
+					(*guild)[msg.ID.String()] = createdMenu
+				})
 
 				// Add reactions to menu
 				for parsedEmoji := range roles {
 					apiEmoji, _ := bot.EmojiConfigAsApi(parsedEmoji)
 					if err := bot.Client.React(msg.ChannelID, msg.ID, apiEmoji); err != nil {
-						log.Printf("failed to react when creating role menu: %v\n", err)
+						p.Log().Error("failed to react when creating role menu", "err", err)
 					}
 					time.Sleep(750 * time.Millisecond) // We want to wait for the actual rate-limit, but Arikawa does not handle that for you
 				}
@@ -363,7 +350,6 @@ func RoleMenuCommand(c bot.Command) error {
 }
 
 func RoleMenuReactionAddHandler(i interface{}) {
-	defer util.LogPanic()
 	e := i.(*gateway.MessageReactionAddEvent)
 
 	// Don't modify bots / self
@@ -378,33 +364,29 @@ func RoleMenuReactionAddHandler(i interface{}) {
 
 	// Remove role if user already has it
 	if util.SliceContains(e.Member.RoleIDs, discord.RoleID(roleID)) {
-		log.Printf("trying to remove role (toggle): %v (%s)\n", roleID, auditLogReason)
+		p.Log().Debug("trying to remove role (toggle)", "guild", e.GuildID, "user", e.UserID, "role", roleID, "reason", auditLogReason)
 
 		if err := bot.Client.RemoveRole(e.GuildID, e.UserID, discord.RoleID(roleID), auditLogReason); err != nil {
-			log.Printf("failed to remove reaction role (toggle): %v\n", err)
+			p.Log().Error("failed to remove reaction role (toggle)", "guild", e.GuildID, "role", roleID, "err", err)
 		}
 		return
 	}
 
 	// Otherwise, we add the role
-	log.Printf("trying to add role: %v (%s)\n", roleID, auditLogReason)
+	p.Log().Debug("trying to add role", "guild", e.GuildID, "user", e.UserID, "role", roleID, "reason", auditLogReason)
 
 	if err := bot.Client.AddRole(e.GuildID, e.UserID, discord.RoleID(roleID), api.AddRoleData{AuditLogReason: auditLogReason}); err != nil {
-		log.Printf("failed to add reaction role: %v\n", err)
+		p.Log().Error("failed to add reaction role", "guild", e.GuildID, "role", roleID, "err", err)
 	}
 }
 
 func getRoleFromEvent(id discord.GuildID, messageID discord.MessageID, channelID discord.ChannelID, emoji discord.Emoji, add bool) (int64, api.AuditLogReason) {
-	if p.Config == nil {
-		return -1, "" // Not configured
-	}
-
-	roleMenus, ok := p.Config.(config).Menus[id.String()]
-	if !ok {
-		return -1, "" // No Menu configured
-	}
+	var menu Menu
+	ok := false
+	menus.View(id.String(), func(guild map[string]Menu) {
+		menu, ok = guild[messageID.String()]
+	})
 
-	menu, ok := roleMenus[messageID.String()]
 	if !ok {
 		return -1, "" // Reacted message does not have a Menu
 	}
@@ -10,7 +10,6 @@ import (
 	"github.com/5HT2/taro-bot/util"
 	"github.com/go-co-op/gocron"
 	"golang.org/x/net/html"
-	"log"
 	"net/http"
 	"net/url"
 	"path"
@@ -22,7 +21,7 @@ import (
 
 var (
 	p                 *plugins.Plugin
-	spotifyBaseUrl    = `https://open.spotify.com/track/`
+	store             = plugins.NewStore(defaultConfig)
 	spotifyRegex      = regexp.MustCompile(`https?://open\.spotify\.com/track/[a-zA-Z\d]\S{2,}`)
 	spotifyTitleRegex = regexp.MustCompile(`(.*) - song( and lyrics)? by (.*) \\\| Spotify`)
 
@@ -30,7 +29,19 @@ var (
 	cachedResults = make(map[string]string, 0) // [spotify ID]YouTube ID
 )
 
-func InitPlugin(_ *plugins.PluginInit) *plugins.Plugin {
+type config struct {
+	SpotifyUrl   string `json:"spotify_url" taro:"operator"`   // SpotifyUrl is where track pages are requested from
+	InstancesUrl string `json:"instances_url" taro:"operator"` // InstancesUrl is the list of Invidious instances, see updateInstances
+}
+
+func defaultConfig() config {
+	return config{
+		SpotifyUrl:   "https://open.spotify.com",
+		InstancesUrl: "https://api.invidious.io",
+	}
+}
+
+func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
 	p = &plugins.Plugin{
 		Name:        "Spotify to YouTube",
 		Description: "Turns Spotify links into YouTube links",
@@ -41,17 +52,21 @@ func InitPlugin(_ *plugins.PluginInit) *plugins.Plugin {
 			Name:        "youtube",
 			Aliases:     []string{"yt"},
 			Description: "Search YouTube for a video!",
+			Usage:       "<video title>",
+			Cooldowns:   []bot.Cooldown{{Scope: bot.CooldownUser, Duration: 5 * time.Second}},
 		}, {
 			Fn:          YoutubeTestCommand,
 			FnName:      "YoutubeTestCommand",
 			Name:        "youtubetest",
 			Aliases:     []string{"ytt"},
 			Description: "Benchmark how long it takes to query Youtube.",
+			Cooldowns:   []bot.Cooldown{{Scope: bot.CooldownGlobal, Duration: 30 * time.Second}},
 		}},
 		Responses: []bot.ResponseInfo{{
-			Fn:       SpotifyToYoutubeResponse,
-			Regexes:  []string{spotifyRegex.String()},
-			MatchMin: 1,
+			Fn:        SpotifyToYoutubeResponse,
+			Regexes:   []string{spotifyRegex.String()},
+			MatchMin:  1,
+			Cooldowns: []bot.Cooldown{{Scope: bot.CooldownChannel, Duration: 3 * time.Second}},
 		}},
 		Jobs: []bot.JobInfo{{
 			Fn: func() (*gocron.Job, error) {
@@ -59,7 +74,10 @@ func InitPlugin(_ *plugins.PluginInit) *plugins.Plugin {
 			},
 			Name: "invidious-instances-update",
 		}},
+		Config: store,
 	}
+	p.ConfigDir = i.ConfigDir
+	p.LoadConfig()
 	return p
 }
 
@@ -130,10 +148,10 @@ func SpotifyToYoutubeResponse(r bot.Response) {
 	}
 
 	spotifyID := path.Base(parsedSpotifyUrl.Path)
-	log.Printf("spotifyID: %s\n\n", spotifyID)
+	r.Log().Debug("found spotify link", "spotify_id", spotifyID)
 
 	if ytID, ok := cachedResults[spotifyID]; ok {
-		log.Printf("spotifyID: found ytID cache: %s\n", ytID)
+		r.Log().Debug("found cached youtube id", "spotify_id", spotifyID, "youtube_id", ytID)
 
 		_, _ = cmd.SendMessage(r.E, "https://youtu.be/"+ytID)
 		return
@@ -142,7 +160,7 @@ func SpotifyToYoutubeResponse(r bot.Response) {
 	// Get Artist and Song Title from Spotify
 	//
 
-	content, resp, err := util.RequestUrl(spotifyBaseUrl+spotifyID, http.MethodGet)
+	content, resp, err := util.RequestUrl(store.Get().SpotifyUrl+"/track/"+spotifyID, http.MethodGet)
 	if err != nil {
 		_, _ = cmd.SendEmbed(r.E, p.Name, "Error: "+err.Error(), bot.ErrorColor)
 		return
@@ -162,7 +180,7 @@ func SpotifyToYoutubeResponse(r bot.Response) {
 
 	text := &bytes.Buffer{}
 	util.ExtractNodeText(node, text)
-	log.Printf("SpotifyToYoutube: text: %s\n", text.String())
+	r.Log().Debug("found spotify title", "title", text.String())
 
 	res := spotifyTitleRegex.FindStringSubmatch(regexp.QuoteMeta(text.String()))
 	if len(res) == 0 {
@@ -170,7 +188,7 @@ func SpotifyToYoutubeResponse(r bot.Response) {
 		return
 	}
 
-	log.Printf("SpotifyToYoutube: res: [%s]\n", strings.Join(res, ", "))
+	r.Log().Debug("parsed spotify title", "matches", res)
 
 	if len(res) != 4 {
 		_, _ = cmd.SendEmbed(r.E, p.Name, "Error: `res` is not 4: `["+strings.Join(res, ", ")+"]`", bot.ErrorColor)
@@ -214,17 +232,17 @@ func queryYoutube(query string, firstRun bool) (*SearchResult, error) {
 		if firstRun {
 			return queryYoutube(query, false)
 		}
-		return nil, bot.GenericError("queryYoutube", "Searching query", "No Invidious instances found")
+		return nil, bot.UpstreamError("queryYoutube", "Searching query", "No Invidious instances found", nil)
 	}
 
-	log.Printf("queryYoutube: searchUrls %s\n", searchUrls)
+	p.Log().Debug("searching youtube", "urls", searchUrls)
 
 	// Query all available search URLs
 	//
 
 	content := util.RequestUrlRetry(searchUrls, http.MethodGet, http.StatusOK)
 	if content == nil {
-		return nil, bot.GenericError("queryYoutube", "Searching `searchUrls`", "nil response received")
+		return nil, bot.UpstreamError("queryYoutube", "Searching `searchUrls`", "No Invidious instances responded", nil)
 	}
 
 	// Parse returned YouTube result
@@ -246,22 +264,22 @@ func queryYoutube(query string, firstRun bool) (*SearchResult, error) {
 		break
 	}
 
-	log.Printf("queryYoutube: searchResult %s\n", searchResult)
+	p.Log().Debug("found youtube search result", "result", searchResult)
 
 	return searchResult, nil
 }
 
 func updateInstances(reason string) {
-	log.Printf("updateInstances: updating because: %s\n", reason)
+	p.Log().Debug("updating invidious instances", "reason", reason)
 
 	getInstancesFn := func() ([]byte, error) {
-		b, _, err := util.RequestUrl("https://api.invidious.io/instances.json?sort_by=users,health", http.MethodGet)
+		b, _, err := util.RequestUrl(store.Get().InstancesUrl+"/instances.json?sort_by=users,health", http.MethodGet)
 		return b, err
 	}
 
 	instancesStr, err := util.RetryFunc(getInstancesFn, 2, 300) // This will take a max of ~16 seconds to execute, with a 5s timeout
 	if err != nil {
-		log.Printf("updateInstances: %v\n", err)
+		p.Log().Error("failed to update invidious instances", "err", err)
 	} else {
 		// We don't want to replace the cache if it errored
 
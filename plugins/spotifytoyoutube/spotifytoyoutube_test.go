@@ -0,0 +1,62 @@
+package main
+
+import (
+	"github.com/5HT2/taro-bot/bot"
+	"github.com/5HT2/taro-bot/cmd/cmdtest"
+	"github.com/5HT2/taro-bot/plugins"
+	"github.com/5HT2/taro-bot/util/fixtures"
+	"strings"
+	"testing"
+)
+
+// newHarness will register the plugin, with its base URLs pointed at the fixtures in testdata
+func newHarness(t *testing.T) *cmdtest.Harness {
+	h := cmdtest.New(t, InitPlugin(&plugins.PluginInit{ConfigDir: "spotifytoyoutube"}))
+
+	spotify := fixtures.NewServer(t, "testdata/spotify", defaultConfig().SpotifyUrl)
+	invidious := fixtures.NewServer(t, "testdata/invidious", defaultConfig().InstancesUrl)
+	store.Update(func(c *config) {
+		c.SpotifyUrl = spotify.URL
+		c.InstancesUrl = invidious.URL
+	})
+
+	instances = nil
+	cachedResults = make(map[string]string, 0)
+	return h
+}
+
+func TestSpotifyToYoutube(t *testing.T) {
+	h := newHarness(t)
+
+	h.Send("listen to https://open.spotify.com/track/4cOdK2wGLETKBW3PvgPWqT?si=abc")
+	if got := h.LastReply().Content; got != "https://youtu.be/dQw4w9WgXcQ" {
+		t.Fatalf("expected the YouTube link, got %q", cmdtest.Text(h.LastReply()))
+	}
+
+	// Only the instance with the API enabled is used
+	if len(instances) != 1 || !strings.HasPrefix(instances[0].URI, "http://127.0.0.1") {
+		t.Errorf("expected one instance from the fixture, got %v", instances)
+	}
+
+	if got := cachedResults["4cOdK2wGLETKBW3PvgPWqT"]; got != "dQw4w9WgXcQ" {
+		t.Errorf("expected the result to be cached, got %q", got)
+	}
+}
+
+func TestSpotifyToYoutubeMissingTrack(t *testing.T) {
+	h := newHarness(t)
+
+	h.Send("https://open.spotify.com/track/0000000000000000000000")
+	if got := cmdtest.Text(h.LastReply()); !strings.Contains(got, "Spotify returned a `404` status code") {
+		t.Errorf("expected the missing track to be an error, got %q", got)
+	}
+}
+
+func TestYoutubeCommand(t *testing.T) {
+	h := newHarness(t)
+
+	h.Send(bot.DefaultPrefix + "youtube never gonna give you up")
+	if got := h.LastReply().Content; got != "https://youtu.be/dQw4w9WgXcQ" {
+		t.Errorf("expected the first video in the search results, got %q", cmdtest.Text(h.LastReply()))
+	}
+}
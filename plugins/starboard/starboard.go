@@ -8,7 +8,6 @@ import (
 	"github.com/5HT2/taro-bot/util"
 	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/diamondburned/arikawa/v3/gateway"
-	"log"
 	"reflect"
 	"sort"
 	"strconv"
@@ -16,6 +15,8 @@ import (
 )
 
 var (
+	p *plugins.Plugin
+
 	escapedStar = "%E2%AD%90"
 	stars3Emoji = "⭐"
 	stars5Emoji = "🌟"
@@ -23,10 +24,12 @@ var (
 	stars9Emoji = "✨"
 
 	starboardColor discord.Color = 0xffac33
+
+	maxTopPosts = 25 // maxTopPosts is the most posts that StarboardTopPostsCommand shows, one on each page
 )
 
-func InitPlugin(_ *plugins.PluginInit) *plugins.Plugin {
-	return &plugins.Plugin{
+func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
+	p = &plugins.Plugin{
 		Name:        "Starboard",
 		Description: "Pin messages to a custom channel",
 		Version:     "1.0.0",
@@ -36,7 +39,9 @@ func InitPlugin(_ *plugins.PluginInit) *plugins.Plugin {
 			Name:        "starboardconfig",
 			Aliases:     []string{"starboardcfg", "scfg"},
 			Description: "Configure Starboard",
+			Usage:       "regular|nsfw|threshold|list [channel|threshold]",
 			GuildOnly:   true,
+			Permission:  "channels",
 		}, {
 			Fn:          StarboardTopPostsCommand,
 			FnName:      "StarboardTopPostsCommand",
@@ -52,6 +57,8 @@ func InitPlugin(_ *plugins.PluginInit) *plugins.Plugin {
 			FnType: reflect.TypeOf(func(*gateway.MessageReactionAddEvent) {}),
 		}},
 	}
+	p.ConfigDir = i.ConfigDir
+	return p
 }
 
 func StarboardTopPostsCommand(c bot.Command) error {
@@ -59,7 +66,7 @@ func StarboardTopPostsCommand(c bot.Command) error {
 	nsfw, argErr := cmd.ParseBoolArg(c.Args, 1)
 	if argErr != nil && len(nsfwArg) > 0 {
 		_, err := cmd.SendEmbed(c.E, c.Name,
-			"Available arguments are:\n- `<show nsfw posts bool>`",
+			c.T("Available arguments are:")+"\n- `<show nsfw posts bool>`",
 			bot.DefaultColor)
 		return err
 	}
@@ -95,14 +102,13 @@ func StarboardTopPostsCommand(c bot.Command) error {
 		return len(posts[i].Stars) > len(posts[j].Stars)
 	})
 
+	if len(posts) > maxTopPosts {
+		posts = posts[:maxTopPosts]
+	}
+
 	embeds := make([]discord.Embed, 0)
-	limit := 0
 
 	for _, p := range posts {
-		limit++
-		if limit > 5 {
-			break
-		}
 
 		var embedAuthor discord.EmbedAuthor
 		if member, err := bot.Client.Member(c.E.GuildID, discord.UserID(p.Author)); err == nil {
@@ -124,7 +130,8 @@ func StarboardTopPostsCommand(c bot.Command) error {
 		embeds = append(embeds, embed)
 	}
 
-	_, err = bot.Client.SendEmbeds(c.E.ChannelID, embeds...)
+	// Each post is shown on its own page
+	_, err = cmd.SendPaginator(c.E, embeds)
 	return err
 }
 
@@ -194,7 +201,7 @@ func StarboardConfigCommand(c bot.Command) error {
 			default:
 				_, err = cmd.SendEmbed(c.E,
 					"Configure Starboard",
-					"Available arguments are:\n- `list`\n- `threshold <threshold>`\n- `nsfw|regular [channel]`",
+					c.T("Available arguments are:")+"\n- `list`\n- `threshold <threshold>`\n- `nsfw|regular [channel]`",
 					bot.DefaultColor)
 				return g, "StarboardConfigCommand: show help"
 			}
@@ -204,10 +211,10 @@ func StarboardConfigCommand(c bot.Command) error {
 }
 
 func StarboardReactionHandler(i interface{}) {
-	defer util.LogPanic()
 
 	e := i.(*gateway.MessageReactionAddEvent)
 	start := time.Now().UnixMilli()
+	logger := p.Log().With("guild", e.GuildID, "channel", e.ChannelID, "message", e.MessageID, "user", e.UserID)
 
 	bot.GuildContext(e.GuildID, func(g *bot.GuildConfig) (*bot.GuildConfig, string) {
 		if g.Starboard.Threshold == 0 {
@@ -216,7 +223,7 @@ func StarboardReactionHandler(i interface{}) {
 
 		// Not starred by a guild member
 		if e.Member == nil {
-			log.Printf("Not a guild member\n")
+			logger.Debug("not a guild member")
 			return g, "StarboardReactionHandler: check guild member"
 		}
 
@@ -238,7 +245,7 @@ func StarboardReactionHandler(i interface{}) {
 		newPost := true
 		cID := int64(channel.ID)
 
-		log.Printf("Checking channel for starboard message %s\n", cmd.CreateMessageLink(int64(e.GuildID), msg, false, false))
+		logger.Debug("checking channel for starboard message")
 
 		// If user reacts to a post in a starboard channel
 		if cID == g.Starboard.Channel || cID == g.Starboard.NsfwChannel {
@@ -274,7 +281,7 @@ func StarboardReactionHandler(i interface{}) {
 				IsNsfw: channel.NSFW,
 				Stars:  make([]int64, 0),
 			}
-			log.Printf("Making new starboard message: %v\n", sMsg)
+			logger.Debug("making new starboard message", "starboard_message", sMsg)
 		}
 
 		// Channel to send starboard message to
@@ -285,14 +292,14 @@ func StarboardReactionHandler(i interface{}) {
 
 		// Channel hasn't been set
 		if cID == 0 {
-			log.Printf("Channel ID is 0\n")
+			logger.Debug("starboard channel is not set", "nsfw", sMsg.IsNsfw)
 			return g, "StarboardReactionHandler: check cID"
 		}
 
 		// Get post channel and ensure it exists
 		postChannel, err := bot.Client.Channel(discord.ChannelID(cID))
 		if err != nil {
-			log.Printf("Couldn't get post channel\n")
+			logger.Warn("couldn't get post channel", "post_channel", cID, "err", err)
 			return g, "StarboardReactionHandler: get post channel"
 		}
 
@@ -302,14 +309,14 @@ func StarboardReactionHandler(i interface{}) {
 		if sMsg.Author != sUserID && !util.SliceContains(sMsg.Stars, sUserID) {
 			sMsg.Stars = append(sMsg.Stars, sUserID)
 		}
-		log.Printf("sUserID: %v\nsMsg:%v\n", sUserID, sMsg)
+		logger.Debug("added star", "starboard_message", sMsg)
 
 		// Update our reactions in case any are missing from the API
 		for _, reaction := range msg.Reactions {
 			if reaction.Emoji.APIString().PathString() == escapedStar {
 				userReactions, err := bot.Client.Reactions(msg.ChannelID, msg.ID, reaction.Emoji.APIString(), 0)
 				if err != nil {
-					log.Printf("Failed to get userReactions: %s\n", err)
+					logger.Error("failed to get reactions", "err", err)
 					return g, "StarboardReactionHandler: update sMsg.Stars"
 				}
 
@@ -328,7 +335,7 @@ func StarboardReactionHandler(i interface{}) {
 
 		// Not enough stars in sMsg to make post
 		if int64(stars) < g.Starboard.Threshold {
-			log.Printf("Not enough stars: %v\n", sMsg.Stars)
+			logger.Debug("not enough stars", "stars", stars, "threshold", g.Starboard.Threshold)
 			return g, "StarboardReactionHandler: check notEnoughStars"
 		}
 
@@ -337,14 +344,14 @@ func StarboardReactionHandler(i interface{}) {
 		// Attempt to get existing message, and make a new one if it isn't there
 		pMsg, err := bot.Client.Message(postChannel.ID, discord.MessageID(sMsg.PostID))
 		if err != nil {
-			log.Printf("Couldn't get pMsg (%v / %v) %v\n", postChannel.ID, sMsg.PostID, err)
+			logger.Debug("couldn't get starboard post, making a new one", "post_channel", postChannel.ID, "post", sMsg.PostID, "err", err)
 
 			//
 			// Construct new starboard post if it couldn't retrieve an existing one
 
 			member, err := bot.Client.Member(e.GuildID, discord.UserID(sMsg.Author))
 			if err != nil {
-				log.Printf("Couldn't get member %v\n", err)
+				logger.Warn("couldn't get author", "author", sMsg.Author, "err", err)
 				return g, "StarboardReactionHandler: get sMsg.Author"
 			}
 
@@ -361,11 +368,11 @@ func StarboardReactionHandler(i interface{}) {
 				Image:       image,
 			}
 
-			log.Printf("Embed image: %v\n", embed.Image)
+			logger.Debug("making starboard post", "image", embed.Image)
 
 			msg, err = bot.Client.SendMessage(postChannel.ID, content, embed)
 			if err != nil {
-				log.Printf("Error sending starboard post: %v\n", err)
+				logger.Error("error sending starboard post", "err", err)
 			} else {
 				sMsg.PostID = int64(msg.ID)
 			}
@@ -373,7 +380,7 @@ func StarboardReactionHandler(i interface{}) {
 			// Edit the post if it exists
 			_, err = bot.Client.EditMessage(postChannel.ID, discord.MessageID(sMsg.PostID), content, pMsg.Embeds...)
 			if err != nil {
-				log.Printf("Error updating starboard post: %v\n", err)
+				logger.Error("error updating starboard post", "err", err)
 			}
 		}
 
@@ -391,7 +398,7 @@ func StarboardReactionHandler(i interface{}) {
 		return g, "StarboardReactionHandler: update post"
 	})
 
-	log.Printf("Execute: %vms (StarboardReactionHandler)\n", time.Now().UnixMilli()-start)
+	logger.Debug("executed starboard reaction handler", "ms", time.Now().UnixMilli()-start)
 }
 
 func getEmoji(stars int) (emoji string) {
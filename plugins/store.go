@@ -0,0 +1,232 @@
+package plugins
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+// ConfigStore is what a Plugin.Config has to be, it is implemented by Store.
+type ConfigStore interface {
+	Type() reflect.Type                  // Type of the stored config
+	load(data []byte) error              // load will replace the stored config with data
+	save(write func([]byte) error) error // save will call write with the config, if it has changed since the last save
+
+	access(write bool, fn func(root reflect.Value) error) error // access will call fn with the addressable config, see GetConfig
+	defaultValue() reflect.Value                                // defaultValue will return a new default config
+	guildDefault(t reflect.Type) (reflect.Value, bool)          // guildDefault will return the default of a GuildMap type
+}
+
+// Store holds the config of a plugin. All access goes through its mutex, and a changed config is persisted by
+// Plugin.SaveConfig, which runs every few minutes and when the bot shuts down.
+type Store[T any] struct {
+	mutex  sync.Mutex
+	value  T
+	def    func() T
+	dirty  bool
+	guilds map[reflect.Type]func() reflect.Value // guilds is the default value of each GuildStore, by its GuildMap type
+	// guildsMutex is separate from mutex, because guilds is read by GetConfig while holding mutex
+	guildsMutex sync.Mutex
+}
+
+// NewStore will create a Store that starts with the value of def.
+// def is also used when a saved config is loaded, so fields missing from the saved config keep their default.
+func NewStore[T any](def func() T) *Store[T] {
+	if def == nil {
+		def = func() (t T) { return t }
+	}
+
+	return &Store[T]{value: def(), def: def, guilds: make(map[reflect.Type]func() reflect.Value)}
+}
+
+// Get will return a copy of the config. Maps and slices in the copy are shared with the Store, so they should only be
+// changed with Update, and read with View when they can be changed concurrently.
+func (s *Store[T]) Get() T {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.value
+}
+
+// View will call fn with the config, while holding the Store's mutex
+func (s *Store[T]) View(fn func(T)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	fn(s.value)
+}
+
+// Update will call fn with a pointer to the config, while holding the Store's mutex, and mark the config to be saved
+func (s *Store[T]) Update(fn func(*T)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	fn(&s.value)
+	s.dirty = true
+}
+
+// Type will return the type of the config
+func (s *Store[T]) Type() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func (s *Store[T]) load(data []byte) error {
+	value := s.def()
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.value = value
+	s.dirty = false
+	return nil
+}
+
+func (s *Store[T]) save(write func([]byte) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+
+	bytes, err := json.MarshalIndent(s.value, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	if err = write(bytes); err == nil {
+		s.dirty = false
+	}
+	return err
+}
+
+func (s *Store[T]) access(write bool, fn func(root reflect.Value) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if write {
+		// Only change the config if fn succeeds, so fn changes a deep copy of it
+		value, err := s.copy()
+		if err != nil {
+			return err
+		}
+		if err := fn(reflect.ValueOf(&value).Elem()); err != nil {
+			return err
+		}
+
+		s.value = value
+		s.dirty = true
+		return nil
+	}
+
+	value := s.value
+	return fn(reflect.ValueOf(&value).Elem())
+}
+
+// copy will return a deep copy of the config, which is made with JSON like saving it, so it has every field that is saved
+func (s *Store[T]) copy() (value T, err error) {
+	bytes, err := json.Marshal(s.value)
+	if err != nil {
+		return value, err
+	}
+
+	err = json.Unmarshal(bytes, &value)
+	return value, err
+}
+
+func (s *Store[T]) defaultValue() reflect.Value {
+	value := s.def()
+	return reflect.ValueOf(&value).Elem()
+}
+
+func (s *Store[T]) guildDefault(t reflect.Type) (reflect.Value, bool) {
+	s.guildsMutex.Lock()
+	defer s.guildsMutex.Unlock()
+
+	if def, ok := s.guilds[t]; ok {
+		return def(), true
+	}
+	return reflect.Value{}, false
+}
+
+// GuildMap is a map of [guild id]V, used for config fields that are stored separately for each guild
+type GuildMap[V any] map[string]V
+
+// guildMap is used to recognize a GuildMap with reflection
+func (GuildMap[V]) guildMap() {}
+
+// GuildStore is a per-guild view of a GuildMap inside a Store, see NewGuildStore
+type GuildStore[T, V any] struct {
+	store *Store[T]
+	field func(*T) *GuildMap[V]
+	def   func() V
+}
+
+// NewGuildStore will create a GuildStore for the GuildMap that field returns.
+// def is the value used for guilds that aren't in the GuildMap yet, and can be nil to use the zero value.
+// It is also used by GetConfig, which only knows the type of each GuildMap, so a config shouldn't have two GuildMaps
+// of the same type with different defaults.
+func NewGuildStore[T, V any](s *Store[T], field func(*T) *GuildMap[V], def func() V) *GuildStore[T, V] {
+	if def == nil {
+		def = func() (v V) { return v }
+	}
+
+	s.guildsMutex.Lock()
+	s.guilds[reflect.TypeOf(GuildMap[V]{})] = func() reflect.Value {
+		return reflect.ValueOf(def())
+	}
+	s.guildsMutex.Unlock()
+
+	return &GuildStore[T, V]{store: s, field: field, def: def}
+}
+
+// Get will return the config for a guild, or the default if it doesn't have one
+func (g *GuildStore[T, V]) Get(id string) V {
+	v, _ := g.Lookup(id)
+	return v
+}
+
+// Lookup will return the config for a guild, and if it exists
+func (g *GuildStore[T, V]) Lookup(id string) (v V, ok bool) {
+	g.store.mutex.Lock()
+	defer g.store.mutex.Unlock()
+
+	if v, ok = (*g.field(&g.store.value))[id]; !ok {
+		v = g.def()
+	}
+	return v, ok
+}
+
+// View will call fn with the config for a guild, or the default, while holding the Store's mutex
+func (g *GuildStore[T, V]) View(id string, fn func(V)) {
+	g.store.View(func(t T) {
+		v, ok := (*g.field(&t))[id]
+		if !ok {
+			v = g.def()
+		}
+		fn(v)
+	})
+}
+
+// Update will call fn with a pointer to the config for a guild, which starts as the default if the guild doesn't have one
+func (g *GuildStore[T, V]) Update(id string, fn func(*V)) {
+	g.store.Update(func(t *T) {
+		m := g.field(t)
+		if *m == nil {
+			*m = make(GuildMap[V])
+		}
+
+		v, ok := (*m)[id]
+		if !ok {
+			v = g.def()
+		}
+		fn(&v)
+		(*m)[id] = v
+	})
+}
+
+// Delete will remove the config for a guild
+func (g *GuildStore[T, V]) Delete(id string) {
+	g.store.Update(func(t *T) {
+		delete(*g.field(t), id)
+	})
+}
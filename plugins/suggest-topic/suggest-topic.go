@@ -30,6 +30,7 @@ func InitPlugin(_ *plugins.PluginInit) *plugins.Plugin {
 			Aliases:     []string{"topiccfg"},
 			Description: "Configure allowed topic channels",
 			GuildOnly:   true,
+			Permission:  "channels",
 		}, {
 			Fn:          TopicCommand,
 			FnName:      "TopicCommand",
@@ -152,7 +153,7 @@ func TopicConfigCommand(c bot.Command) error {
 	default:
 		_, err := cmd.SendEmbed(c.E,
 			"Configure Topics",
-			"Available arguments are:\n- `list`\n- `threshold [threshold]`\n- `enable|disable [channel]`",
+			c.T("Available arguments are:")+"\n- `list`\n- `threshold [threshold]`\n- `enable|disable [channel]`",
 			bot.DefaultColor)
 		return err
 	}
@@ -163,7 +164,7 @@ func TopicCommand(c bot.Command) error {
 	if argErr != nil {
 		_, err := cmd.SendEmbed(c.E,
 			"Suggest Topic",
-			"Available arguments are:\n- `[topic to suggest]`\nUse the `topicconfig` command to figure topic channels!",
+			c.T("Available arguments are:")+"\n- `[topic to suggest]`\nUse the `topicconfig` command to figure topic channels!",
 			bot.DefaultColor)
 		return err
 	}
@@ -202,7 +203,6 @@ func TopicCommand(c bot.Command) error {
 }
 
 func TopicReactionHandler(i interface{}) {
-	defer util.LogPanic()
 	e := i.(*gateway.MessageReactionAddEvent)
 
 	reactionMatchesActiveVote := false
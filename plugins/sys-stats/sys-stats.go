@@ -2,10 +2,10 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/5HT2/taro-bot/bot"
@@ -18,7 +18,8 @@ import (
 )
 
 var (
-	runningFetches = make(map[string]chan bool)
+	runningFetches      = make(map[string]chan struct{}) // [guild id]channel that is closed to cancel the running fetch
+	runningFetchesMutex sync.Mutex
 )
 
 func InitPlugin(_ *plugins.PluginInit) *plugins.Plugin {
@@ -32,6 +33,7 @@ func InitPlugin(_ *plugins.PluginInit) *plugins.Plugin {
 			Name:        "systemstats",
 			Aliases:     []string{"stats", "stat", "sysstat"},
 			Description: "Provides system statistics",
+			Cooldowns:   []bot.Cooldown{{Scope: bot.CooldownChannel, Duration: 10 * time.Second}},
 		}},
 	}
 }
@@ -41,13 +43,15 @@ func spacedString(s string, offset int) string {
 }
 
 func SysStatsCommand(c bot.Command) error {
-	// If we have a running fetch command in this guild, cancel it
-	if quit, ok := runningFetches[c.E.GuildID.String()]; ok {
-		quit <- true
-	} else {
-		// Start a new quit channel
-		runningFetches[c.E.GuildID.String()] = make(chan bool)
+	// If we have a running fetch command in this guild, cancel it.
+	// Its channel is closed instead of sent to, so that the fetch still sees it when it is busy editing the message.
+	quit := make(chan struct{})
+	runningFetchesMutex.Lock()
+	if old, ok := runningFetches[c.E.GuildID.String()]; ok {
+		close(old)
 	}
+	runningFetches[c.E.GuildID.String()] = quit
+	runningFetchesMutex.Unlock()
 
 	// Determine displayed shell based on
 	shell := "$"
@@ -61,7 +65,7 @@ func SysStatsCommand(c bot.Command) error {
 	// Get hostname
 	hostname, err := os.Hostname()
 	if err != nil {
-		return bot.GenericError(c.FnName, "getting hostname", err.Error())
+		return bot.InternalError(c.FnName, "getting hostname", "couldn't get the hostname", err)
 	}
 	hostname = "taro@" + hostname
 
@@ -70,7 +74,7 @@ func SysStatsCommand(c bot.Command) error {
 	if err != nil {
 		// Try for darwin
 		if out, err := exec.Command("uname", "-r").CombinedOutput(); err != nil {
-			return bot.GenericSyntaxError(c.FnName, "getting kernel release", err.Error())
+			return bot.InternalError(c.FnName, "getting kernel release", "couldn't get the kernel release", err)
 		} else {
 			kernelRelease = append(out, []byte("-macOS")...)
 		}
@@ -79,7 +83,7 @@ func SysStatsCommand(c bot.Command) error {
 	// Get current uptime
 	uptimeDuration, err := uptime.Get()
 	if err != nil {
-		return bot.GenericError(c.FnName, "getting uptime", err.Error())
+		return bot.InternalError(c.FnName, "getting uptime", "couldn't get the uptime", err)
 	}
 
 	var days int64 = 0
@@ -96,7 +100,7 @@ func SysStatsCommand(c bot.Command) error {
 	// Get starting CPU usage
 	cpuBefore, err := cpu.Get()
 	if err != nil {
-		return bot.GenericError(c.FnName, "getting cpu info", err.Error())
+		return bot.InternalError(c.FnName, "getting cpu info", "couldn't get the cpu usage", err)
 	}
 
 	// Fetch data to display inside fetch
@@ -132,27 +136,29 @@ func SysStatsCommand(c bot.Command) error {
 
 	// Start a goroutine for 60 seconds which updates CPU and memory in the fetch image.
 	// If another stats command is started in the same guild, this fetch is cancelled in favor of the new one.
-	go func() {
+	// It is dispatched so that shutting down waits for it, and it stops early when the bot is shutting down.
+	bot.Dispatch(func() {
 		msg, _ := cmd.SendMessage(c.E, generateFetch())
 		i := 0
 
 		for {
 			select { // only allow one running fetch per guild, by cancelling when receiving a quit signal from another message
-			case <-runningFetches[c.E.GuildID.String()]:
+			case <-quit:
 				return
-			default:
+			case <-bot.ShuttingDown().Done():
+				return
+			case <-time.After(time.Duration(1500) * time.Millisecond):
 				i++
-				time.Sleep(time.Duration(1500) * time.Millisecond)
 
 				cpuAfter, err := cpu.Get()
 				if err != nil {
-					log.Printf("%s\n", bot.GenericError(c.FnName, "getting cpu info", err.Error()))
+					c.Log().Error("failed to get cpu info", "err", err)
 					break
 				}
 
 				mem, err := memory.Get()
 				if err != nil {
-					log.Printf("%s\n", bot.GenericError(c.FnName, "getting memory info", err.Error()))
+					c.Log().Error("failed to get memory info", "err", err)
 					break
 				}
 
@@ -175,7 +181,7 @@ func SysStatsCommand(c bot.Command) error {
 				}
 			}
 		}
-	}()
+	})
 
 	return nil
 }
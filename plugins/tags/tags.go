@@ -0,0 +1,574 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/5HT2/taro-bot/bot"
+	"github.com/5HT2/taro-bot/cmd"
+	"github.com/5HT2/taro-bot/plugins"
+	"github.com/5HT2/taro-bot/util"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	p      *plugins.Plugin
+	store  = plugins.NewStore[config](nil)
+	guilds = plugins.NewGuildStore(store, func(c *config) *plugins.GuildMap[map[string]Tag] { return &c.Guilds }, func() map[string]Tag {
+		return make(map[string]Tag)
+	})
+
+	regexCache      = make(map[string]*regexp.Regexp) // [trigger]compiled regex, for MatchRegex and MatchWord tags
+	regexCacheMutex sync.Mutex
+)
+
+const (
+	maxTags       = 100
+	maxTagName    = 32
+	maxTriggerLen = 256
+	tagUsage      = "- `add <name> <exact|contains|word|regex> <trigger> <reply|embed json>`\n" +
+		"- `edit <name> trigger|match|reply|channels|cooldown <value>`\n" +
+		"- `remove <name>`\n" +
+		"- `list`\n" +
+		"- `info <name>`\n\n" +
+		"Triggers with spaces can be wrapped in quotes, like `\"good morning\"`.\n" +
+		"Replies can use `{user}`, `{user.name}`, `{user.id}`, `{channel}`, `{channel.id}`, `{guild}` and `{guild.id}`."
+)
+
+type config struct {
+	Guilds plugins.GuildMap[map[string]Tag] `json:"guilds,omitempty"` // [guild id][tag name]Tag
+}
+
+// MatchType is how a Tag's Trigger is matched against a message
+type MatchType string
+
+const (
+	MatchExact    MatchType = "exact"    // MatchExact matches the whole message, ignoring case
+	MatchContains MatchType = "contains" // MatchContains matches anywhere in the message, ignoring case
+	MatchWord     MatchType = "word"     // MatchWord matches whole words in the message, ignoring case
+	MatchRegex    MatchType = "regex"    // MatchRegex matches a regular expression
+)
+
+var matchTypes = []MatchType{MatchExact, MatchContains, MatchWord, MatchRegex}
+
+type Tag struct {
+	Name     string         `json:"name"`
+	Trigger  string         `json:"trigger"`
+	Match    MatchType      `json:"match"`
+	Content  string         `json:"content,omitempty"`
+	Embed    *discord.Embed `json:"embed,omitempty"`
+	Channels []int64        `json:"channels,omitempty"` // Channels the tag is sent in, all channels if empty
+	Cooldown int64          `json:"cooldown,omitempty"` // Cooldown in seconds before the tag is sent again in the same channel
+	Author   int64          `json:"author"`             // Author is the user that added the tag
+	Created  int64          `json:"created"`            // Created in epoch seconds
+	Uses     int64          `json:"uses,omitempty"`
+}
+
+func (t Tag) String() string {
+	return fmt.Sprintf("[%s, %s, \"%s\", %v, %v, %v]", t.Name, t.Match, t.Trigger, t.Channels, t.Cooldown, t.Uses)
+}
+
+func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
+	p = &plugins.Plugin{
+		Name:        "Tags",
+		Description: "Custom auto-responses for each guild, managed by moderators",
+		Version:     "1.0.0",
+		Commands: []bot.CommandInfo{{
+			Fn:          TagCommand,
+			FnName:      "TagCommand",
+			Name:        "tag",
+			Aliases:     []string{"tags"},
+			Description: "Add, edit or remove auto-responses",
+			Usage:       "add|edit|remove|list|info [name] [args]",
+			GuildOnly:   true,
+			Permission:  "moderate",
+		}},
+		Responses: []bot.ResponseInfo{{
+			Fn:          TagResponse,
+			Regexes:     []string{"."},
+			MatchMin:    1,
+			NoRateLimit: true, // this matches every message, so the rate limit is only used when a tag is sent
+		}},
+		Config: store,
+	}
+	p.ConfigDir = i.ConfigDir
+	p.LoadConfig()
+	return p
+}
+
+// TagResponse will send the first tag, by name, that matches the message
+func TagResponse(r bot.Response) {
+	if !r.E.GuildID.IsValid() {
+		return
+	}
+
+	id := r.E.GuildID.String()
+
+	var tags []Tag
+	guilds.View(id, func(m map[string]Tag) {
+		for _, t := range m {
+			tags = append(tags, t)
+		}
+	})
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+
+	for _, t := range tags {
+		if len(t.Channels) > 0 && !util.SliceContains(t.Channels, int64(r.E.ChannelID)) {
+			continue
+		}
+
+		if !t.matches(r.E.Message.Content) {
+			continue
+		}
+
+		// Tags on cooldown are skipped silently, like responses, and the next matching tag is tried instead
+		if remaining, _ := bot.UseCooldowns("tag/"+id+"/"+t.Name, t.cooldowns(), r.E); remaining > 0 {
+			continue
+		}
+
+		if !bot.WaitRateLimit() {
+			r.Log().Warn("dropped tag, rate limited", "tag", t.Name)
+			return
+		}
+
+		content, embed := t.reply(r)
+		if _, err := cmd.SendMessageEmbedSafe(r.E.ChannelID, content, embed); err != nil {
+			r.Log().Error("error sending tag", "tag", t.Name, "err", err)
+			return
+		}
+
+		guilds.Update(id, func(m *map[string]Tag) {
+			if t, ok := (*m)[t.Name]; ok {
+				t.Uses++
+				(*m)[t.Name] = t
+			}
+		})
+		return
+	}
+}
+
+func TagCommand(c bot.Command) error {
+	if err := cmd.HasPermission(c, cmd.PermModerate); err != nil {
+		return err
+	}
+
+	arg, _ := cmd.ParseStringArg(c.Args, 1, true)
+
+	switch arg {
+	case "add":
+		return addTag(c)
+	case "edit":
+		return editTag(c)
+	case "remove", "rm", "delete":
+		return removeTag(c)
+	case "list", "ls":
+		return listTags(c)
+	case "info":
+		return tagInfo(c)
+	default:
+		_, err := cmd.SendEmbed(c.E, "Tags", c.T("Available arguments are:")+"\n"+tagUsage, bot.DefaultColor)
+		return err
+	}
+}
+
+func addTag(c bot.Command) error {
+	name, err := parseTagName(c.Args)
+	if err != nil {
+		return err
+	}
+
+	matchArg, argErr := cmd.ParseStringArg(c.Args, 3, true)
+	if argErr != nil {
+		return argErr
+	}
+	match, err := parseMatchType(matchArg)
+	if err != nil {
+		return err
+	}
+
+	trigger, rest, err := parseTrigger(c.Args[3:])
+	if err != nil {
+		return err
+	}
+
+	t := Tag{
+		Name:    name,
+		Trigger: trigger,
+		Match:   match,
+		Author:  int64(c.E.Author.ID),
+		Created: time.Now().Unix(),
+	}
+	if err := t.validate(); err != nil {
+		return err
+	}
+	if t.Content, t.Embed, err = parseReply(rest); err != nil {
+		return err
+	}
+
+	var exists, full bool
+	guilds.Update(c.E.GuildID.String(), func(m *map[string]Tag) {
+		if _, exists = (*m)[name]; exists {
+			return
+		}
+		if full = len(*m) >= maxTags; full {
+			return
+		}
+		(*m)[name] = t
+	})
+
+	if exists {
+		return bot.GenericError(c.FnName, "adding tag", "`"+name+"` already exists, use `tag edit` to change it")
+	}
+	if full {
+		return bot.GenericError(c.FnName, "adding tag", fmt.Sprintf("this guild already has %v tags", maxTags))
+	}
+
+	_, sendErr := cmd.SendEmbed(c.E, "Tags", fmt.Sprintf("Added tag `%s`, matching %s `%s`!", name, t.Match, t.Trigger), bot.SuccessColor)
+	return sendErr
+}
+
+func editTag(c bot.Command) error {
+	name, err := parseTagName(c.Args)
+	if err != nil {
+		return err
+	}
+
+	t, err := getTag(c, name)
+	if err != nil {
+		return err
+	}
+
+	field, argErr := cmd.ParseStringArg(c.Args, 3, true)
+	if argErr != nil {
+		return argErr
+	}
+
+	oldTrigger := t.Trigger
+	message := ""
+
+	switch field {
+	case "trigger":
+		if t.Trigger, _, err = parseTrigger(c.Args[3:]); err != nil {
+			return err
+		}
+		message = fmt.Sprintf("Set trigger of `%s` to `%s`!", name, t.Trigger)
+	case "match":
+		matchArg, argErr := cmd.ParseStringArg(c.Args, 4, true)
+		if argErr != nil {
+			return argErr
+		}
+		if t.Match, err = parseMatchType(matchArg); err != nil {
+			return err
+		}
+		message = fmt.Sprintf("Set match type of `%s` to %s!", name, t.Match)
+	case "reply":
+		if t.Content, t.Embed, err = parseReply(c.Args[3:]); err != nil {
+			return err
+		}
+		message = fmt.Sprintf("Set reply of `%s`!", name)
+	case "channels":
+		if arg, _ := cmd.ParseStringArg(c.Args, 4, true); arg == "all" || arg == "none" {
+			t.Channels = nil
+			message = fmt.Sprintf("`%s` will be sent in all channels!", name)
+			break
+		}
+
+		channels, argErr := cmd.ParseChannelSliceArg(c.Args, 4, -1)
+		if argErr != nil {
+			return argErr
+		}
+		t.Channels = channels
+		message = fmt.Sprintf("`%s` will only be sent in %s!", name, util.JoinInt64Slice(channels, ", ", "<#", ">"))
+	case "cooldown":
+		arg, argErr := cmd.ParseStringArg(c.Args, 4, true)
+		if argErr != nil {
+			return argErr
+		}
+
+		cooldown := time.Duration(0)
+		if arg != "0" && arg != "off" && arg != "none" {
+			if cooldown, err = time.ParseDuration(arg); err != nil || cooldown < 0 {
+				return bot.GenericSyntaxError(c.FnName, arg, "expected a duration such as `30s` or `5m`")
+			}
+		}
+
+		t.Cooldown = int64(cooldown.Seconds())
+		message = fmt.Sprintf("Set cooldown of `%s` to %s!", name, util.FormattedTime(t.Cooldown))
+	default:
+		_, err := cmd.SendEmbed(c.E, "Tags", c.T("Available arguments are:")+"\n"+tagUsage, bot.DefaultColor)
+		return err
+	}
+
+	if err := t.validate(); err != nil {
+		return err
+	}
+
+	guilds.Update(c.E.GuildID.String(), func(m *map[string]Tag) {
+		(*m)[name] = t
+	})
+	if oldTrigger != t.Trigger {
+		forgetRegex(oldTrigger)
+	}
+
+	_, sendErr := cmd.SendEmbed(c.E, "Tags", message, bot.SuccessColor)
+	return sendErr
+}
+
+func removeTag(c bot.Command) error {
+	name, err := parseTagName(c.Args)
+	if err != nil {
+		return err
+	}
+
+	t, err := getTag(c, name)
+	if err != nil {
+		return err
+	}
+
+	guilds.Update(c.E.GuildID.String(), func(m *map[string]Tag) {
+		delete(*m, name)
+	})
+	forgetRegex(t.Trigger)
+
+	_, sendErr := cmd.SendEmbed(c.E, "Tags", fmt.Sprintf("Removed tag `%s`!", name), bot.SuccessColor)
+	return sendErr
+}
+
+func listTags(c bot.Command) error {
+	lines := make([]string, 0)
+	guilds.View(c.E.GuildID.String(), func(m map[string]Tag) {
+		for _, t := range m {
+			lines = append(lines, fmt.Sprintf("`%s` - %s `%s`", t.Name, t.Match, t.Trigger))
+		}
+	})
+
+	if len(lines) == 0 {
+		_, err := cmd.SendEmbed(c.E, "Tags", "This guild doesn't have any tags yet!", bot.WarnColor)
+		return err
+	}
+
+	sort.Strings(lines)
+	_, err := cmd.SendEmbedFooter(c.E, "Tags", util.HeadLinesLimit(strings.Join(lines, "\n"), 2048), util.JoinIntAndStr(len(lines), "tag"), bot.DefaultColor)
+	return err
+}
+
+func tagInfo(c bot.Command) error {
+	name, err := parseTagName(c.Args)
+	if err != nil {
+		return err
+	}
+
+	t, err := getTag(c, name)
+	if err != nil {
+		return err
+	}
+
+	channels := "All"
+	if len(t.Channels) > 0 {
+		channels = util.JoinInt64Slice(t.Channels, ", ", "<#", ">")
+	}
+
+	reply := t.Content
+	if t.Embed != nil {
+		reply += "\n*(with embed)*"
+	}
+
+	embed := cmd.MakeEmbed("Tag `"+t.Name+"`", "", bot.DefaultColor)
+	embed.Fields = []discord.EmbedField{
+		{Name: "Trigger", Value: fmt.Sprintf("%s `%s`", t.Match, t.Trigger)},
+		{Name: "Reply", Value: util.HeadLinesLimit(reply, 1024)},
+		{Name: "Channels", Value: channels, Inline: true},
+		{Name: "Cooldown", Value: util.FormattedTime(t.Cooldown), Inline: true},
+		{Name: "Uses", Value: util.FormattedNum(t.Uses), Inline: true},
+		{Name: "Author", Value: fmt.Sprintf("<@%v>", t.Author), Inline: true},
+		{Name: "Created", Value: fmt.Sprintf("<t:%v:R>", t.Created), Inline: true},
+	}
+
+	_, sendErr := cmd.SendCustomEmbed(c.E.ChannelID, embed)
+	return sendErr
+}
+
+// getTag will return the tag with name in the command's guild, or an error if it doesn't exist
+func getTag(c bot.Command, name string) (Tag, error) {
+	var t Tag
+	var ok bool
+	guilds.View(c.E.GuildID.String(), func(m map[string]Tag) {
+		t, ok = m[name]
+	})
+
+	if !ok {
+		return t, bot.NotFoundError(c.FnName, "getting tag", "`"+name+"` doesn't exist")
+	}
+	return t, nil
+}
+
+func parseTagName(a []string) (string, error) {
+	name, argErr := cmd.ParseStringArg(a, 2, true)
+	if argErr != nil {
+		return "", argErr
+	}
+
+	if len(name) > maxTagName {
+		return "", bot.GenericSyntaxError("parseTagName", name, fmt.Sprintf("tag names can be at most %v characters", maxTagName))
+	}
+	return name, nil
+}
+
+func parseMatchType(s string) (MatchType, error) {
+	for _, m := range matchTypes {
+		if MatchType(s) == m {
+			return m, nil
+		}
+	}
+
+	return "", bot.GenericSyntaxError("parseMatchType", s, "expected one of exact, contains, word or regex")
+}
+
+// parseTrigger will return the trigger at the start of a, which can be wrapped in quotes, and the remaining args
+func parseTrigger(a []string) (string, []string, error) {
+	if len(a) == 0 || len(a[0]) == 0 {
+		return "", nil, bot.GenericSyntaxError("parseTrigger", "nothing", "expected a trigger")
+	}
+
+	if !strings.HasPrefix(a[0], "\"") {
+		return a[0], a[1:], nil
+	}
+
+	for n := range a {
+		if (n > 0 || len(a[0]) > 1) && strings.HasSuffix(a[n], "\"") {
+			trigger := strings.Join(a[:n+1], " ")
+			return trigger[1 : len(trigger)-1], a[n+1:], nil
+		}
+	}
+
+	return "", nil, bot.GenericSyntaxError("parseTrigger", strings.Join(a, " "), "trigger is missing a closing quote")
+}
+
+// parseReply will return the content of a reply, or its embed if it is JSON
+func parseReply(a []string) (string, *discord.Embed, error) {
+	reply := strings.TrimSpace(strings.Join(a, " "))
+	if len(reply) == 0 {
+		return "", nil, bot.GenericSyntaxError("parseReply", "nothing", "expected a reply")
+	}
+
+	if !strings.HasPrefix(reply, "{") {
+		return reply, nil, nil
+	}
+
+	var embed discord.Embed
+	if err := json.Unmarshal([]byte(reply), &embed); err != nil {
+		return "", nil, bot.GenericSyntaxError("parseReply", "embed", err.Error())
+	}
+	return "", &embed, nil
+}
+
+// validate will check that the Trigger can be matched with the Match type
+func (t Tag) validate() error {
+	if len(t.Trigger) == 0 {
+		return bot.GenericSyntaxError("validate", "trigger", "trigger is empty")
+	}
+	if len(t.Trigger) > maxTriggerLen {
+		return bot.GenericSyntaxError("validate", "trigger", fmt.Sprintf("triggers can be at most %v characters", maxTriggerLen))
+	}
+
+	if t.Match == MatchRegex || t.Match == MatchWord {
+		if _, err := compileTrigger(t); err != nil {
+			return bot.GenericSyntaxError("validate", t.Trigger, err.Error())
+		}
+	}
+	return nil
+}
+
+// matches will return if the tag's Trigger matches content
+func (t Tag) matches(content string) bool {
+	switch t.Match {
+	case MatchExact:
+		return strings.EqualFold(strings.TrimSpace(content), t.Trigger)
+	case MatchContains:
+		return strings.Contains(strings.ToLower(content), strings.ToLower(t.Trigger))
+	case MatchWord, MatchRegex:
+		re, err := compileTrigger(t)
+		if err != nil {
+			p.Log().Error("error compiling tag", "tag", t.Name, "err", err)
+			return false
+		}
+		return re.MatchString(content)
+	default:
+		return false
+	}
+}
+
+// reply will return the tag's content and embed, with variables replaced
+func (t Tag) reply(r bot.Response) (string, *discord.Embed) {
+	guildName := r.E.GuildID.String()
+	if guild, err := bot.Client.Guild(r.E.GuildID); err == nil {
+		guildName = guild.Name
+	}
+
+	replacer := strings.NewReplacer(
+		"{user}", r.E.Author.Mention(),
+		"{user.name}", util.FormattedUserTag(r.E.Author),
+		"{user.id}", r.E.Author.ID.String(),
+		"{channel}", r.E.ChannelID.Mention(),
+		"{channel.id}", r.E.ChannelID.String(),
+		"{guild}", guildName,
+		"{guild.id}", r.E.GuildID.String(),
+	)
+
+	var embed *discord.Embed
+	if t.Embed != nil {
+		e := *t.Embed
+		e.Title = replacer.Replace(e.Title)
+		e.Description = replacer.Replace(e.Description)
+		embed = &e
+	}
+
+	return replacer.Replace(t.Content), embed
+}
+
+// compileTrigger will return the compiled regex of a MatchWord or MatchRegex tag, which is cached by its Trigger
+func compileTrigger(t Tag) (*regexp.Regexp, error) {
+	pattern := t.Trigger
+	if t.Match == MatchWord {
+		pattern = `(?i)\b` + regexp.QuoteMeta(t.Trigger) + `\b`
+	}
+
+	regexCacheMutex.Lock()
+	defer regexCacheMutex.Unlock()
+
+	if re, ok := regexCache[pattern]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	regexCache[pattern] = re
+	return re, nil
+}
+
+// forgetRegex will remove a trigger from the regex cache, it is compiled again if another tag still uses it
+func forgetRegex(trigger string) {
+	regexCacheMutex.Lock()
+	defer regexCacheMutex.Unlock()
+
+	delete(regexCache, trigger)
+	delete(regexCache, `(?i)\b`+regexp.QuoteMeta(trigger)+`\b`)
+}
+
+// cooldowns will return the cooldowns of the tag for bot.UseCooldowns, which are counted for each channel
+func (t Tag) cooldowns() []bot.Cooldown {
+	if t.Cooldown <= 0 {
+		return nil
+	}
+	return []bot.Cooldown{{Scope: bot.CooldownChannel, Duration: time.Duration(t.Cooldown) * time.Second}}
+}
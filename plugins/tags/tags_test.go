@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/5HT2/taro-bot/bot"
+	"github.com/5HT2/taro-bot/cmd/cmdtest"
+	"github.com/5HT2/taro-bot/plugins"
+	"strings"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	h := cmdtest.New(t, InitPlugin(&plugins.PluginInit{ConfigDir: "tags"}))
+	prefix := bot.DefaultPrefix
+
+	// Adding tags needs the moderate permission
+	h.Send(prefix + "tag add hello word hello Hi {user.name}!")
+	if got := h.LastReply().Embeds[0].Color; got != bot.WarnColor {
+		t.Fatalf("expected a user without permission to not be able to add tags")
+	}
+
+	h.SendAs(h.Owner, prefix+"tag add hello word hello Hi {user.name}!")
+	if got := cmdtest.Text(h.LastReply()); !strings.Contains(got, "Added tag `hello`") {
+		t.Fatalf("expected the tag to be added, got %q", got)
+	}
+
+	h.Send("well hello there")
+	if got := h.LastReply().Content; got != "Hi user#0001!" {
+		t.Errorf("expected the tag to be sent, got %q", got)
+	}
+
+	replies := len(h.Replies())
+	h.Send("othello")
+	if got := len(h.Replies()); got != replies {
+		t.Errorf("expected a word tag to not match inside of another word")
+	}
+
+	h.SendAs(h.Owner, prefix+"tag remove hello")
+	h.Send("hello")
+	if got := cmdtest.Text(h.LastReply()); !strings.Contains(got, "Removed tag `hello`") {
+		t.Errorf("expected the removed tag to not be sent, got %q", got)
+	}
+}
+
+func TestTagCooldown(t *testing.T) {
+	h := cmdtest.New(t, InitPlugin(&plugins.PluginInit{ConfigDir: "tags"}))
+	prefix := bot.DefaultPrefix
+
+	h.SendAs(h.Owner, prefix+"tag add slow word slow Not so fast")
+	h.SendAs(h.Owner, prefix+"tag edit slow cooldown 1h")
+
+	h.Send("slow")
+	replies := len(h.Replies())
+	if got := h.LastReply().Content; got != "Not so fast" {
+		t.Fatalf("expected the tag to be sent, got %q", got)
+	}
+
+	h.Send("slow")
+	if got := len(h.Replies()); got != replies {
+		t.Errorf("expected the tag to not be sent again while on cooldown")
+	}
+}
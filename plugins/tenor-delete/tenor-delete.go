@@ -4,20 +4,18 @@ import (
 	"github.com/5HT2/taro-bot/bot"
 	"github.com/5HT2/taro-bot/cmd"
 	"github.com/5HT2/taro-bot/plugins"
-	"log"
-	"reflect"
 	"regexp"
-	"sync"
 )
 
 var (
 	p          *plugins.Plugin
-	mutex      sync.Mutex
+	store      = plugins.NewStore[config](nil)
+	guilds     = plugins.NewGuildStore(store, func(c *config) *plugins.GuildMap[bool] { return &c.Guilds }, nil)
 	tenorRegex = regexp.MustCompile(`http(s)?://t([ex])nor\.[A-z]+/view/.*`)
 )
 
 type config struct {
-	Guilds map[string]bool `json:"guilds,omitempty"` // [guild id]enabled
+	Guilds plugins.GuildMap[bool] `json:"guilds,omitempty"` // [guild id]enabled
 }
 
 func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
@@ -25,37 +23,36 @@ func InitPlugin(i *plugins.PluginInit) *plugins.Plugin {
 		Name:        "Tenor Delete",
 		Description: "Automatically delete tenor gifs",
 		Version:     "1.0.0",
-		ConfigType:  reflect.TypeOf(config{}),
+		Config:      store,
 		Commands: []bot.CommandInfo{{
 			Fn:          TenorDeleteCommand,
 			FnName:      "TenorDeleteCommand",
 			Name:        "tenordelete",
 			Description: "Toggle tenor deletion on or off",
 			GuildOnly:   true,
+			Permission:  "moderate",
 		}},
 		Responses: []bot.ResponseInfo{{
 			Fn:       TenorDeleteResponse,
 			Regexes:  []string{tenorRegex.String()},
 			MatchMin: 1,
+			Priority: 10,
 		}},
 	}
 	p.ConfigDir = i.ConfigDir
-	p.Config = p.LoadConfig()
+	p.LoadConfig()
 	return p
 }
 
 func TenorDeleteResponse(r bot.Response) {
-	mutex.Lock()
-	defer mutex.Unlock()
-
-	if p.Config == nil {
-		return
-	}
-
-	if enabled, ok := p.Config.(config).Guilds[r.E.GuildID.String()]; ok && enabled {
+	if guilds.Get(r.E.GuildID.String()) {
 		if err := bot.Client.DeleteMessage(r.E.ChannelID, r.E.Message.ID, "Matched Tenor gif"); err != nil {
-			log.Printf("TenorDeleteResponse: %v\n", err)
+			r.Log().Error("failed to delete tenor gif", "err", err)
+			return
 		}
+
+		// The message is gone, so other responses shouldn't reply to it
+		r.Consume()
 	}
 }
 
@@ -64,18 +61,13 @@ func TenorDeleteCommand(c bot.Command) error {
 		return err
 	}
 
-	id := c.E.GuildID.String()
 	var err error = nil
+	enabled := false
 
-	mutex.Lock()
-	defer mutex.Unlock()
-
-	if p.Config == nil {
-		p.Config = config{Guilds: map[string]bool{id: false}}
-	}
-
-	enabled, _ := p.Config.(config).Guilds[id]
-	p.Config.(config).Guilds[id] = !enabled
+	guilds.Update(c.E.GuildID.String(), func(g *bool) {
+		enabled = *g
+		*g = !enabled
+	})
 
 	if !enabled {
 		_, err = cmd.SendEmbed(c.E, "Tenor Delete", "✅ Enabled Tenor Delete for this guild", bot.SuccessColor)
@@ -2,10 +2,15 @@
 
 PLUGINS_FILE="config/plugins.json"
 
+# build_plugin will build the plugin in $1, and copy its manifest next to it so it can be validated before loading
+build_plugin() {
+  echo "building $1"
+  go build -o "bin/" -buildmode=plugin "$1" && cp "$1/manifest.json" "bin/$(basename "$1").manifest.json"
+}
+
 build_all() {
   for d in ./plugins/*/; do
-    echo "building $d"
-    go build -o "bin/" -buildmode=plugin "$d"
+    build_plugin "$d"
   done
 }
 
@@ -33,8 +38,7 @@ else
     LOADED="$(plugin_loaded "$d")"
 
     if [ -n "$LOADED" ] && [ "$LOADED" != "null" ]; then
-      echo "building $d"
-      go build -o "bin/" -buildmode=plugin "$d"
+      build_plugin "$d"
     fi
   done
 fi
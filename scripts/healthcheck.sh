@@ -0,0 +1,9 @@
+#!/bin/bash
+
+# Used by the Dockerfile's HEALTHCHECK, which needs HTTP_ADDR to be set
+if [[ -z "$HTTP_ADDR" ]]; then
+  echo "HTTP_ADDR not set!"
+  exit 1
+fi
+
+curl -fsS "http://127.0.0.1:${HTTP_ADDR##*:}/healthz"
@@ -1,3 +1,3 @@
 #!/bin/bash
 
-/taro-bot/taro -debug="$DEBUG" -plugindir="$PLUGIN_DIR"
+/taro-bot/taro -debug="$DEBUG" -logjson="$LOG_JSON" -httpaddr="$HTTP_ADDR" -plugindir="$PLUGIN_DIR"
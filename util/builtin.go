@@ -1,9 +1,7 @@
 package util
 
 import (
-	"encoding/json"
-	"log"
-	"reflect"
+	"log/slog"
 	"runtime/debug"
 	"sort"
 	"strconv"
@@ -20,7 +18,7 @@ type retryFunction func() ([]byte, error)
 func LogPanic() {
 	if x := recover(); x != nil {
 		// recovering from a panic; x contains whatever was passed to panic()
-		log.Printf("panic: %s\n", debug.Stack())
+		slog.Error("recovered from panic", "panic", x, "stack", string(debug.Stack()))
 	}
 }
 
@@ -46,19 +44,6 @@ func RetryFunc(fn retryFunction, n int, delayMs time.Duration) ([]byte, error) {
 	return fn()
 }
 
-func NewInterface(typ reflect.Type, data []byte) (interface{}, error) {
-	if typ.Kind() == reflect.Ptr {
-		typ = typ.Elem()
-		dst := reflect.New(typ).Elem()
-		err := json.Unmarshal(data, dst.Addr().Interface())
-		return dst.Addr().Interface(), err
-	} else {
-		dst := reflect.New(typ).Elem()
-		err := json.Unmarshal(data, dst.Addr().Interface())
-		return dst.Interface(), err
-	}
-}
-
 // SliceContains will return if slice s contains e
 func SliceContains[T comparable](s []T, e T) bool {
 	for _, a := range s {
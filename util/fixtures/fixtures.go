@@ -0,0 +1,134 @@
+// Package fixtures serves recorded HTTP responses from files, so that plugins that use external APIs can be tested without
+// network access. Each plugin points its base URLs at the Server in its tests.
+//
+// A request for /api/v1/search?q=frog is answered with the file api/v1/search in the fixture dir, or else the first file
+// that matches api/v1/search.*, so the query is ignored, and the extension sets the Content-Type. A request without a
+// fixture gets a 404, so that tests can check how a plugin handles missing resources.
+// Running the tests with -record proxies missing fixtures to the real upstream instead, and saves their responses.
+package fixtures
+
+import (
+	"flag"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Placeholder is replaced with the URL of the Server in fixtures, for APIs that return links to themselves
+const Placeholder = "{{fixtures.URL}}"
+
+var (
+	record = flag.Bool("record", false, "Record missing HTTP fixtures from their real upstream")
+)
+
+// Server is an httptest.Server that serves fixtures
+type Server struct {
+	*httptest.Server
+	t        *testing.T
+	dir      string
+	upstream string
+}
+
+// NewServer will start a Server for the fixtures in dir, which records them from upstream with -record.
+// The Server is closed when the test finishes.
+func NewServer(t *testing.T, dir, upstream string) *Server {
+	s := &Server{t: t, dir: dir, upstream: strings.TrimSuffix(upstream, "/")}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	file, ok := s.find(r.URL.Path)
+	if !ok && *record {
+		file, ok = s.record(r)
+	}
+
+	if !ok {
+		s.t.Logf("fixtures: no fixture for %s %s in %s", r.Method, r.URL, s.dir)
+		http.NotFound(w, r)
+		return
+	}
+
+	body, err := os.ReadFile(file)
+	if err != nil {
+		s.t.Errorf("fixtures: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if contentType := mime.TypeByExtension(filepath.Ext(file)); len(contentType) > 0 {
+		w.Header().Set("Content-Type", contentType)
+	}
+	_, _ = w.Write([]byte(strings.ReplaceAll(string(body), Placeholder, s.URL)))
+}
+
+// find will return the fixture for urlPath, if there is one
+func (s *Server) find(urlPath string) (string, bool) {
+	name := filepath.Join(s.dir, filepath.FromSlash(path.Clean("/"+urlPath)))
+	if info, err := os.Stat(name); err == nil && !info.IsDir() {
+		return name, true
+	}
+
+	matches, err := filepath.Glob(name + ".*")
+	if err != nil || len(matches) == 0 {
+		return "", false
+	}
+	return matches[0], true
+}
+
+// record will save the upstream response for r as a fixture, and return its file
+func (s *Server) record(r *http.Request) (string, bool) {
+	if len(s.upstream) == 0 {
+		return "", false
+	}
+
+	res, err := http.Get(s.upstream + r.URL.RequestURI())
+	if err != nil {
+		s.t.Errorf("fixtures: recording %s: %v", r.URL, err)
+		return "", false
+	}
+	defer res.Body.Close()
+
+	// Missing resources aren't recorded, so they are a 404 like when they aren't recorded
+	if res.StatusCode != http.StatusOK {
+		s.t.Logf("fixtures: not recording %s: status %v", r.URL, res.StatusCode)
+		return "", false
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		s.t.Errorf("fixtures: recording %s: %v", r.URL, err)
+		return "", false
+	}
+
+	// The extension is kept for the Content-Type, which is added if the path doesn't have one
+	file := filepath.Join(s.dir, filepath.FromSlash(path.Clean("/"+r.URL.Path)))
+	if len(filepath.Ext(file)) == 0 {
+		ext := ".txt"
+		if exts, _ := mime.ExtensionsByType(res.Header.Get("Content-Type")); len(exts) > 0 {
+			ext = exts[0]
+		}
+		file += ext
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		s.t.Errorf("fixtures: recording %s: %v", r.URL, err)
+		return "", false
+	}
+
+	// Links to the upstream are replaced, so that they point at the Server when the fixture is served
+	body = []byte(strings.ReplaceAll(string(body), s.upstream, Placeholder))
+	if err := os.WriteFile(file, body, 0644); err != nil {
+		s.t.Errorf("fixtures: recording %s: %v", r.URL, err)
+		return "", false
+	}
+
+	s.t.Logf("fixtures: recorded %s to %s", r.URL, file)
+	return file, true
+}
@@ -3,14 +3,13 @@ package util
 import (
 	"fmt"
 	"github.com/diamondburned/arikawa/v3/discord"
-	"golang.org/x/text/language"
 	"golang.org/x/text/message"
 	"strconv"
 	"strings"
 )
 
 var (
-	printer = message.NewPrinter(language.English)
+	printer = Printer(DefaultLocale)
 )
 
 // HeadLinesLimit will take the first amount of lines that fit into the X char limit
@@ -95,19 +94,24 @@ func GetUserMention(id int64) string {
 
 // FormattedTime will turn seconds into a pretty time representation
 func FormattedTime(secondsIn int64) string {
+	return FormattedTimeIn(printer, secondsIn)
+}
+
+// FormattedTimeIn will turn seconds into a pretty time representation, translated by p
+func FormattedTimeIn(p *message.Printer, secondsIn int64) string {
 	hours := secondsIn / 3600
 	minutes := (secondsIn / 60) - (60 * hours)
 	seconds := secondsIn % 60
 
 	units := make([]string, 0)
 	if hours != 0 {
-		units = append(units, JoinInt64AndStr(hours, "hour"))
+		units = append(units, p.Sprintf("%d hours", hours))
 	}
 	if minutes != 0 {
-		units = append(units, JoinInt64AndStr(minutes, "minute"))
+		units = append(units, p.Sprintf("%d minutes", minutes))
 	}
 	if seconds != 0 || (hours == 0 && minutes == 0) {
-		units = append(units, JoinInt64AndStr(seconds, "second"))
+		units = append(units, p.Sprintf("%d seconds", seconds))
 	}
 
 	return strings.Join(units, ", ")
@@ -115,7 +119,12 @@ func FormattedTime(secondsIn int64) string {
 
 // FormattedNum will insert commas as necessary in large numbers
 func FormattedNum(num int64) string {
-	return printer.Sprintf("%d", num)
+	return FormattedNumIn(printer, num)
+}
+
+// FormattedNumIn will format num with the digit grouping of the locale of p
+func FormattedNumIn(p *message.Printer, num int64) string {
+	return p.Sprintf("%d", num)
 }
 
 // FormattedUserTag will return the user#discrim if it's non-0 otherwise just username
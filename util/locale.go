@@ -0,0 +1,123 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/message/catalog"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+//
+// Message catalog for translating user-facing text
+//
+
+var (
+	DefaultLocale = language.English // DefaultLocale is used when a guild or user hasn't set a locale, and for missing translations
+
+	translations = catalog.NewBuilder(catalog.Fallback(DefaultLocale))
+)
+
+func init() {
+	// The English text is the key of each message, so only plurals have to be added for it
+	for key, forms := range map[string][2]string{
+		"%d hours":   {"%d hour", "%d hours"},
+		"%d minutes": {"%d minute", "%d minutes"},
+		"%d seconds": {"%d second", "%d seconds"},
+	} {
+		_ = translations.Set(DefaultLocale, key, plural.Selectf(1, "%d", plural.One, forms[0], plural.Other, forms[1]))
+	}
+}
+
+// Printer will return a printer that translates messages and formats numbers for locale
+func Printer(locale language.Tag) *message.Printer {
+	return message.NewPrinter(locale, message.Catalog(translations))
+}
+
+// Locales will return the locales that have translations
+func Locales() []language.Tag {
+	return translations.Languages()
+}
+
+// ParseLocale will parse s as a locale, such as "de" or "pt-BR", which has to have translations
+func ParseLocale(s string) (language.Tag, error) {
+	tag, err := language.Parse(s)
+	if err != nil {
+		return DefaultLocale, err
+	}
+
+	base, _ := tag.Base()
+	for _, l := range Locales() {
+		if b, _ := l.Base(); b == base {
+			return tag, nil
+		}
+	}
+
+	return DefaultLocale, fmt.Errorf("no translations for %q", tag)
+}
+
+// LoadTranslations will add the translations of every <locale>.json file in fsys, such as "de.json".
+// Each file is a map of the English text of a message to its translation, which can also be a map of plural forms
+// such as {"one": "%d Stunde", "other": "%d Stunden"}, chosen by the first argument of the message.
+func LoadTranslations(fsys fs.FS) error {
+	files, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		tag, err := language.Parse(strings.TrimSuffix(path.Base(file), ".json"))
+		if err != nil {
+			return fmt.Errorf("invalid locale for %s: %w", file, err)
+		}
+
+		bytes, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+
+		messages := make(map[string]json.RawMessage)
+		if err := json.Unmarshal(bytes, &messages); err != nil {
+			return fmt.Errorf("invalid translations in %s: %w", file, err)
+		}
+
+		for key, raw := range messages {
+			if err := addTranslation(tag, key, raw); err != nil {
+				return fmt.Errorf("invalid translation of %q in %s: %w", key, file, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// addTranslation will add raw as the translation of key, raw is either a string or a map of plural forms
+func addTranslation(tag language.Tag, key string, raw json.RawMessage) error {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return translations.SetString(tag, key, s)
+	}
+
+	forms := make(map[string]string)
+	if err := json.Unmarshal(raw, &forms); err != nil {
+		return err
+	}
+
+	cases := make([]interface{}, 0, len(forms)*2)
+	for _, form := range []string{"zero", "one", "two", "few", "many"} {
+		if f, ok := forms[form]; ok {
+			cases = append(cases, form, f)
+		}
+	}
+	other, ok := forms["other"]
+	if !ok {
+		return fmt.Errorf("missing the \"other\" plural form")
+	}
+	cases = append(cases, "other", other)
+
+	return translations.Set(tag, key, plural.Selectf(1, "%d", cases...))
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"golang.org/x/net/html"
 	"image/color"
+	"strconv"
 	"strings"
 )
 
@@ -83,3 +84,49 @@ func ParseHexColorFast(s string) (c color.RGBA, err error) {
 	}
 	return
 }
+
+// ParseVersion will parse a semver string into its major, minor and patch numbers.
+// Pre-release and build metadata (anything after a `-` or `+`) is ignored.
+func ParseVersion(s string) ([3]int64, error) {
+	var v [3]int64
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.IndexAny(s, "-+"); i != -1 {
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return v, errors.New(fmt.Sprintf("`%s` is not in the format major.minor.patch", s))
+	}
+
+	for n, part := range parts {
+		i, err := strconv.ParseUint(part, 10, 63)
+		if err != nil {
+			return v, errors.New(fmt.Sprintf("`%s` is not a valid version number", part))
+		}
+		v[n] = int64(i)
+	}
+	return v, nil
+}
+
+// CompareVersions will compare two semver strings, returning -1 if a < b, 0 if a == b and 1 if a > b.
+// Pre-release and build metadata is ignored, see ParseVersion.
+func CompareVersions(a, b string) (int, error) {
+	va, err := ParseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := ParseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for n := range va {
+		if va[n] < vb[n] {
+			return -1, nil
+		} else if va[n] > vb[n] {
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,35 @@
+package util
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		want    int
+		wantErr bool
+	}{
+		{a: "1.0.0", b: "1.0.0", want: 0},
+		{a: "v1.2.3", b: "1.2.3", want: 0},
+		{a: "1.0.0", b: "1.0.1", want: -1},
+		{a: "1.10.0", b: "1.9.0", want: 1},
+		{a: "2.0.0", b: "1.99.99", want: 1},
+		{a: "1.0.0-beta", b: "1.0.0", want: 0},
+		{a: "1.0.0+build.5", b: "1.0.0-rc.1", want: 0},
+		{a: "1.0.1-alpha", b: "1.0.0", want: 1},
+		{a: "1.0", b: "1.0.0", wantErr: true},
+		{a: "1.0.0.0", b: "1.0.0", wantErr: true},
+		{a: "1.0.0", b: "1.x.0", wantErr: true},
+		{a: "1.-1.0", b: "1.0.0", wantErr: true},
+		{a: "", b: "1.0.0", wantErr: true},
+		{a: "1.0.0", b: "-beta", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := CompareVersions(tt.a, tt.b)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CompareVersions(%q, %q) returned error %v, want error: %v", tt.a, tt.b, err, tt.wantErr)
+		} else if got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}